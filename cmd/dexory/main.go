@@ -1,19 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	exportcontroller "github.com/habbas99/dexory/internal/controllers/export"
+	"github.com/habbas99/dexory/internal/controllers/mapping"
 	"github.com/habbas99/dexory/internal/controllers/report"
 	scancontroller "github.com/habbas99/dexory/internal/controllers/scan"
 	"github.com/habbas99/dexory/internal/repositories"
 	"github.com/habbas99/dexory/internal/services/comparison"
 	exportservice "github.com/habbas99/dexory/internal/services/export"
 	"github.com/habbas99/dexory/internal/services/file"
+	"github.com/habbas99/dexory/internal/services/job"
+	"github.com/habbas99/dexory/internal/services/location"
 	scanservice "github.com/habbas99/dexory/internal/services/scan"
 	log "github.com/sirupsen/logrus"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/habbas99/dexory/internal/db"
+	"github.com/habbas99/dexory/internal/models"
 	"github.com/joho/godotenv"
 )
 
@@ -62,23 +74,64 @@ func main() {
 	bulkScanRecordRepository := repositories.NewBulkScanRecordRepository(database.DB)
 	scanRepository := repositories.NewScanRepository(database.DB)
 	reportRecordRepository := repositories.NewReportRecordRepository(database.DB)
+	columnMappingProfileRepository := repositories.NewColumnMappingProfileRepository(database.DB)
 	comparisonDataRepository := repositories.NewComparisonDataRepository(database.DB)
 	exportReportRecordRepository := repositories.NewExportReportRecordRepository(database.DB)
+	jobRepository := repositories.NewJobRepository(database.DB)
+
+	// warehouses whose column mapping profile or bulk scan upload has no location pattern use the default grammar
+	defaultLocationGrammar, err := location.NewLocationGrammar(getEnv("LOCATION_PATTERN", location.DefaultLocationPattern))
+	if err != nil {
+		log.Fatalf("failed to create default location grammar, error: %v", err)
+	}
 
 	fileStorageService := file.NewFileStorageService()
-	scanService := scanservice.NewScanService(bulkScanRecordRepository, scanRepository, 50)
+	scanService := scanservice.NewScanService(
+		bulkScanRecordRepository, scanRepository, fileStorageService, defaultLocationGrammar, scanservice.ScanServiceConfig{
+			BatchSize:               getEnvInt("SCAN_INGESTION_BATCH_SIZE", 5000),
+			ValidationPolicy:        getValidationPolicy("SCAN_VALIDATION_POLICY", models.RejectInvalidScans),
+			ValidationReportDirPath: "./bulk-scan-validation-reports",
+		},
+	)
 
 	comparisonDataService := comparison.NewComparisonDataService(
-		scanRepository, comparisonDataRepository, reportRecordRepository,
+		scanRepository, comparisonDataRepository, reportRecordRepository, defaultLocationGrammar, 1000,
 	)
 
-	exportReportService := exportservice.NewExportReportService(exportReportRecordRepository, comparisonDataRepository)
+	exportReportService := exportservice.NewExportReportService(
+		exportReportRecordRepository, reportRecordRepository, comparisonDataRepository, exportservice.ExportReportServiceConfig{
+			CSVBarcodeSeparator:        getEnv("EXPORT_CSV_BARCODE_SEPARATOR", "|"),
+			PDFIncludeMatchedLocations: getEnvBool("EXPORT_PDF_INCLUDE_MATCHED_LOCATIONS", false),
+		},
+	)
+
+	hostname, _ := os.Hostname()
+	jobWorkerService := job.NewJobWorkerService(jobRepository, job.JobWorkerConfig{
+		WorkerID:     fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		Concurrency:  4,
+		PollInterval: 2 * time.Second,
+		LeaseTimeout: 5 * time.Minute,
+		MaxAttempts:  5,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   10 * time.Minute,
+	})
+	jobWorkerService.RegisterHandler(models.ScanIngestionJob, scanService.ProcessBulkScanRecord)
+	jobWorkerService.RegisterHandler(models.ComparisonDataJob, comparisonDataService.GenerateComparisonDataForReportRecord)
+	jobWorkerService.RegisterHandler(models.ExportReportJob, exportReportService.ExportReportRecord)
+
+	// run job workers until the server receives a shutdown signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	workersDone := make(chan struct{})
+	go func() {
+		jobWorkerService.Run(ctx)
+		close(workersDone)
+	}()
 
 	scanController := scancontroller.NewScanController(
 		"./bulk-uploaded-scans",
 		fileStorageService,
 		bulkScanRecordRepository,
-		scanService,
 	)
 
 	reportRecordController := report.NewReportRecordController(
@@ -86,12 +139,15 @@ func main() {
 		fileStorageService,
 		bulkScanRecordRepository,
 		reportRecordRepository,
+		columnMappingProfileRepository,
 		comparisonDataRepository,
 		comparisonDataService,
 	)
 
+	columnMappingProfileController := mapping.NewColumnMappingProfileController(columnMappingProfileRepository)
+
 	exportReportController := exportcontroller.NewExportReportController(
-		"./exported-reports", fileStorageService, exportReportRecordRepository, exportReportService,
+		"./exported-reports", fileStorageService, exportReportRecordRepository, reportRecordRepository, exportReportService,
 	)
 
 	// setup Gin router
@@ -120,17 +176,98 @@ func main() {
 
 	// routes
 	router.GET("/bulk-scan-records", scanController.GetBulkScanRecords)
+	router.GET("/bulk-scan-records/:id/validation-report", scanController.DownloadValidationReport)
 	router.POST("/upload-bulk-scan-file", scanController.UploadBulkScanFile)
+	router.GET("/column-mapping-profiles", columnMappingProfileController.GetColumnMappingProfiles)
+	router.POST("/column-mapping-profiles", columnMappingProfileController.CreateColumnMappingProfile)
 	router.GET("/inventory-comparison-reports", reportRecordController.GetAllReportRecords)
 	router.POST("/inventory-comparison-reports", reportRecordController.CreateReportRecord)
+	router.POST("/inventory-comparison-reports/validate", reportRecordController.ValidateReferenceFile)
+	router.POST("/scan-diff-reports", reportRecordController.CreateScanDiff)
 	router.GET("/inventory-comparison-reports/:id", reportRecordController.GetReport)
 	router.GET("/inventory-comparison-reports/:id/data", reportRecordController.GetComparisonData)
+	router.GET("/inventory-comparison-reports/:id/summary", reportRecordController.GetSummary)
+	router.GET("/inventory-comparison-reports/:id/summary/locations", reportRecordController.GetLocationSummary)
+	router.GET("/inventory-comparison-reports/:id/diff", reportRecordController.GetReportDiff)
 	router.GET("/inventory-comparison-reports/:id/exports", exportReportController.GetExportReportRecords)
+	router.GET("/inventory-comparison-reports/:id/download", exportReportController.StreamReport)
 	router.POST("/export-report-records", exportReportController.CreateExportReportRecord)
 	router.GET("/export-report-records/:id/download", exportReportController.DownloadReport)
 
 	log.Info("server initialized")
 
 	// Run the server
-	router.Run(":8080")
+	server := &http.Server{Addr: ":8080", Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("failed running server, error: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("failed to shut down server gracefully, error: %v", err)
+	}
+
+	// in-flight jobs are allowed to finish, anything left behind is reclaimed once its lease expires
+	<-workersDone
+}
+
+// getEnv reads a setting from the environment, falling back to the default when it is not set.
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvInt reads a positive integer setting from the environment, falling back to the default when it is not set.
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("environment variable %s must be a positive integer, got=%s", key, value)
+	}
+
+	return parsed
+}
+
+// getEnvBool reads a true or false setting from the environment, falling back to the default when it is not set.
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("environment variable %s must be true or false, got=%s", key, value)
+	}
+
+	return parsed
+}
+
+// getValidationPolicy reads the validation policy applied to bulk scan files from the environment, falling back to
+// the default when it is not set.
+func getValidationPolicy(key string, defaultValue models.ValidationPolicy) models.ValidationPolicy {
+	value := models.ValidationPolicy(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	if value != models.RejectInvalidScans && value != models.QuarantineInvalidScans {
+		log.Fatalf("environment variable %s must be one of %s or %s, got=%s", key, models.RejectInvalidScans, models.QuarantineInvalidScans, value)
+	}
+
+	return value
 }
@@ -15,6 +15,8 @@ const ComparisonTable = ({ data }) => {
                 <th>Occupied</th>
                 <th>Actual Barcodes</th>
                 <th>Expected Barcodes</th>
+                <th>Missing Barcodes</th>
+                <th>Unexpected Barcodes</th>
                 <th>Result</th>
                 </tr>
             </thead>
@@ -26,6 +28,8 @@ const ComparisonTable = ({ data }) => {
                     <td>{renderBooleanIcon(item.occupied)}</td>
                     <td>{item.actualBarcodes.join(', ')}</td>
                     <td>{item.expectedBarcodes.join(', ')}</td>
+                    <td>{(item.missingBarcodes || []).join(', ')}</td>
+                    <td>{(item.unexpectedBarcodes || []).join(', ')}</td>
                     <td>{item.result}</td>
                 </tr>
                 ))}
@@ -3,9 +3,12 @@ import { Modal, Button, Form } from 'react-bootstrap';
 import axios from 'axios';
 
 const CreateReportModal = ({ showModal, handleCloseModal, handleCreateReport }) => {
-  const [bulkScanFileName, setBulkScanFileName] = useState('');
+  const [bulkScanRecordId, setBulkScanRecordId] = useState('');
   const [csvFile, setCsvFile] = useState(null);
   const [bulkScanRecords, setBulkScanRecords] = useState([]);
+  const [columnMappingProfile, setColumnMappingProfile] = useState('');
+  const [columnMappingProfiles, setColumnMappingProfiles] = useState([]);
+  const [sheet, setSheet] = useState('');
 
   useEffect(() => {
     const fetchBulkScanRecords = async () => {
@@ -13,14 +16,24 @@ const CreateReportModal = ({ showModal, handleCloseModal, handleCreateReport })
         const response = await axios.get('/bulk-scan-records');
         setBulkScanRecords(response.data);
         if (response.data.length > 0) {
-            setBulkScanFileName(response.data[0].fileName);
+            setBulkScanRecordId(String(response.data[0].id));
         }
       } catch (error) {
         console.error('Error fetching bulk scan records:', error);
       }
     };
 
+    const fetchColumnMappingProfiles = async () => {
+      try {
+        const response = await axios.get('/column-mapping-profiles');
+        setColumnMappingProfiles(response.data);
+      } catch (error) {
+        console.error('Error fetching column mapping profiles:', error);
+      }
+    };
+
     fetchBulkScanRecords();
+    fetchColumnMappingProfiles();
   }, []);
 
   const handleFileChange = (e) => {
@@ -29,12 +42,15 @@ const CreateReportModal = ({ showModal, handleCloseModal, handleCreateReport })
 
   const onSubmit = (e) => {
     e.preventDefault();
-    console.log("on submit, bulkScanFileName = " + bulkScanFileName)
-    handleCreateReport({ bulkScanFileName, csvFile });
-    setBulkScanFileName('');
+    handleCreateReport({ bulkScanRecordId, csvFile, columnMappingProfile, sheet });
+    setBulkScanRecordId('');
     setCsvFile(null);
+    setColumnMappingProfile('');
+    setSheet('');
   };
 
+  const isXlsxFile = csvFile && csvFile.name.toLowerCase().endsWith('.xlsx');
+
   return (
     <Modal show={showModal} onHide={null} backdrop="static">
       <Modal.Header>
@@ -46,27 +62,55 @@ const CreateReportModal = ({ showModal, handleCloseModal, handleCreateReport })
             <Form.Label>Select Bulk Scan File</Form.Label>
             <Form.Control
               as="select"
-              value={bulkScanFileName}
-              onChange={(e) => setBulkScanFileName(e.target.value)}
+              value={bulkScanRecordId}
+              onChange={(e) => setBulkScanRecordId(e.target.value)}
               required
             >
               {bulkScanRecords.map((record) => (
-                <option key={record.id} value={record.fileName}>
+                <option key={record.id} value={record.id}>
                   {record.fileName}
                 </option>
               ))}
             </Form.Control>
           </Form.Group>
 
+          <Form.Group controlId="formColumnMappingProfile" className="my-3">
+            <Form.Label>Column Mapping Profile</Form.Label>
+            <Form.Control
+              as="select"
+              value={columnMappingProfile}
+              onChange={(e) => setColumnMappingProfile(e.target.value)}
+            >
+              <option value="">Default (location, item)</option>
+              {columnMappingProfiles.map((profile) => (
+                <option key={profile.id} value={profile.name}>
+                  {profile.name}
+                </option>
+              ))}
+            </Form.Control>
+          </Form.Group>
+
           <Form.Group controlId="formCsvFile" className="my-3">
-            <Form.Label>CSV File</Form.Label>
+            <Form.Label>CSV or XLSX File</Form.Label>
             <Form.Control
               type="file"
-              accept=".csv"
+              accept=".csv,.xlsx"
               onChange={handleFileChange}
               required
             />
           </Form.Group>
+
+          {isXlsxFile && (
+            <Form.Group controlId="formSheet" className="my-3">
+              <Form.Label>Sheet</Form.Label>
+              <Form.Control
+                type="text"
+                placeholder="First sheet"
+                value={sheet}
+                onChange={(e) => setSheet(e.target.value)}
+              />
+            </Form.Group>
+          )}
         </Form>
       </Modal.Body>
       <Modal.Footer className="text-end">
@@ -0,0 +1,91 @@
+import React, { useState, useEffect } from 'react';
+import { Modal, Button, Form } from 'react-bootstrap';
+import axios from 'axios';
+
+const CreateScanDiffModal = ({ showModal, handleCloseModal, handleCreateScanDiff }) => {
+  const [bulkScanRecords, setBulkScanRecords] = useState([]);
+  const [baselineBulkScanRecordId, setBaselineBulkScanRecordId] = useState('');
+  const [bulkScanRecordId, setBulkScanRecordId] = useState('');
+
+  useEffect(() => {
+    const fetchBulkScanRecords = async () => {
+      try {
+        const response = await axios.get('/bulk-scan-records');
+        const completed = response.data.filter((record) => record.status === 'completed');
+        setBulkScanRecords(completed);
+        if (completed.length > 1) {
+          setBaselineBulkScanRecordId(String(completed[1].id));
+          setBulkScanRecordId(String(completed[0].id));
+        }
+      } catch (error) {
+        console.error('Error fetching bulk scan records:', error);
+      }
+    };
+
+    fetchBulkScanRecords();
+  }, []);
+
+  const onSubmit = (e) => {
+    e.preventDefault();
+    handleCreateScanDiff({
+      baselineBulkScanRecordId: Number(baselineBulkScanRecordId),
+      bulkScanRecordId: Number(bulkScanRecordId),
+    });
+  };
+
+  const renderOptions = () => bulkScanRecords.map((record) => (
+    <option key={record.id} value={record.id}>
+      {record.fileName}
+    </option>
+  ));
+
+  return (
+    <Modal show={showModal} onHide={null} backdrop="static">
+      <Modal.Header>
+        <Modal.Title>Compare Bulk Scans</Modal.Title>
+      </Modal.Header>
+      <Modal.Body>
+        <Form onSubmit={onSubmit}>
+          <Form.Group controlId="formBaselineBulkScan">
+            <Form.Label>Baseline Bulk Scan File</Form.Label>
+            <Form.Control
+              as="select"
+              value={baselineBulkScanRecordId}
+              onChange={(e) => setBaselineBulkScanRecordId(e.target.value)}
+              required
+            >
+              {renderOptions()}
+            </Form.Control>
+          </Form.Group>
+
+          <Form.Group controlId="formBulkScan" className="my-3">
+            <Form.Label>Later Bulk Scan File</Form.Label>
+            <Form.Control
+              as="select"
+              value={bulkScanRecordId}
+              onChange={(e) => setBulkScanRecordId(e.target.value)}
+              required
+            >
+              {renderOptions()}
+            </Form.Control>
+          </Form.Group>
+        </Form>
+      </Modal.Body>
+      <Modal.Footer className="text-end">
+        <Button variant="secondary" onClick={handleCloseModal}>
+          Close
+        </Button>
+        <Button
+          variant="success"
+          type="submit"
+          onClick={onSubmit}
+          disabled={!bulkScanRecordId || baselineBulkScanRecordId === bulkScanRecordId}
+        >
+          Compare
+        </Button>
+      </Modal.Footer>
+    </Modal>
+  );
+};
+
+export default CreateScanDiffModal;
@@ -4,12 +4,26 @@ import axios from 'axios';
 
 const ExportReportModal = ({ show, handleClose, reportId }) => {
     const [exportType, setExportType] = useState('json');
+    const [discrepanciesOnly, setDiscrepanciesOnly] = useState(false);
+    const [locationPrefix, setLocationPrefix] = useState('');
+    const [scanned, setScanned] = useState('');
+    const [occupied, setOccupied] = useState('');
+    const [baseline, setBaseline] = useState('');
+
+    const toFlag = (value) => (value === '' ? undefined : value === 'true');
 
     const handleExport = async () => {
         try {
             const response = await axios.post('/export-report-records', {
                 reportRecordId: Number(reportId),
                 reportType: exportType,
+                filter: {
+                    discrepanciesOnly,
+                    locationPrefix,
+                    scanned: toFlag(scanned),
+                    occupied: toFlag(occupied),
+                    baselineReportRecordId: baseline ? Number(baseline) : undefined,
+                },
             });
             console.log('Export successful:', response.data);
             handleClose(); // Close the modal after export
@@ -18,6 +32,30 @@ const ExportReportModal = ({ show, handleClose, reportId }) => {
         }
     };
 
+    // json and csv can also be streamed straight from the report, without waiting for an export
+    const canStream = exportType === 'json' || exportType === 'csv';
+
+    const handleStream = () => {
+        const params = new URLSearchParams({ format: exportType });
+        if (discrepanciesOnly) {
+            params.append('discrepanciesOnly', 'true');
+        }
+        if (locationPrefix) {
+            params.append('locationPrefix', locationPrefix);
+        }
+        if (scanned !== '') {
+            params.append('scanned', scanned);
+        }
+        if (occupied !== '') {
+            params.append('occupied', occupied);
+        }
+        if (baseline) {
+            params.append('baseline', baseline);
+        }
+        window.location.href = `/inventory-comparison-reports/${reportId}/download?${params.toString()}`;
+        handleClose();
+    };
+
     return (
         <Modal show={show} onHide={handleClose}>
             <Modal.Header closeButton>
@@ -34,12 +72,65 @@ const ExportReportModal = ({ show, handleClose, reportId }) => {
                         >
                             <option value="json">JSON</option>
                             <option value="csv">CSV</option>
+                            <option value="xlsx">XLSX</option>
+                            <option value="pdf">PDF</option>
                         </Form.Control>
                     </Form.Group>
+
+                    <Form.Group controlId="exportDiscrepanciesOnly" className="my-3">
+                        <Form.Check
+                            type="checkbox"
+                            label="Only discrepancies"
+                            checked={discrepanciesOnly}
+                            onChange={(e) => setDiscrepanciesOnly(e.target.checked)}
+                        />
+                    </Form.Group>
+
+                    <Form.Group controlId="exportLocationPrefix" className="my-3">
+                        <Form.Label>Location Prefix</Form.Label>
+                        <Form.Control
+                            type="text"
+                            placeholder="All locations"
+                            value={locationPrefix}
+                            onChange={(e) => setLocationPrefix(e.target.value)}
+                        />
+                    </Form.Group>
+
+                    <Form.Group controlId="exportScanned" className="my-3">
+                        <Form.Label>Scanned</Form.Label>
+                        <Form.Control as="select" value={scanned} onChange={(e) => setScanned(e.target.value)}>
+                            <option value="">Any</option>
+                            <option value="true">Yes</option>
+                            <option value="false">No</option>
+                        </Form.Control>
+                    </Form.Group>
+
+                    <Form.Group controlId="exportOccupied" className="my-3">
+                        <Form.Label>Occupied</Form.Label>
+                        <Form.Control as="select" value={occupied} onChange={(e) => setOccupied(e.target.value)}>
+                            <option value="">Any</option>
+                            <option value="true">Yes</option>
+                            <option value="false">No</option>
+                        </Form.Control>
+                    </Form.Group>
+
+                    <Form.Group controlId="exportBaseline" className="my-3">
+                        <Form.Label>Compare Against Report</Form.Label>
+                        <Form.Control
+                            type="number"
+                            min="1"
+                            placeholder="No baseline report"
+                            value={baseline}
+                            onChange={(e) => setBaseline(e.target.value)}
+                        />
+                    </Form.Group>
                 </Form>
             </Modal.Body>
             <Modal.Footer>
                 <Button variant="secondary" onClick={handleClose}>Close</Button>
+                {canStream && (
+                    <Button variant="outline-primary" onClick={handleStream}>Download Now</Button>
+                )}
                 <Button variant="primary" onClick={handleExport}>Export</Button>
             </Modal.Footer>
         </Modal>
@@ -4,6 +4,33 @@ import React from 'react';
 import { ListGroup, Button } from 'react-bootstrap';
 import {renderStatusBadge} from "./utils";
 
+const describeFilter = (filter) => {
+    if (!filter) {
+        return 'all locations';
+    }
+
+    const parts = [];
+    if (filter.discrepanciesOnly) {
+        parts.push('discrepancies only');
+    }
+    if (filter.results) {
+        parts.push(`${filter.results.length} result(s)`);
+    }
+    if (filter.locationPrefix) {
+        parts.push(`locations ${filter.locationPrefix}*`);
+    }
+    if (filter.scanned !== undefined) {
+        parts.push(filter.scanned ? 'scanned' : 'not scanned');
+    }
+    if (filter.occupied !== undefined) {
+        parts.push(filter.occupied ? 'occupied' : 'not occupied');
+    }
+    if (filter.columns) {
+        parts.push(`columns ${filter.columns.join(', ')}`);
+    }
+    return parts.join(', ');
+};
+
 const ExportReportRecordList = ({ exportReportRecords }) => {
     const handleDownload = async (exportReportRecordId) => {
         try {
@@ -48,7 +75,10 @@ const ExportReportRecordList = ({ exportReportRecords }) => {
                     exportReportRecords.map((exportReportRecord) => (
                         <ListGroup.Item key={exportReportRecord.id}>
                             <div className="d-flex justify-content-between align-items-center">
-                                <span>{exportReportRecord.fileName}</span>
+                                <span>
+                                    {exportReportRecord.fileName}
+                                    <small className="text-muted ms-2">{describeFilter(exportReportRecord.filter)}</small>
+                                </span>
                                 {exportReportRecord.status === 'completed' ? (
                                     <Button
                                         variant="link"
@@ -57,7 +87,7 @@ const ExportReportRecordList = ({ exportReportRecords }) => {
                                         Download
                                     </Button>
                                 ) : (
-                                    <span>{renderStatusBadge(exportReportRecord.status)}</span>
+                                    <span title={exportReportRecord.errorMessage}>{renderStatusBadge(exportReportRecord.status)}</span>
                                 )}
                             </div>
                         </ListGroup.Item>
@@ -0,0 +1,44 @@
+import React from 'react';
+import { Table } from 'react-bootstrap';
+
+// locations the location pattern of the warehouse does not match have no hierarchy to narrow the data down to
+const describeGroup = (group) => {
+  if (group.unparsed) return 'unparsed locations';
+  return ['zone', 'aisle', 'bay', 'level']
+    .filter((field) => group[field])
+    .map((field) => `${field} ${group[field]}`)
+    .join(', ') || 'unknown';
+};
+
+const LocationSummary = ({ groups, onGroupClick }) => {
+  return (
+    <Table striped bordered hover size="sm" className="mt-3">
+      <thead>
+        <tr>
+          <th>Group</th>
+          <th>Locations</th>
+          <th>Discrepancies</th>
+          <th>Unscanned</th>
+          <th>Accuracy (%)</th>
+        </tr>
+      </thead>
+      <tbody>
+        {groups.map((group) => (
+          <tr
+            key={describeGroup(group)}
+            onClick={() => !group.unparsed && onGroupClick(group)}
+            style={{ cursor: group.unparsed ? 'default' : 'pointer' }}
+          >
+            <td>{describeGroup(group)}</td>
+            <td>{group.total}</td>
+            <td>{group.discrepancies}</td>
+            <td>{group.unscanned}</td>
+            <td>{group.accuracy}</td>
+          </tr>
+        ))}
+      </tbody>
+    </Table>
+  );
+};
+
+export default LocationSummary;
@@ -4,34 +4,46 @@ import { useParams } from 'react-router-dom';
 import { Container, Row, Col, Form, Button } from 'react-bootstrap';
 import { renderStatusBadge, renderDateStr } from './utils';
 import Summary from './Summary';
+import LocationSummary from './LocationSummary';
+import ReportDiff from './ReportDiff';
 import ComparisonTable from './ComparisonTable';
 import SearchBar from './SearchBar';
 import ExportReportModal from "./ExportReportModal";
 import ExportReportRecordList from "./ExportReportRecordList";
 
+const pageSize = 100;
+
 const ReportDetail = () => {
   const { reportId } = useParams();
   const [report, setReport] = useState(null);
   const [comparisonData, setComparisonData] = useState([]);
-  const [filteredData, setFilteredData] = useState([]);
-  const [searchStr, setSearchStr] = useState('');
-  const [summary, setSummary] = useState({});
+  const [total, setTotal] = useState(0);
+  const [nextCursor, setNextCursor] = useState('');
+  const [result, setResult] = useState('');
+  const [locationPrefix, setLocationPrefix] = useState('');
+  const [barcode, setBarcode] = useState('');
+  const [sort, setSort] = useState('location');
+  const [order, setOrder] = useState('asc');
+  const [summary, setSummary] = useState(null);
+  const [groupBy, setGroupBy] = useState('aisle');
+  const [locationGroups, setLocationGroups] = useState([]);
+  const [hierarchy, setHierarchy] = useState({});
   const [showExportModal, setShowExportModal] = useState(false);
   const [exportReportRecords, setExportReportRecords] = useState([]);
 
   useEffect(() => {
     fetchReport();
-    fetchComparisonData();
+    fetchSummary();
     fetchExportReportRecords();
   }, [reportId]);
 
   useEffect(() => {
-    filterData();
-  }, [searchStr, comparisonData]);
+    fetchComparisonData('');
+  }, [reportId, result, locationPrefix, barcode, hierarchy, sort, order]);
 
   useEffect(() => {
-    calculateSummary();
-  }, [comparisonData]);
+    fetchLocationSummary();
+  }, [reportId, groupBy]);
 
   const fetchReport = async () => {
     try {
@@ -42,11 +54,22 @@ const ReportDetail = () => {
     }
   };
 
-  const fetchComparisonData = async () => {
+  const comparisonDataParams = (cursor) => {
+    const params = new URLSearchParams({ sort, order, limit: pageSize });
+    if (result) params.append('result', result);
+    if (locationPrefix.trim()) params.append('locationPrefix', locationPrefix.trim());
+    if (barcode.trim()) params.append('barcode', barcode.trim());
+    Object.keys(hierarchy).forEach((field) => params.append(field, hierarchy[field]));
+    if (cursor) params.append('cursor', cursor);
+    return params;
+  };
+
+  const fetchComparisonData = async (cursor) => {
     try {
-      const response = await axios.get(`/inventory-comparison-reports/${reportId}/data`);
-      setComparisonData(response.data);
-      setFilteredData(response.data);
+      const response = await axios.get(`/inventory-comparison-reports/${reportId}/data`, { params: comparisonDataParams(cursor) });
+      setComparisonData((loaded) => (cursor ? loaded.concat(response.data.data) : response.data.data));
+      setTotal(response.data.total);
+      setNextCursor(response.data.nextCursor || '');
     } catch (error) {
       console.error('Error fetching comparison data:', error);
     }
@@ -61,48 +84,43 @@ const ReportDetail = () => {
     }
   };
 
-  const filterData = () => {
-    const lowerCasedFilter = searchStr.toLowerCase();
-    const filtered = comparisonData.filter((item) => {
-      return (
-        item.location.toLowerCase().includes(lowerCasedFilter) ||
-        item.result.toLowerCase().includes(lowerCasedFilter) ||
-        item.actualBarcodes.some(
-          (barcode) => barcode.toLowerCase().includes(lowerCasedFilter)
-        ) ||
-        item.expectedBarcodes.some(
-          (barcode) => barcode.toLowerCase().includes(lowerCasedFilter)
-        )
-      );
-    });
-    setFilteredData(filtered);
+  const fetchSummary = async () => {
+    try {
+      const response = await axios.get(`/inventory-comparison-reports/${reportId}/summary`);
+      if (response.status === 200) {
+        setSummary(response.data);
+      }
+    } catch (error) {
+      console.error('Error fetching summary:', error);
+    }
   };
 
-  const calculateSummary = () => {
-    const summary = {
-      "The location was empty, as expected": 0,
-      "The location was empty, but it should have been occupied": 0,
-      "The location was occupied by the expected items": 0,
-      "The location was occupied by the wrong items": 0,
-      "The location was occupied, but no barcode could be identified": 0,
-      "The location was occupied by an item, but should have been empty": 0,
-    };
-
-    comparisonData.forEach((item) => {
-      if (summary.hasOwnProperty(item.result)) {
-        summary[item.result] += 1;
-      }
-    });
+  // aisles, bays and levels are numbered within their zone, so they are grouped with the fields above them
+  const fetchLocationSummary = async () => {
+    const fields = ['zone', 'aisle', 'bay', 'level'];
+    const params = new URLSearchParams();
+    fields.slice(0, fields.indexOf(groupBy) + 1).forEach((field) => params.append('groupBy', field));
 
-    setSummary(summary);
+    try {
+      const response = await axios.get(`/inventory-comparison-reports/${reportId}/summary/locations`, { params });
+      if (response.status === 200) {
+        setLocationGroups(response.data.groups);
+      }
+    } catch (error) {
+      console.error('Error fetching location summary:', error);
+    }
   };
 
-  const handleSearchChange = (str) => {
-    setSearchStr(str);
+  const handleLocationGroupClick = (group) => {
+    const selected = {};
+    ['zone', 'aisle', 'bay', 'level'].forEach((field) => {
+      if (group[field]) selected[field] = group[field];
+    });
+    setHierarchy(selected);
   };
 
   const handleSummaryCountClick = (str) => {
-    setSearchStr(str);
+    setResult(str);
   };
 
   const handleExportModalClose = () => {
@@ -121,8 +139,7 @@ const ReportDetail = () => {
           <Button variant="secondary" onClick={() => window.history.back()}>Back</Button>
         </Col>
         <Col className="text-end">
-          <Button variant="primary" onClick={() => setShowExportModal(true)} disabled={exportReportRecords.length >= 1}
-          >Export</Button>
+          <Button variant="primary" onClick={() => setShowExportModal(true)}>Export</Button>
         </Col>
       </Row>
 
@@ -130,10 +147,18 @@ const ReportDetail = () => {
         <Col>
           <p><strong>ID:</strong> {report.id}</p>
           <p><strong>Status:</strong> {renderStatusBadge(report.status)}</p>
+          {report.errorMessage && (
+            <p><strong>Error:</strong> {report.errorMessage}
+              {report.failedRecordNumber ? ` (line ${report.failedRecordNumber})` : ''}</p>
+          )}
         </Col>
         <Col>
           <p><strong>Bulk Scan File:</strong> {report.bulkScanFileName}</p>
-          <p><strong>Reference File:</strong> {report.referenceFileName}</p>
+          {report.mode === 'scan_diff' ? (
+            <p><strong>Baseline Bulk Scan File:</strong> {report.baselineBulkScanFileName}</p>
+          ) : (
+            <p><strong>Reference File:</strong> {report.referenceFileName}</p>
+          )}
         </Col>
         <Col>
           <p><strong>Created At:</strong> {renderDateStr(report.createdAt)}</p>
@@ -157,8 +182,67 @@ const ReportDetail = () => {
 
       <Row className="my-4">
         <Col>
-            <SearchBar placeholderStr="Search by location, barcode, or result" searchStr={searchStr} onSearchChange={handleSearchChange} />
-          <ComparisonTable data={filteredData} />
+          <h5>Worst locations by:</h5>
+          <Form.Select value={groupBy} onChange={(e) => setGroupBy(e.target.value)} style={{ width: 'auto' }}>
+            <option value="zone">Zone</option>
+            <option value="aisle">Aisle</option>
+            <option value="bay">Bay</option>
+            <option value="level">Level</option>
+          </Form.Select>
+          <LocationSummary groups={locationGroups} onGroupClick={handleLocationGroupClick} />
+        </Col>
+      </Row>
+
+      {report.mode !== 'scan_diff' && (
+        <Row className="my-4">
+          <Col>
+            <h5>Discrepancies since:</h5>
+            <ReportDiff reportId={reportId} />
+          </Col>
+        </Row>
+      )}
+
+      <Row className="my-4">
+        <Col>
+          <Row>
+            <Col>
+              <SearchBar placeholderStr="Search by location prefix" searchStr={locationPrefix} onSearchChange={setLocationPrefix} />
+            </Col>
+            <Col>
+              <SearchBar placeholderStr="Search by barcode" searchStr={barcode} onSearchChange={setBarcode} />
+            </Col>
+            <Col md="auto">
+              <Form.Select value={sort} onChange={(e) => setSort(e.target.value)}>
+                <option value="location">Sort by location</option>
+                <option value="result">Sort by result</option>
+                <option value="scanned">Sort by scanned</option>
+                <option value="occupied">Sort by occupied</option>
+              </Form.Select>
+            </Col>
+            <Col md="auto">
+              <Form.Select value={order} onChange={(e) => setOrder(e.target.value)}>
+                <option value="asc">Ascending</option>
+                <option value="desc">Descending</option>
+              </Form.Select>
+            </Col>
+          </Row>
+          {result && (
+            <p className="mt-3">
+              <strong>Result:</strong> {result}
+              <Button variant="link" size="sm" onClick={() => setResult('')}>Clear</Button>
+            </p>
+          )}
+          {Object.keys(hierarchy).length > 0 && (
+            <p className="mt-3">
+              <strong>Locations in:</strong> {Object.keys(hierarchy).map((field) => `${field} ${hierarchy[field]}`).join(', ')}
+              <Button variant="link" size="sm" onClick={() => setHierarchy({})}>Clear</Button>
+            </p>
+          )}
+          <ComparisonTable data={comparisonData} />
+          <p>Showing {comparisonData.length} of {total} locations</p>
+          {nextCursor && (
+            <Button variant="secondary" onClick={() => fetchComparisonData(nextCursor)}>Load more</Button>
+          )}
         </Col>
       </Row>
 
@@ -0,0 +1,113 @@
+import React, { useState, useEffect } from 'react';
+import axios from 'axios';
+import { Form, Table, Button, Badge } from 'react-bootstrap';
+
+const pageSize = 100;
+
+const changeVariants = {
+  resolved: 'success',
+  persistent: 'warning',
+  new: 'danger',
+};
+
+const ReportDiff = ({ reportId }) => {
+  const [baselineReports, setBaselineReports] = useState([]);
+  const [baseline, setBaseline] = useState('');
+  const [change, setChange] = useState('');
+  const [diff, setDiff] = useState(null);
+  const [changes, setChanges] = useState([]);
+
+  useEffect(() => {
+    const fetchBaselineReports = async () => {
+      try {
+        const response = await axios.get('/inventory-comparison-reports');
+        setBaselineReports(response.data.filter((report) =>
+          String(report.id) !== String(reportId) && report.mode !== 'scan_diff' && report.status === 'completed'));
+      } catch (error) {
+        console.error('Error fetching reports:', error);
+      }
+    };
+
+    fetchBaselineReports();
+  }, [reportId]);
+
+  useEffect(() => {
+    fetchDiff('');
+  }, [reportId, baseline, change]);
+
+  const fetchDiff = async (cursor) => {
+    if (!baseline) {
+      setDiff(null);
+      setChanges([]);
+      return;
+    }
+
+    const params = new URLSearchParams({ baseline, limit: pageSize });
+    if (change) params.append('change', change);
+    if (cursor) params.append('cursor', cursor);
+
+    try {
+      const response = await axios.get(`/inventory-comparison-reports/${reportId}/diff`, { params });
+      setDiff(response.data);
+      setChanges(cursor ? (previous) => [...previous, ...response.data.changes] : response.data.changes);
+    } catch (error) {
+      console.error('Error fetching report diff:', error);
+    }
+  };
+
+  return (
+    <>
+      <div className="d-flex gap-2">
+        <Form.Select value={baseline} onChange={(e) => setBaseline(e.target.value)} style={{ width: 'auto' }}>
+          <option value="">Select a baseline report</option>
+          {baselineReports.map((report) => (
+            <option key={report.id} value={report.id}>
+              Report {report.id} ({report.bulkScanFileName})
+            </option>
+          ))}
+        </Form.Select>
+        <Form.Select value={change} onChange={(e) => setChange(e.target.value)} style={{ width: 'auto' }}>
+          <option value="">All changes</option>
+          <option value="resolved">Resolved</option>
+          <option value="persistent">Persistent</option>
+          <option value="new">New</option>
+        </Form.Select>
+      </div>
+
+      {diff && (
+        <>
+          <p className="mt-3">
+            <Badge bg="success">{diff.resolved} resolved</Badge>{' '}
+            <Badge bg="warning">{diff.persistent} persistent</Badge>{' '}
+            <Badge bg="danger">{diff.new} new</Badge>
+          </p>
+          <Table striped bordered hover size="sm">
+            <thead>
+              <tr>
+                <th>Location</th>
+                <th>Change</th>
+                <th>Previous Result</th>
+                <th>Result</th>
+              </tr>
+            </thead>
+            <tbody>
+              {changes.map((entry) => (
+                <tr key={entry.location}>
+                  <td>{entry.location}</td>
+                  <td><Badge bg={changeVariants[entry.change]}>{entry.change}</Badge></td>
+                  <td>{entry.previousResult || '-'}</td>
+                  <td>{entry.result || '-'}</td>
+                </tr>
+              ))}
+            </tbody>
+          </Table>
+          {diff.nextCursor && (
+            <Button variant="outline-secondary" onClick={() => fetchDiff(diff.nextCursor)}>Load more</Button>
+          )}
+        </>
+      )}
+    </>
+  );
+};
+
+export default ReportDiff;
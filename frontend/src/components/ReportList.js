@@ -5,10 +5,12 @@ import { useNavigate } from 'react-router-dom';
 import { Table, Button, Container, Row, Col } from 'react-bootstrap';
 import { renderStatusBadge, renderDateStr } from './utils';
 import CreateReportModal from './CreateReportModal';
+import CreateScanDiffModal from './CreateScanDiffModal';
 
 const ReportList = () => {
   const [reports, setReports] = useState([]);
   const [showModal, setShowModal] = useState(false);
+  const [showScanDiffModal, setShowScanDiffModal] = useState(false);
   const navigate = useNavigate();
 
   useEffect(() => {
@@ -24,10 +26,16 @@ const ReportList = () => {
     }
   };
 
-  const handleCreateReport = async ({ bulkScanFileName, csvFile }) => {
+  const handleCreateReport = async ({ bulkScanRecordId, csvFile, columnMappingProfile, sheet }) => {
     const formData = new FormData();
-    formData.append('bulkScanFileName', bulkScanFileName);
+    formData.append('bulkScanRecordId', bulkScanRecordId);
     formData.append('csvFile', csvFile);
+    if (columnMappingProfile) {
+      formData.append('columnMappingProfile', columnMappingProfile);
+    }
+    if (sheet) {
+      formData.append('sheet', sheet);
+    }
 
     try {
       await axios.post('/inventory-comparison-reports', formData);
@@ -38,6 +46,16 @@ const ReportList = () => {
     }
   };
 
+  const handleCreateScanDiff = async (scanDiff) => {
+    try {
+      await axios.post('/scan-diff-reports', scanDiff);
+      fetchReports();
+      setShowScanDiffModal(false);
+    } catch (error) {
+      console.error('Error creating scan diff:', error);
+    }
+  };
+
   const handleRowClick = (reportId) => {
     navigate(`/report/${reportId}`);
   };
@@ -49,6 +67,9 @@ const ReportList = () => {
     <Container>
       <Row className="my-4">
         <Col className="text-end">
+          <Button variant="outline-primary" className="me-2" onClick={() => setShowScanDiffModal(true)}>
+            Compare Scans
+          </Button>
           <Button variant="primary" onClick={handleOpenModal}>
             Create Report
           </Button>
@@ -62,7 +83,7 @@ const ReportList = () => {
               <tr>
                 <th>ID</th>
                 <th>Bulk Scan File</th>
-                <th>Reference File</th>
+                <th>Compared Against</th>
                 <th>Created</th>
                 <th>Updated</th>
                 <th>Status</th>
@@ -73,7 +94,7 @@ const ReportList = () => {
                 <tr key={report.id} onClick={() => handleRowClick(report.id)} style={{ cursor: 'pointer' }}>
                   <td>{report.id}</td>
                   <td>{report.bulkScanFileName}</td>
-                  <td>{report.referenceFileName}</td>
+                  <td>{report.mode === 'scan_diff' ? report.baselineBulkScanFileName : report.referenceFileName}</td>
                   <td>{renderDateStr(report.createdAt)}</td>
                   <td>{renderDateStr(report.updatedAt)}</td>
                   <td>{renderStatusBadge(report.status)}</td>
@@ -89,6 +110,12 @@ const ReportList = () => {
         handleCloseModal={handleCloseModal}
         handleCreateReport={handleCreateReport}
       />
+
+      <CreateScanDiffModal
+        showModal={showScanDiffModal}
+        handleCloseModal={() => setShowScanDiffModal(false)}
+        handleCreateScanDiff={handleCreateScanDiff}
+      />
     </Container>
   );
 };
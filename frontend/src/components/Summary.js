@@ -1,18 +1,31 @@
 import React from 'react';
 
 const Summary = ({ summary, onSummaryCountClick }) => {
+  if (!summary) {
+    return <div>Summary is not available yet</div>;
+  }
+
   return (
     <div style={{ border: '1px solid #ddd', padding: '15px', borderRadius: '5px' }}>
+      <p>
+        <strong>Accuracy:</strong> {summary.accuracy}% ({summary.matched} of {summary.total} locations matched,
+        {' '}{summary.discrepancies} discrepancies)
+      </p>
+      <p>
+        <strong>Scanned:</strong> {summary.scanned} <strong className="ms-3">Unscanned:</strong> {summary.unscanned}
+        <strong className="ms-3">Occupied:</strong> {summary.occupied} <strong className="ms-3">Empty:</strong> {summary.empty}
+      </p>
       <ul>
-        {Object.keys(summary).map((key, index) => (
-          <li key={index}>
-            {key}: 
+        {summary.outcomes.filter((outcome) => outcome.count > 0).map((outcome) => (
+          <li key={outcome.result}>
+            {outcome.result}:
             <span
-              onClick={() => onSummaryCountClick(key)}
+              onClick={() => onSummaryCountClick(outcome.result)}
               style={{ cursor: 'pointer', color: '#007bff', marginLeft: '10px' }}
             >
-              {summary[key]}
+              {outcome.count}
             </span>
+            {' '}({outcome.percentage}%)
           </li>
         ))}
       </ul>
@@ -5,6 +5,7 @@
 package mockexportreportcontroller
 
 import (
+	io "io"
 	os "os"
 	reflect "reflect"
 
@@ -74,18 +75,18 @@ func (m *MockexportReportRecordClient) EXPECT() *MockexportReportRecordClientMoc
 }
 
 // Create mocks base method.
-func (m *MockexportReportRecordClient) Create(reportRecordID uint, filePath, reportType string) (*models.ExportReportRecord, error) {
+func (m *MockexportReportRecordClient) Create(reportRecordID uint, filePath, reportType string, filter models.ExportFilter) (*models.ExportReportRecord, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", reportRecordID, filePath, reportType)
+	ret := m.ctrl.Call(m, "Create", reportRecordID, filePath, reportType, filter)
 	ret0, _ := ret[0].(*models.ExportReportRecord)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockexportReportRecordClientMockRecorder) Create(reportRecordID, filePath, reportType interface{}) *gomock.Call {
+func (mr *MockexportReportRecordClientMockRecorder) Create(reportRecordID, filePath, reportType, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockexportReportRecordClient)(nil).Create), reportRecordID, filePath, reportType)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockexportReportRecordClient)(nil).Create), reportRecordID, filePath, reportType, filter)
 }
 
 // Get mocks base method.
@@ -118,52 +119,92 @@ func (mr *MockexportReportRecordClientMockRecorder) GetAll(reportRecordID interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockexportReportRecordClient)(nil).GetAll), reportRecordID)
 }
 
-// GetByReportType mocks base method.
-func (m *MockexportReportRecordClient) GetByReportType(reportRecordID uint, reportType string) (*models.ExportReportRecord, error) {
+// GetByFilter mocks base method.
+func (m *MockexportReportRecordClient) GetByFilter(reportRecordID uint, reportType, filterKey string) (*models.ExportReportRecord, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByReportType", reportRecordID, reportType)
+	ret := m.ctrl.Call(m, "GetByFilter", reportRecordID, reportType, filterKey)
 	ret0, _ := ret[0].(*models.ExportReportRecord)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetByReportType indicates an expected call of GetByReportType.
-func (mr *MockexportReportRecordClientMockRecorder) GetByReportType(reportRecordID, reportType interface{}) *gomock.Call {
+// GetByFilter indicates an expected call of GetByFilter.
+func (mr *MockexportReportRecordClientMockRecorder) GetByFilter(reportRecordID, reportType, filterKey interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByReportType", reflect.TypeOf((*MockexportReportRecordClient)(nil).GetByReportType), reportRecordID, reportType)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByFilter", reflect.TypeOf((*MockexportReportRecordClient)(nil).GetByFilter), reportRecordID, reportType, filterKey)
 }
 
-// MockexportReportServiceClient is a mock of exportReportServiceClient interface.
-type MockexportReportServiceClient struct {
+// MockreportRecordClient is a mock of reportRecordClient interface.
+type MockreportRecordClient struct {
 	ctrl     *gomock.Controller
-	recorder *MockexportReportServiceClientMockRecorder
+	recorder *MockreportRecordClientMockRecorder
 }
 
-// MockexportReportServiceClientMockRecorder is the mock recorder for MockexportReportServiceClient.
-type MockexportReportServiceClientMockRecorder struct {
-	mock *MockexportReportServiceClient
+// MockreportRecordClientMockRecorder is the mock recorder for MockreportRecordClient.
+type MockreportRecordClientMockRecorder struct {
+	mock *MockreportRecordClient
 }
 
-// NewMockexportReportServiceClient creates a new mock instance.
-func NewMockexportReportServiceClient(ctrl *gomock.Controller) *MockexportReportServiceClient {
-	mock := &MockexportReportServiceClient{ctrl: ctrl}
-	mock.recorder = &MockexportReportServiceClientMockRecorder{mock}
+// NewMockreportRecordClient creates a new mock instance.
+func NewMockreportRecordClient(ctrl *gomock.Controller) *MockreportRecordClient {
+	mock := &MockreportRecordClient{ctrl: ctrl}
+	mock.recorder = &MockreportRecordClientMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockexportReportServiceClient) EXPECT() *MockexportReportServiceClientMockRecorder {
+func (m *MockreportRecordClient) EXPECT() *MockreportRecordClientMockRecorder {
 	return m.recorder
 }
 
-// ExportReport mocks base method.
-func (m *MockexportReportServiceClient) ExportReport(exportReportRecord *models.ExportReportRecord) {
+// Get mocks base method.
+func (m *MockreportRecordClient) Get(reportRecordID uint) (*models.ReportRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", reportRecordID)
+	ret0, _ := ret[0].(*models.ReportRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockreportRecordClientMockRecorder) Get(reportRecordID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockreportRecordClient)(nil).Get), reportRecordID)
+}
+
+// MockreportStreamClient is a mock of reportStreamClient interface.
+type MockreportStreamClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockreportStreamClientMockRecorder
+}
+
+// MockreportStreamClientMockRecorder is the mock recorder for MockreportStreamClient.
+type MockreportStreamClientMockRecorder struct {
+	mock *MockreportStreamClient
+}
+
+// NewMockreportStreamClient creates a new mock instance.
+func NewMockreportStreamClient(ctrl *gomock.Controller) *MockreportStreamClient {
+	mock := &MockreportStreamClient{ctrl: ctrl}
+	mock.recorder = &MockreportStreamClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockreportStreamClient) EXPECT() *MockreportStreamClientMockRecorder {
+	return m.recorder
+}
+
+// StreamReport mocks base method.
+func (m *MockreportStreamClient) StreamReport(reportRecordID uint, reportType models.ExportReportType, filter models.ExportFilter, w io.Writer) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "ExportReport", exportReportRecord)
+	ret := m.ctrl.Call(m, "StreamReport", reportRecordID, reportType, filter, w)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// ExportReport indicates an expected call of ExportReport.
-func (mr *MockexportReportServiceClientMockRecorder) ExportReport(exportReportRecord interface{}) *gomock.Call {
+// StreamReport indicates an expected call of StreamReport.
+func (mr *MockreportStreamClientMockRecorder) StreamReport(reportRecordID, reportType, filter, w interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportReport", reflect.TypeOf((*MockexportReportServiceClient)(nil).ExportReport), exportReportRecord)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamReport", reflect.TypeOf((*MockreportStreamClient)(nil).StreamReport), reportRecordID, reportType, filter, w)
 }
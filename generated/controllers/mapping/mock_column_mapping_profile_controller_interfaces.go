@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/controllers/mapping/column_mapping_profile_controller.go
+
+// Package mockcolumnmappingprofilecontroller is a generated GoMock package.
+package mockcolumnmappingprofilecontroller
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/habbas99/dexory/internal/models"
+)
+
+// MockcolumnMappingProfileClient is a mock of columnMappingProfileClient interface.
+type MockcolumnMappingProfileClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockcolumnMappingProfileClientMockRecorder
+}
+
+// MockcolumnMappingProfileClientMockRecorder is the mock recorder for MockcolumnMappingProfileClient.
+type MockcolumnMappingProfileClientMockRecorder struct {
+	mock *MockcolumnMappingProfileClient
+}
+
+// NewMockcolumnMappingProfileClient creates a new mock instance.
+func NewMockcolumnMappingProfileClient(ctrl *gomock.Controller) *MockcolumnMappingProfileClient {
+	mock := &MockcolumnMappingProfileClient{ctrl: ctrl}
+	mock.recorder = &MockcolumnMappingProfileClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockcolumnMappingProfileClient) EXPECT() *MockcolumnMappingProfileClientMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockcolumnMappingProfileClient) Create(columnMappingProfile *models.ColumnMappingProfile) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", columnMappingProfile)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockcolumnMappingProfileClientMockRecorder) Create(columnMappingProfile interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockcolumnMappingProfileClient)(nil).Create), columnMappingProfile)
+}
+
+// GetAll mocks base method.
+func (m *MockcolumnMappingProfileClient) GetAll() ([]models.ColumnMappingProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll")
+	ret0, _ := ret[0].([]models.ColumnMappingProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockcolumnMappingProfileClientMockRecorder) GetAll() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockcolumnMappingProfileClient)(nil).GetAll))
+}
+
+// GetByName mocks base method.
+func (m *MockcolumnMappingProfileClient) GetByName(name string) (*models.ColumnMappingProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByName", name)
+	ret0, _ := ret[0].(*models.ColumnMappingProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByName indicates an expected call of GetByName.
+func (mr *MockcolumnMappingProfileClientMockRecorder) GetByName(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockcolumnMappingProfileClient)(nil).GetByName), name)
+}
@@ -11,6 +11,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/habbas99/dexory/internal/models"
+	comparison "github.com/habbas99/dexory/internal/services/comparison"
 )
 
 // MockfileStorageClient is a mock of fileStorageClient interface.
@@ -75,18 +76,33 @@ func (m *MockreportRecordClient) EXPECT() *MockreportRecordClientMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockreportRecordClient) Create(bulkScanRecord models.BulkScanRecord, referenceFilePath string) (*models.ReportRecord, error) {
+func (m *MockreportRecordClient) Create(bulkScanRecord models.BulkScanRecord, columnMappingProfile *models.ColumnMappingProfile, referenceFilePath, referenceSheet, referenceFileHash string) (*models.ReportRecord, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", bulkScanRecord, referenceFilePath)
+	ret := m.ctrl.Call(m, "Create", bulkScanRecord, columnMappingProfile, referenceFilePath, referenceSheet, referenceFileHash)
 	ret0, _ := ret[0].(*models.ReportRecord)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockreportRecordClientMockRecorder) Create(bulkScanRecord, referenceFilePath interface{}) *gomock.Call {
+func (mr *MockreportRecordClientMockRecorder) Create(bulkScanRecord, columnMappingProfile, referenceFilePath, referenceSheet, referenceFileHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockreportRecordClient)(nil).Create), bulkScanRecord, referenceFilePath)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockreportRecordClient)(nil).Create), bulkScanRecord, columnMappingProfile, referenceFilePath, referenceSheet, referenceFileHash)
+}
+
+// CreateScanDiff mocks base method.
+func (m *MockreportRecordClient) CreateScanDiff(baselineBulkScanRecord, bulkScanRecord models.BulkScanRecord) (*models.ReportRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateScanDiff", baselineBulkScanRecord, bulkScanRecord)
+	ret0, _ := ret[0].(*models.ReportRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateScanDiff indicates an expected call of CreateScanDiff.
+func (mr *MockreportRecordClientMockRecorder) CreateScanDiff(baselineBulkScanRecord, bulkScanRecord interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScanDiff", reflect.TypeOf((*MockreportRecordClient)(nil).CreateScanDiff), baselineBulkScanRecord, bulkScanRecord)
 }
 
 // Get mocks base method.
@@ -119,6 +135,74 @@ func (mr *MockreportRecordClientMockRecorder) GetAll() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockreportRecordClient)(nil).GetAll))
 }
 
+// GetCompletedByReferenceFileHash mocks base method.
+func (m *MockreportRecordClient) GetCompletedByReferenceFileHash(bulkScanRecordID uint, columnMappingProfileID *uint, referenceSheet, referenceFileHash string) (*models.ReportRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCompletedByReferenceFileHash", bulkScanRecordID, columnMappingProfileID, referenceSheet, referenceFileHash)
+	ret0, _ := ret[0].(*models.ReportRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCompletedByReferenceFileHash indicates an expected call of GetCompletedByReferenceFileHash.
+func (mr *MockreportRecordClientMockRecorder) GetCompletedByReferenceFileHash(bulkScanRecordID, columnMappingProfileID, referenceSheet, referenceFileHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompletedByReferenceFileHash", reflect.TypeOf((*MockreportRecordClient)(nil).GetCompletedByReferenceFileHash), bulkScanRecordID, columnMappingProfileID, referenceSheet, referenceFileHash)
+}
+
+// GetCompletedScanDiff mocks base method.
+func (m *MockreportRecordClient) GetCompletedScanDiff(baselineBulkScanRecordID, bulkScanRecordID uint) (*models.ReportRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCompletedScanDiff", baselineBulkScanRecordID, bulkScanRecordID)
+	ret0, _ := ret[0].(*models.ReportRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCompletedScanDiff indicates an expected call of GetCompletedScanDiff.
+func (mr *MockreportRecordClientMockRecorder) GetCompletedScanDiff(baselineBulkScanRecordID, bulkScanRecordID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompletedScanDiff", reflect.TypeOf((*MockreportRecordClient)(nil).GetCompletedScanDiff), baselineBulkScanRecordID, bulkScanRecordID)
+}
+
+// MockcolumnMappingProfileClient is a mock of columnMappingProfileClient interface.
+type MockcolumnMappingProfileClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockcolumnMappingProfileClientMockRecorder
+}
+
+// MockcolumnMappingProfileClientMockRecorder is the mock recorder for MockcolumnMappingProfileClient.
+type MockcolumnMappingProfileClientMockRecorder struct {
+	mock *MockcolumnMappingProfileClient
+}
+
+// NewMockcolumnMappingProfileClient creates a new mock instance.
+func NewMockcolumnMappingProfileClient(ctrl *gomock.Controller) *MockcolumnMappingProfileClient {
+	mock := &MockcolumnMappingProfileClient{ctrl: ctrl}
+	mock.recorder = &MockcolumnMappingProfileClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockcolumnMappingProfileClient) EXPECT() *MockcolumnMappingProfileClientMockRecorder {
+	return m.recorder
+}
+
+// GetByName mocks base method.
+func (m *MockcolumnMappingProfileClient) GetByName(name string) (*models.ColumnMappingProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByName", name)
+	ret0, _ := ret[0].(*models.ColumnMappingProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByName indicates an expected call of GetByName.
+func (mr *MockcolumnMappingProfileClientMockRecorder) GetByName(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockcolumnMappingProfileClient)(nil).GetByName), name)
+}
+
 // MockbulkScanRecordClient is a mock of bulkScanRecordClient interface.
 type MockbulkScanRecordClient struct {
 	ctrl     *gomock.Controller
@@ -142,6 +226,21 @@ func (m *MockbulkScanRecordClient) EXPECT() *MockbulkScanRecordClientMockRecorde
 	return m.recorder
 }
 
+// Get mocks base method.
+func (m *MockbulkScanRecordClient) Get(bulkScanRecordID uint) (*models.BulkScanRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", bulkScanRecordID)
+	ret0, _ := ret[0].(*models.BulkScanRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockbulkScanRecordClientMockRecorder) Get(bulkScanRecordID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockbulkScanRecordClient)(nil).Get), bulkScanRecordID)
+}
+
 // GetByFileName mocks base method.
 func (m *MockbulkScanRecordClient) GetByFileName(fileName string) (*models.BulkScanRecord, error) {
 	m.ctrl.T.Helper()
@@ -180,52 +279,130 @@ func (m *MockcomparisonDataClient) EXPECT() *MockcomparisonDataClientMockRecorde
 	return m.recorder
 }
 
-// GetAllPaginated mocks base method.
-func (m *MockcomparisonDataClient) GetAllPaginated(reportRecordID uint, limit, offset int) ([]models.ComparisonData, error) {
+// Count mocks base method.
+func (m *MockcomparisonDataClient) Count(reportRecordID uint, pageQuery models.ComparisonDataPageQuery) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", reportRecordID, pageQuery)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockcomparisonDataClientMockRecorder) Count(reportRecordID, pageQuery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockcomparisonDataClient)(nil).Count), reportRecordID, pageQuery)
+}
+
+// CountReportDiff mocks base method.
+func (m *MockcomparisonDataClient) CountReportDiff(baselineReportRecordID, reportRecordID uint) (map[models.DiscrepancyChange]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountReportDiff", baselineReportRecordID, reportRecordID)
+	ret0, _ := ret[0].(map[models.DiscrepancyChange]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountReportDiff indicates an expected call of CountReportDiff.
+func (mr *MockcomparisonDataClientMockRecorder) CountReportDiff(baselineReportRecordID, reportRecordID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountReportDiff", reflect.TypeOf((*MockcomparisonDataClient)(nil).CountReportDiff), baselineReportRecordID, reportRecordID)
+}
+
+// GetLocationSummary mocks base method.
+func (m *MockcomparisonDataClient) GetLocationSummary(reportRecordID uint, groupBy []models.LocationField, within models.LocationHierarchy) ([]models.LocationGroupSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationSummary", reportRecordID, groupBy, within)
+	ret0, _ := ret[0].([]models.LocationGroupSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationSummary indicates an expected call of GetLocationSummary.
+func (mr *MockcomparisonDataClientMockRecorder) GetLocationSummary(reportRecordID, groupBy, within interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationSummary", reflect.TypeOf((*MockcomparisonDataClient)(nil).GetLocationSummary), reportRecordID, groupBy, within)
+}
+
+// GetPage mocks base method.
+func (m *MockcomparisonDataClient) GetPage(reportRecordID uint, pageQuery models.ComparisonDataPageQuery) ([]models.ComparisonData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllPaginated", reportRecordID, limit, offset)
+	ret := m.ctrl.Call(m, "GetPage", reportRecordID, pageQuery)
 	ret0, _ := ret[0].([]models.ComparisonData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAllPaginated indicates an expected call of GetAllPaginated.
-func (mr *MockcomparisonDataClientMockRecorder) GetAllPaginated(reportRecordID, limit, offset interface{}) *gomock.Call {
+// GetPage indicates an expected call of GetPage.
+func (mr *MockcomparisonDataClientMockRecorder) GetPage(reportRecordID, pageQuery interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllPaginated", reflect.TypeOf((*MockcomparisonDataClient)(nil).GetAllPaginated), reportRecordID, limit, offset)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPage", reflect.TypeOf((*MockcomparisonDataClient)(nil).GetPage), reportRecordID, pageQuery)
 }
 
-// MockcomparisonDataServiceClient is a mock of comparisonDataServiceClient interface.
-type MockcomparisonDataServiceClient struct {
+// GetReportDiff mocks base method.
+func (m *MockcomparisonDataClient) GetReportDiff(baselineReportRecordID, reportRecordID uint, diffQuery models.ReportDiffQuery) ([]models.ReportDiffEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReportDiff", baselineReportRecordID, reportRecordID, diffQuery)
+	ret0, _ := ret[0].([]models.ReportDiffEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReportDiff indicates an expected call of GetReportDiff.
+func (mr *MockcomparisonDataClientMockRecorder) GetReportDiff(baselineReportRecordID, reportRecordID, diffQuery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReportDiff", reflect.TypeOf((*MockcomparisonDataClient)(nil).GetReportDiff), baselineReportRecordID, reportRecordID, diffQuery)
+}
+
+// GetSummary mocks base method.
+func (m *MockcomparisonDataClient) GetSummary(reportRecordID uint) (*models.ReportSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSummary", reportRecordID)
+	ret0, _ := ret[0].(*models.ReportSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSummary indicates an expected call of GetSummary.
+func (mr *MockcomparisonDataClientMockRecorder) GetSummary(reportRecordID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSummary", reflect.TypeOf((*MockcomparisonDataClient)(nil).GetSummary), reportRecordID)
+}
+
+// MockreferenceFileValidationClient is a mock of referenceFileValidationClient interface.
+type MockreferenceFileValidationClient struct {
 	ctrl     *gomock.Controller
-	recorder *MockcomparisonDataServiceClientMockRecorder
+	recorder *MockreferenceFileValidationClientMockRecorder
 }
 
-// MockcomparisonDataServiceClientMockRecorder is the mock recorder for MockcomparisonDataServiceClient.
-type MockcomparisonDataServiceClientMockRecorder struct {
-	mock *MockcomparisonDataServiceClient
+// MockreferenceFileValidationClientMockRecorder is the mock recorder for MockreferenceFileValidationClient.
+type MockreferenceFileValidationClientMockRecorder struct {
+	mock *MockreferenceFileValidationClient
 }
 
-// NewMockcomparisonDataServiceClient creates a new mock instance.
-func NewMockcomparisonDataServiceClient(ctrl *gomock.Controller) *MockcomparisonDataServiceClient {
-	mock := &MockcomparisonDataServiceClient{ctrl: ctrl}
-	mock.recorder = &MockcomparisonDataServiceClientMockRecorder{mock}
+// NewMockreferenceFileValidationClient creates a new mock instance.
+func NewMockreferenceFileValidationClient(ctrl *gomock.Controller) *MockreferenceFileValidationClient {
+	mock := &MockreferenceFileValidationClient{ctrl: ctrl}
+	mock.recorder = &MockreferenceFileValidationClientMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockcomparisonDataServiceClient) EXPECT() *MockcomparisonDataServiceClientMockRecorder {
+func (m *MockreferenceFileValidationClient) EXPECT() *MockreferenceFileValidationClientMockRecorder {
 	return m.recorder
 }
 
-// GenerateComparisonDataForReport mocks base method.
-func (m *MockcomparisonDataServiceClient) GenerateComparisonDataForReport(reportRecord *models.ReportRecord) {
+// ValidateReferenceFile mocks base method.
+func (m *MockreferenceFileValidationClient) ValidateReferenceFile(bulkScanRecordID uint, file io.Reader, options comparison.ReferenceFileOptions) (*comparison.ReferenceFileValidation, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "GenerateComparisonDataForReport", reportRecord)
+	ret := m.ctrl.Call(m, "ValidateReferenceFile", bulkScanRecordID, file, options)
+	ret0, _ := ret[0].(*comparison.ReferenceFileValidation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// GenerateComparisonDataForReport indicates an expected call of GenerateComparisonDataForReport.
-func (mr *MockcomparisonDataServiceClientMockRecorder) GenerateComparisonDataForReport(reportRecord interface{}) *gomock.Call {
+// ValidateReferenceFile indicates an expected call of ValidateReferenceFile.
+func (mr *MockreferenceFileValidationClientMockRecorder) ValidateReferenceFile(bulkScanRecordID, file, options interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateComparisonDataForReport", reflect.TypeOf((*MockcomparisonDataServiceClient)(nil).GenerateComparisonDataForReport), reportRecord)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateReferenceFile", reflect.TypeOf((*MockreferenceFileValidationClient)(nil).ValidateReferenceFile), bulkScanRecordID, file, options)
 }
@@ -13,31 +13,31 @@ import (
 	models "github.com/habbas99/dexory/internal/models"
 )
 
-// MockFileStorageClient is a mock of FileStorageClient interface.
-type MockFileStorageClient struct {
+// MockfileStorageClient is a mock of fileStorageClient interface.
+type MockfileStorageClient struct {
 	ctrl     *gomock.Controller
-	recorder *MockFileStorageClientMockRecorder
+	recorder *MockfileStorageClientMockRecorder
 }
 
-// MockFileStorageClientMockRecorder is the mock recorder for MockFileStorageClient.
-type MockFileStorageClientMockRecorder struct {
-	mock *MockFileStorageClient
+// MockfileStorageClientMockRecorder is the mock recorder for MockfileStorageClient.
+type MockfileStorageClientMockRecorder struct {
+	mock *MockfileStorageClient
 }
 
-// NewMockFileStorageClient creates a new mock instance.
-func NewMockFileStorageClient(ctrl *gomock.Controller) *MockFileStorageClient {
-	mock := &MockFileStorageClient{ctrl: ctrl}
-	mock.recorder = &MockFileStorageClientMockRecorder{mock}
+// NewMockfileStorageClient creates a new mock instance.
+func NewMockfileStorageClient(ctrl *gomock.Controller) *MockfileStorageClient {
+	mock := &MockfileStorageClient{ctrl: ctrl}
+	mock.recorder = &MockfileStorageClientMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockFileStorageClient) EXPECT() *MockFileStorageClientMockRecorder {
+func (m *MockfileStorageClient) EXPECT() *MockfileStorageClientMockRecorder {
 	return m.recorder
 }
 
 // SaveFile mocks base method.
-func (m *MockFileStorageClient) SaveFile(dirPath, fileName string, fileContent io.Reader) (*os.File, error) {
+func (m *MockfileStorageClient) SaveFile(dirPath, fileName string, fileContent io.Reader) (*os.File, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SaveFile", dirPath, fileName, fileContent)
 	ret0, _ := ret[0].(*os.File)
@@ -46,95 +46,90 @@ func (m *MockFileStorageClient) SaveFile(dirPath, fileName string, fileContent i
 }
 
 // SaveFile indicates an expected call of SaveFile.
-func (mr *MockFileStorageClientMockRecorder) SaveFile(dirPath, fileName, fileContent interface{}) *gomock.Call {
+func (mr *MockfileStorageClientMockRecorder) SaveFile(dirPath, fileName, fileContent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveFile", reflect.TypeOf((*MockFileStorageClient)(nil).SaveFile), dirPath, fileName, fileContent)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveFile", reflect.TypeOf((*MockfileStorageClient)(nil).SaveFile), dirPath, fileName, fileContent)
 }
 
-// MockBulkScanRecordClient is a mock of BulkScanRecordClient interface.
-type MockBulkScanRecordClient struct {
+// MockbulkScanRecordClient is a mock of bulkScanRecordClient interface.
+type MockbulkScanRecordClient struct {
 	ctrl     *gomock.Controller
-	recorder *MockBulkScanRecordClientMockRecorder
+	recorder *MockbulkScanRecordClientMockRecorder
 }
 
-// MockBulkScanRecordClientMockRecorder is the mock recorder for MockBulkScanRecordClient.
-type MockBulkScanRecordClientMockRecorder struct {
-	mock *MockBulkScanRecordClient
+// MockbulkScanRecordClientMockRecorder is the mock recorder for MockbulkScanRecordClient.
+type MockbulkScanRecordClientMockRecorder struct {
+	mock *MockbulkScanRecordClient
 }
 
-// NewMockBulkScanRecordClient creates a new mock instance.
-func NewMockBulkScanRecordClient(ctrl *gomock.Controller) *MockBulkScanRecordClient {
-	mock := &MockBulkScanRecordClient{ctrl: ctrl}
-	mock.recorder = &MockBulkScanRecordClientMockRecorder{mock}
+// NewMockbulkScanRecordClient creates a new mock instance.
+func NewMockbulkScanRecordClient(ctrl *gomock.Controller) *MockbulkScanRecordClient {
+	mock := &MockbulkScanRecordClient{ctrl: ctrl}
+	mock.recorder = &MockbulkScanRecordClientMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockBulkScanRecordClient) EXPECT() *MockBulkScanRecordClientMockRecorder {
+func (m *MockbulkScanRecordClient) EXPECT() *MockbulkScanRecordClientMockRecorder {
 	return m.recorder
 }
 
 // Create mocks base method.
-func (m *MockBulkScanRecordClient) Create(filePath string) (*models.BulkScanRecord, error) {
+func (m *MockbulkScanRecordClient) Create(filePath, contentHash string, allowDuplicate bool, validationPolicy models.ValidationPolicy, locationPattern string) (*models.BulkScanRecord, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", filePath)
+	ret := m.ctrl.Call(m, "Create", filePath, contentHash, allowDuplicate, validationPolicy, locationPattern)
 	ret0, _ := ret[0].(*models.BulkScanRecord)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockBulkScanRecordClientMockRecorder) Create(filePath interface{}) *gomock.Call {
+func (mr *MockbulkScanRecordClientMockRecorder) Create(filePath, contentHash, allowDuplicate, validationPolicy, locationPattern interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockBulkScanRecordClient)(nil).Create), filePath)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockbulkScanRecordClient)(nil).Create), filePath, contentHash, allowDuplicate, validationPolicy, locationPattern)
 }
 
-// GetAll mocks base method.
-func (m *MockBulkScanRecordClient) GetAll() ([]models.BulkScanRecord, error) {
+// Get mocks base method.
+func (m *MockbulkScanRecordClient) Get(bulkScanRecordID uint) (*models.BulkScanRecord, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
-	ret0, _ := ret[0].([]models.BulkScanRecord)
+	ret := m.ctrl.Call(m, "Get", bulkScanRecordID)
+	ret0, _ := ret[0].(*models.BulkScanRecord)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAll indicates an expected call of GetAll.
-func (mr *MockBulkScanRecordClientMockRecorder) GetAll() *gomock.Call {
+// Get indicates an expected call of Get.
+func (mr *MockbulkScanRecordClientMockRecorder) Get(bulkScanRecordID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockBulkScanRecordClient)(nil).GetAll))
-}
-
-// MockScanServiceClient is a mock of ScanServiceClient interface.
-type MockScanServiceClient struct {
-	ctrl     *gomock.Controller
-	recorder *MockScanServiceClientMockRecorder
-}
-
-// MockScanServiceClientMockRecorder is the mock recorder for MockScanServiceClient.
-type MockScanServiceClientMockRecorder struct {
-	mock *MockScanServiceClient
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockbulkScanRecordClient)(nil).Get), bulkScanRecordID)
 }
 
-// NewMockScanServiceClient creates a new mock instance.
-func NewMockScanServiceClient(ctrl *gomock.Controller) *MockScanServiceClient {
-	mock := &MockScanServiceClient{ctrl: ctrl}
-	mock.recorder = &MockScanServiceClientMockRecorder{mock}
-	return mock
+// GetAll mocks base method.
+func (m *MockbulkScanRecordClient) GetAll() ([]models.BulkScanRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll")
+	ret0, _ := ret[0].([]models.BulkScanRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockScanServiceClient) EXPECT() *MockScanServiceClientMockRecorder {
-	return m.recorder
+// GetAll indicates an expected call of GetAll.
+func (mr *MockbulkScanRecordClientMockRecorder) GetAll() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockbulkScanRecordClient)(nil).GetAll))
 }
 
-// ProcessFile mocks base method.
-func (m *MockScanServiceClient) ProcessFile(bulkScanRecord *models.BulkScanRecord) {
+// GetByContentHash mocks base method.
+func (m *MockbulkScanRecordClient) GetByContentHash(contentHash string) (*models.BulkScanRecord, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "ProcessFile", bulkScanRecord)
+	ret := m.ctrl.Call(m, "GetByContentHash", contentHash)
+	ret0, _ := ret[0].(*models.BulkScanRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// ProcessFile indicates an expected call of ProcessFile.
-func (mr *MockScanServiceClientMockRecorder) ProcessFile(bulkScanRecord interface{}) *gomock.Call {
+// GetByContentHash indicates an expected call of GetByContentHash.
+func (mr *MockbulkScanRecordClientMockRecorder) GetByContentHash(contentHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessFile", reflect.TypeOf((*MockScanServiceClient)(nil).ProcessFile), bulkScanRecord)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByContentHash", reflect.TypeOf((*MockbulkScanRecordClient)(nil).GetByContentHash), contentHash)
 }
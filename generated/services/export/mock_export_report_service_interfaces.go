@@ -34,6 +34,21 @@ func (m *MockexportReportRecordClient) EXPECT() *MockexportReportRecordClientMoc
 	return m.recorder
 }
 
+// Get mocks base method.
+func (m *MockexportReportRecordClient) Get(exportReportRecordID uint) (*models.ExportReportRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", exportReportRecordID)
+	ret0, _ := ret[0].(*models.ExportReportRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockexportReportRecordClientMockRecorder) Get(exportReportRecordID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockexportReportRecordClient)(nil).Get), exportReportRecordID)
+}
+
 // Update mocks base method.
 func (m *MockexportReportRecordClient) Update(exportReportRecord *models.ExportReportRecord) error {
 	m.ctrl.T.Helper()
@@ -48,6 +63,44 @@ func (mr *MockexportReportRecordClientMockRecorder) Update(exportReportRecord in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockexportReportRecordClient)(nil).Update), exportReportRecord)
 }
 
+// MockreportRecordClient is a mock of reportRecordClient interface.
+type MockreportRecordClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockreportRecordClientMockRecorder
+}
+
+// MockreportRecordClientMockRecorder is the mock recorder for MockreportRecordClient.
+type MockreportRecordClientMockRecorder struct {
+	mock *MockreportRecordClient
+}
+
+// NewMockreportRecordClient creates a new mock instance.
+func NewMockreportRecordClient(ctrl *gomock.Controller) *MockreportRecordClient {
+	mock := &MockreportRecordClient{ctrl: ctrl}
+	mock.recorder = &MockreportRecordClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockreportRecordClient) EXPECT() *MockreportRecordClientMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockreportRecordClient) Get(reportRecordID uint) (*models.ReportRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", reportRecordID)
+	ret0, _ := ret[0].(*models.ReportRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockreportRecordClientMockRecorder) Get(reportRecordID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockreportRecordClient)(nil).Get), reportRecordID)
+}
+
 // MockcomparisonDataClient is a mock of comparisonDataClient interface.
 type MockcomparisonDataClient struct {
 	ctrl     *gomock.Controller
@@ -71,17 +124,140 @@ func (m *MockcomparisonDataClient) EXPECT() *MockcomparisonDataClientMockRecorde
 	return m.recorder
 }
 
-// GetAllPaginated mocks base method.
-func (m *MockcomparisonDataClient) GetAllPaginated(reportRecordID uint, limit, offset int) ([]models.ComparisonData, error) {
+// GetPage mocks base method.
+func (m *MockcomparisonDataClient) GetPage(reportRecordID uint, pageQuery models.ComparisonDataPageQuery) ([]models.ComparisonData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllPaginated", reportRecordID, limit, offset)
+	ret := m.ctrl.Call(m, "GetPage", reportRecordID, pageQuery)
 	ret0, _ := ret[0].([]models.ComparisonData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAllPaginated indicates an expected call of GetAllPaginated.
-func (mr *MockcomparisonDataClientMockRecorder) GetAllPaginated(reportRecordID, limit, offset interface{}) *gomock.Call {
+// GetPage indicates an expected call of GetPage.
+func (mr *MockcomparisonDataClientMockRecorder) GetPage(reportRecordID, pageQuery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPage", reflect.TypeOf((*MockcomparisonDataClient)(nil).GetPage), reportRecordID, pageQuery)
+}
+
+// GetReportDiff mocks base method.
+func (m *MockcomparisonDataClient) GetReportDiff(baselineReportRecordID, reportRecordID uint, diffQuery models.ReportDiffQuery) ([]models.ReportDiffEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReportDiff", baselineReportRecordID, reportRecordID, diffQuery)
+	ret0, _ := ret[0].([]models.ReportDiffEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReportDiff indicates an expected call of GetReportDiff.
+func (mr *MockcomparisonDataClientMockRecorder) GetReportDiff(baselineReportRecordID, reportRecordID, diffQuery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReportDiff", reflect.TypeOf((*MockcomparisonDataClient)(nil).GetReportDiff), baselineReportRecordID, reportRecordID, diffQuery)
+}
+
+// GetResults mocks base method.
+func (m *MockcomparisonDataClient) GetResults(reportRecordID uint, locations []string) (map[string]models.ScanComparisonOutcome, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResults", reportRecordID, locations)
+	ret0, _ := ret[0].(map[string]models.ScanComparisonOutcome)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResults indicates an expected call of GetResults.
+func (mr *MockcomparisonDataClientMockRecorder) GetResults(reportRecordID, locations interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResults", reflect.TypeOf((*MockcomparisonDataClient)(nil).GetResults), reportRecordID, locations)
+}
+
+// MockreportWriter is a mock of reportWriter interface.
+type MockreportWriter struct {
+	ctrl     *gomock.Controller
+	recorder *MockreportWriterMockRecorder
+}
+
+// MockreportWriterMockRecorder is the mock recorder for MockreportWriter.
+type MockreportWriterMockRecorder struct {
+	mock *MockreportWriter
+}
+
+// NewMockreportWriter creates a new mock instance.
+func NewMockreportWriter(ctrl *gomock.Controller) *MockreportWriter {
+	mock := &MockreportWriter{ctrl: ctrl}
+	mock.recorder = &MockreportWriterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockreportWriter) EXPECT() *MockreportWriterMockRecorder {
+	return m.recorder
+}
+
+// close mocks base method.
+func (m *MockreportWriter) close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// close indicates an expected call of close.
+func (mr *MockreportWriterMockRecorder) close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "close", reflect.TypeOf((*MockreportWriter)(nil).close))
+}
+
+// flush mocks base method.
+func (m *MockreportWriter) flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// flush indicates an expected call of flush.
+func (mr *MockreportWriterMockRecorder) flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "flush", reflect.TypeOf((*MockreportWriter)(nil).flush))
+}
+
+// write mocks base method.
+func (m *MockreportWriter) write(comparisonData models.ComparisonData) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "write", comparisonData)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// write indicates an expected call of write.
+func (mr *MockreportWriterMockRecorder) write(comparisonData interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "write", reflect.TypeOf((*MockreportWriter)(nil).write), comparisonData)
+}
+
+// writeEnd mocks base method.
+func (m *MockreportWriter) writeEnd() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "writeEnd")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// writeEnd indicates an expected call of writeEnd.
+func (mr *MockreportWriterMockRecorder) writeEnd() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "writeEnd", reflect.TypeOf((*MockreportWriter)(nil).writeEnd))
+}
+
+// writeStart mocks base method.
+func (m *MockreportWriter) writeStart() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "writeStart")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// writeStart indicates an expected call of writeStart.
+func (mr *MockreportWriterMockRecorder) writeStart() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllPaginated", reflect.TypeOf((*MockcomparisonDataClient)(nil).GetAllPaginated), reportRecordID, limit, offset)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "writeStart", reflect.TypeOf((*MockreportWriter)(nil).writeStart))
 }
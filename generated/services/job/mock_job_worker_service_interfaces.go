@@ -0,0 +1,107 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/services/job/job_worker_service.go
+
+// Package mockjobworkerservice is a generated GoMock package.
+package mockjobworkerservice
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/habbas99/dexory/internal/models"
+)
+
+// MockjobClient is a mock of jobClient interface.
+type MockjobClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockjobClientMockRecorder
+}
+
+// MockjobClientMockRecorder is the mock recorder for MockjobClient.
+type MockjobClientMockRecorder struct {
+	mock *MockjobClient
+}
+
+// NewMockjobClient creates a new mock instance.
+func NewMockjobClient(ctrl *gomock.Controller) *MockjobClient {
+	mock := &MockjobClient{ctrl: ctrl}
+	mock.recorder = &MockjobClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockjobClient) EXPECT() *MockjobClientMockRecorder {
+	return m.recorder
+}
+
+// Claim mocks base method.
+func (m *MockjobClient) Claim(workerID string, leaseTimeout time.Duration, maxAttempts int) (*models.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Claim", workerID, leaseTimeout, maxAttempts)
+	ret0, _ := ret[0].(*models.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Claim indicates an expected call of Claim.
+func (mr *MockjobClientMockRecorder) Claim(workerID, leaseTimeout, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Claim", reflect.TypeOf((*MockjobClient)(nil).Claim), workerID, leaseTimeout, maxAttempts)
+}
+
+// Complete mocks base method.
+func (m *MockjobClient) Complete(job *models.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockjobClientMockRecorder) Complete(job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockjobClient)(nil).Complete), job)
+}
+
+// Fail mocks base method.
+func (m *MockjobClient) Fail(job *models.Job, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fail", job, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Fail indicates an expected call of Fail.
+func (mr *MockjobClientMockRecorder) Fail(job, lastError interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fail", reflect.TypeOf((*MockjobClient)(nil).Fail), job, lastError)
+}
+
+// Heartbeat mocks base method.
+func (m *MockjobClient) Heartbeat(job *models.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Heartbeat", job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Heartbeat indicates an expected call of Heartbeat.
+func (mr *MockjobClientMockRecorder) Heartbeat(job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Heartbeat", reflect.TypeOf((*MockjobClient)(nil).Heartbeat), job)
+}
+
+// Retry mocks base method.
+func (m *MockjobClient) Retry(job *models.Job, runAt time.Time, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Retry", job, runAt, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Retry indicates an expected call of Retry.
+func (mr *MockjobClientMockRecorder) Retry(job, runAt, lastError interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Retry", reflect.TypeOf((*MockjobClient)(nil).Retry), job, runAt, lastError)
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: internal/services/report/comparison_data_service.go
+// Source: internal/services/comparison/comparison_data_service.go
 
 // Package mockcomparisondataservice is a generated GoMock package.
 package mockcomparisondataservice
@@ -11,106 +11,121 @@ import (
 	models "github.com/habbas99/dexory/internal/models"
 )
 
-// MockScanClient is a mock of ScanClient interface.
-type MockScanClient struct {
+// MockscanClient is a mock of scanClient interface.
+type MockscanClient struct {
 	ctrl     *gomock.Controller
-	recorder *MockScanClientMockRecorder
+	recorder *MockscanClientMockRecorder
 }
 
-// MockScanClientMockRecorder is the mock recorder for MockScanClient.
-type MockScanClientMockRecorder struct {
-	mock *MockScanClient
+// MockscanClientMockRecorder is the mock recorder for MockscanClient.
+type MockscanClientMockRecorder struct {
+	mock *MockscanClient
 }
 
-// NewMockScanClient creates a new mock instance.
-func NewMockScanClient(ctrl *gomock.Controller) *MockScanClient {
-	mock := &MockScanClient{ctrl: ctrl}
-	mock.recorder = &MockScanClientMockRecorder{mock}
+// NewMockscanClient creates a new mock instance.
+func NewMockscanClient(ctrl *gomock.Controller) *MockscanClient {
+	mock := &MockscanClient{ctrl: ctrl}
+	mock.recorder = &MockscanClientMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockScanClient) EXPECT() *MockScanClientMockRecorder {
+func (m *MockscanClient) EXPECT() *MockscanClientMockRecorder {
 	return m.recorder
 }
 
-// Get mocks base method.
-func (m *MockScanClient) Get(bulkScanRecordID uint, location string) (*models.Scan, error) {
+// GetBatch mocks base method.
+func (m *MockscanClient) GetBatch(bulkScanRecordID, afterID uint, limit int) ([]models.Scan, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", bulkScanRecordID, location)
-	ret0, _ := ret[0].(*models.Scan)
+	ret := m.ctrl.Call(m, "GetBatch", bulkScanRecordID, afterID, limit)
+	ret0, _ := ret[0].([]models.Scan)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Get indicates an expected call of Get.
-func (mr *MockScanClientMockRecorder) Get(bulkScanRecordID, location interface{}) *gomock.Call {
+// GetBatch indicates an expected call of GetBatch.
+func (mr *MockscanClientMockRecorder) GetBatch(bulkScanRecordID, afterID, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockScanClient)(nil).Get), bulkScanRecordID, location)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBatch", reflect.TypeOf((*MockscanClient)(nil).GetBatch), bulkScanRecordID, afterID, limit)
 }
 
-// MockComparisonDataClient is a mock of ComparisonDataClient interface.
-type MockComparisonDataClient struct {
+// MockcomparisonDataClient is a mock of comparisonDataClient interface.
+type MockcomparisonDataClient struct {
 	ctrl     *gomock.Controller
-	recorder *MockComparisonDataClientMockRecorder
+	recorder *MockcomparisonDataClientMockRecorder
 }
 
-// MockComparisonDataClientMockRecorder is the mock recorder for MockComparisonDataClient.
-type MockComparisonDataClientMockRecorder struct {
-	mock *MockComparisonDataClient
+// MockcomparisonDataClientMockRecorder is the mock recorder for MockcomparisonDataClient.
+type MockcomparisonDataClientMockRecorder struct {
+	mock *MockcomparisonDataClient
 }
 
-// NewMockComparisonDataClient creates a new mock instance.
-func NewMockComparisonDataClient(ctrl *gomock.Controller) *MockComparisonDataClient {
-	mock := &MockComparisonDataClient{ctrl: ctrl}
-	mock.recorder = &MockComparisonDataClientMockRecorder{mock}
+// NewMockcomparisonDataClient creates a new mock instance.
+func NewMockcomparisonDataClient(ctrl *gomock.Controller) *MockcomparisonDataClient {
+	mock := &MockcomparisonDataClient{ctrl: ctrl}
+	mock.recorder = &MockcomparisonDataClientMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockComparisonDataClient) EXPECT() *MockComparisonDataClientMockRecorder {
+func (m *MockcomparisonDataClient) EXPECT() *MockcomparisonDataClientMockRecorder {
 	return m.recorder
 }
 
-// Create mocks base method.
-func (m *MockComparisonDataClient) Create(comparisonData *models.ComparisonData) error {
+// CreateAllInTransaction mocks base method.
+func (m *MockcomparisonDataClient) CreateAllInTransaction(reportRecordID uint, fn func(func([]models.ComparisonData) error) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", comparisonData)
+	ret := m.ctrl.Call(m, "CreateAllInTransaction", reportRecordID, fn)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Create indicates an expected call of Create.
-func (mr *MockComparisonDataClientMockRecorder) Create(comparisonData interface{}) *gomock.Call {
+// CreateAllInTransaction indicates an expected call of CreateAllInTransaction.
+func (mr *MockcomparisonDataClientMockRecorder) CreateAllInTransaction(reportRecordID, fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockComparisonDataClient)(nil).Create), comparisonData)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAllInTransaction", reflect.TypeOf((*MockcomparisonDataClient)(nil).CreateAllInTransaction), reportRecordID, fn)
 }
 
-// MockReportRecordClient is a mock of ReportRecordClient interface.
-type MockReportRecordClient struct {
+// MockreportRecordClient is a mock of reportRecordClient interface.
+type MockreportRecordClient struct {
 	ctrl     *gomock.Controller
-	recorder *MockReportRecordClientMockRecorder
+	recorder *MockreportRecordClientMockRecorder
 }
 
-// MockReportRecordClientMockRecorder is the mock recorder for MockReportRecordClient.
-type MockReportRecordClientMockRecorder struct {
-	mock *MockReportRecordClient
+// MockreportRecordClientMockRecorder is the mock recorder for MockreportRecordClient.
+type MockreportRecordClientMockRecorder struct {
+	mock *MockreportRecordClient
 }
 
-// NewMockReportRecordClient creates a new mock instance.
-func NewMockReportRecordClient(ctrl *gomock.Controller) *MockReportRecordClient {
-	mock := &MockReportRecordClient{ctrl: ctrl}
-	mock.recorder = &MockReportRecordClientMockRecorder{mock}
+// NewMockreportRecordClient creates a new mock instance.
+func NewMockreportRecordClient(ctrl *gomock.Controller) *MockreportRecordClient {
+	mock := &MockreportRecordClient{ctrl: ctrl}
+	mock.recorder = &MockreportRecordClientMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockReportRecordClient) EXPECT() *MockReportRecordClientMockRecorder {
+func (m *MockreportRecordClient) EXPECT() *MockreportRecordClientMockRecorder {
 	return m.recorder
 }
 
+// Get mocks base method.
+func (m *MockreportRecordClient) Get(reportRecordID uint) (*models.ReportRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", reportRecordID)
+	ret0, _ := ret[0].(*models.ReportRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockreportRecordClientMockRecorder) Get(reportRecordID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockreportRecordClient)(nil).Get), reportRecordID)
+}
+
 // Update mocks base method.
-func (m *MockReportRecordClient) Update(reportRecord *models.ReportRecord) error {
+func (m *MockreportRecordClient) Update(reportRecord *models.ReportRecord) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Update", reportRecord)
 	ret0, _ := ret[0].(error)
@@ -118,7 +133,45 @@ func (m *MockReportRecordClient) Update(reportRecord *models.ReportRecord) error
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockReportRecordClientMockRecorder) Update(reportRecord interface{}) *gomock.Call {
+func (mr *MockreportRecordClientMockRecorder) Update(reportRecord interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockreportRecordClient)(nil).Update), reportRecord)
+}
+
+// MocklocationParser is a mock of locationParser interface.
+type MocklocationParser struct {
+	ctrl     *gomock.Controller
+	recorder *MocklocationParserMockRecorder
+}
+
+// MocklocationParserMockRecorder is the mock recorder for MocklocationParser.
+type MocklocationParserMockRecorder struct {
+	mock *MocklocationParser
+}
+
+// NewMocklocationParser creates a new mock instance.
+func NewMocklocationParser(ctrl *gomock.Controller) *MocklocationParser {
+	mock := &MocklocationParser{ctrl: ctrl}
+	mock.recorder = &MocklocationParserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocklocationParser) EXPECT() *MocklocationParserMockRecorder {
+	return m.recorder
+}
+
+// Parse mocks base method.
+func (m *MocklocationParser) Parse(location string) (models.LocationHierarchy, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Parse", location)
+	ret0, _ := ret[0].(models.LocationHierarchy)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Parse indicates an expected call of Parse.
+func (mr *MocklocationParserMockRecorder) Parse(location interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockReportRecordClient)(nil).Update), reportRecord)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Parse", reflect.TypeOf((*MocklocationParser)(nil).Parse), location)
 }
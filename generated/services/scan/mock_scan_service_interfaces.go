@@ -5,6 +5,7 @@
 package mockscanservice
 
 import (
+	os "os"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,18 +35,94 @@ func (m *MockscanClient) EXPECT() *MockscanClientMockRecorder {
 	return m.recorder
 }
 
-// CreateAll mocks base method.
-func (m *MockscanClient) CreateAll(scans []models.Scan) error {
+// CopyAllInTransaction mocks base method.
+func (m *MockscanClient) CopyAllInTransaction(bulkScanRecordID uint, fn func(func([]models.Scan) (int64, error)) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAll", scans)
+	ret := m.ctrl.Call(m, "CopyAllInTransaction", bulkScanRecordID, fn)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// CreateAll indicates an expected call of CreateAll.
-func (mr *MockscanClientMockRecorder) CreateAll(scans interface{}) *gomock.Call {
+// CopyAllInTransaction indicates an expected call of CopyAllInTransaction.
+func (mr *MockscanClientMockRecorder) CopyAllInTransaction(bulkScanRecordID, fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAll", reflect.TypeOf((*MockscanClient)(nil).CreateAll), scans)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyAllInTransaction", reflect.TypeOf((*MockscanClient)(nil).CopyAllInTransaction), bulkScanRecordID, fn)
+}
+
+// MockfileStorageClient is a mock of fileStorageClient interface.
+type MockfileStorageClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockfileStorageClientMockRecorder
+}
+
+// MockfileStorageClientMockRecorder is the mock recorder for MockfileStorageClient.
+type MockfileStorageClientMockRecorder struct {
+	mock *MockfileStorageClient
+}
+
+// NewMockfileStorageClient creates a new mock instance.
+func NewMockfileStorageClient(ctrl *gomock.Controller) *MockfileStorageClient {
+	mock := &MockfileStorageClient{ctrl: ctrl}
+	mock.recorder = &MockfileStorageClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockfileStorageClient) EXPECT() *MockfileStorageClientMockRecorder {
+	return m.recorder
+}
+
+// CreateFile mocks base method.
+func (m *MockfileStorageClient) CreateFile(dirPath, fileName string) (*os.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFile", dirPath, fileName)
+	ret0, _ := ret[0].(*os.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFile indicates an expected call of CreateFile.
+func (mr *MockfileStorageClientMockRecorder) CreateFile(dirPath, fileName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFile", reflect.TypeOf((*MockfileStorageClient)(nil).CreateFile), dirPath, fileName)
+}
+
+// MocklocationParser is a mock of locationParser interface.
+type MocklocationParser struct {
+	ctrl     *gomock.Controller
+	recorder *MocklocationParserMockRecorder
+}
+
+// MocklocationParserMockRecorder is the mock recorder for MocklocationParser.
+type MocklocationParserMockRecorder struct {
+	mock *MocklocationParser
+}
+
+// NewMocklocationParser creates a new mock instance.
+func NewMocklocationParser(ctrl *gomock.Controller) *MocklocationParser {
+	mock := &MocklocationParser{ctrl: ctrl}
+	mock.recorder = &MocklocationParserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocklocationParser) EXPECT() *MocklocationParserMockRecorder {
+	return m.recorder
+}
+
+// Parse mocks base method.
+func (m *MocklocationParser) Parse(location string) (models.LocationHierarchy, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Parse", location)
+	ret0, _ := ret[0].(models.LocationHierarchy)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Parse indicates an expected call of Parse.
+func (mr *MocklocationParserMockRecorder) Parse(location interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Parse", reflect.TypeOf((*MocklocationParser)(nil).Parse), location)
 }
 
 // MockbulkScanRecordClient is a mock of bulkScanRecordClient interface.
@@ -71,6 +148,21 @@ func (m *MockbulkScanRecordClient) EXPECT() *MockbulkScanRecordClientMockRecorde
 	return m.recorder
 }
 
+// Get mocks base method.
+func (m *MockbulkScanRecordClient) Get(bulkScanRecordID uint) (*models.BulkScanRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", bulkScanRecordID)
+	ret0, _ := ret[0].(*models.BulkScanRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockbulkScanRecordClientMockRecorder) Get(bulkScanRecordID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockbulkScanRecordClient)(nil).Get), bulkScanRecordID)
+}
+
 // Update mocks base method.
 func (m *MockbulkScanRecordClient) Update(bulkScanRecord *models.BulkScanRecord) error {
 	m.ctrl.T.Helper()
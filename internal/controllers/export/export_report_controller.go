@@ -6,55 +6,92 @@ import (
 	"github.com/habbas99/dexory/internal/models"
 	"github.com/habbas99/dexory/internal/utilities"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
+type exportFilter struct {
+	Results                []string `json:"results,omitempty"`
+	DiscrepanciesOnly      bool     `json:"discrepanciesOnly,omitempty"`
+	LocationPrefix         string   `json:"locationPrefix,omitempty"`
+	Scanned                *bool    `json:"scanned,omitempty"`
+	Occupied               *bool    `json:"occupied,omitempty"`
+	Columns                []string `json:"columns,omitempty"`
+	BaselineReportRecordID *uint    `json:"baselineReportRecordId,omitempty"`
+}
+
 type exportReportRecordRequest struct {
-	ReportRecordID uint   `json:"reportRecordId"`
-	ReportType     string `json:"reportType"`
+	ReportRecordID uint          `json:"reportRecordId"`
+	ReportType     string        `json:"reportType"`
+	Filter         *exportFilter `json:"filter"`
 }
 
 type exportReportRecordResponse struct {
-	ID       uint   `json:"id"`
-	FileName string `json:"fileName"`
-	Status   string `json:"status"`
+	ID                 uint          `json:"id"`
+	FileName           string        `json:"fileName"`
+	ReportType         string        `json:"reportType"`
+	Status             string        `json:"status"`
+	Filter             *exportFilter `json:"filter,omitempty"`
+	ErrorCode          string        `json:"errorCode,omitempty"`
+	ErrorMessage       string        `json:"errorMessage,omitempty"`
+	FailedRecordNumber int           `json:"failedRecordNumber,omitempty"`
+}
+
+// streamContentTypes are the content types of the report types a report can be streamed as.
+var streamContentTypes = map[models.ExportReportType]string{
+	models.ExportReportJson:   "application/json",
+	models.ExportReportNdjson: "application/x-ndjson",
+	models.ExportReportCsv:    "text/csv",
 }
 
+// streamErrorTrailer is the trailer telling a client that a streamed report was cut short, the status of the
+// response is sent before the report is read.
+const streamErrorTrailer = "X-Stream-Error"
+
 type fileStorageClient interface {
 	CreateFile(dirPath, fileName string) (*os.File, error)
 }
 
 type exportReportRecordClient interface {
 	GetAll(reportRecordID uint) ([]models.ExportReportRecord, error)
-	Create(reportRecordID uint, filePath, reportType string) (*models.ExportReportRecord, error)
+	Create(reportRecordID uint, filePath, reportType string, filter models.ExportFilter) (*models.ExportReportRecord, error)
 	Get(exportReportRecordID uint) (*models.ExportReportRecord, error)
-	GetByReportType(reportRecordID uint, reportType string) (*models.ExportReportRecord, error)
+	GetByFilter(reportRecordID uint, reportType string, filterKey string) (*models.ExportReportRecord, error)
+}
+
+type reportRecordClient interface {
+	Get(reportRecordID uint) (*models.ReportRecord, error)
 }
 
-type exportReportServiceClient interface {
-	ExportReport(exportReportRecord *models.ExportReportRecord)
+type reportStreamClient interface {
+	StreamReport(reportRecordID uint, reportType models.ExportReportType, filter models.ExportFilter, w io.Writer) error
 }
 
 type ExportReportController struct {
-	dirPath                   string
-	fileStorageClient         fileStorageClient
-	exportReportRecordClient  exportReportRecordClient
-	exportReportServiceClient exportReportServiceClient
+	dirPath                  string
+	fileStorageClient        fileStorageClient
+	exportReportRecordClient exportReportRecordClient
+	reportRecordClient       reportRecordClient
+	reportStreamClient       reportStreamClient
 }
 
 func NewExportReportController(
 	dirPath string,
 	fileStorageClient fileStorageClient,
 	exportReportRecordClient exportReportRecordClient,
-	exportReportServiceClient exportReportServiceClient,
+	reportRecordClient reportRecordClient,
+	reportStreamClient reportStreamClient,
 ) *ExportReportController {
 	return &ExportReportController{
-		dirPath:                   dirPath,
-		fileStorageClient:         fileStorageClient,
-		exportReportRecordClient:  exportReportRecordClient,
-		exportReportServiceClient: exportReportServiceClient,
+		dirPath:                  dirPath,
+		fileStorageClient:        fileStorageClient,
+		exportReportRecordClient: exportReportRecordClient,
+		reportRecordClient:       reportRecordClient,
+		reportStreamClient:       reportStreamClient,
 	}
 }
 
@@ -79,9 +116,14 @@ func (er *ExportReportController) GetExportReportRecords(c *gin.Context) {
 	exportReportRecordResponses := []exportReportRecordResponse{}
 	for _, exportReportRecord := range exportReportRecords {
 		response := exportReportRecordResponse{
-			ID:       exportReportRecord.ID,
-			FileName: exportReportRecord.FileName,
-			Status:   string(exportReportRecord.Status),
+			ID:                 exportReportRecord.ID,
+			FileName:           exportReportRecord.FileName,
+			ReportType:         string(exportReportRecord.ReportType),
+			Status:             string(exportReportRecord.Status),
+			Filter:             toExportFilterResponse(exportReportRecord.Filter),
+			ErrorCode:          string(exportReportRecord.ErrorCode),
+			ErrorMessage:       exportReportRecord.ErrorMessage,
+			FailedRecordNumber: exportReportRecord.FailedRecordNumber,
 		}
 		exportReportRecordResponses = append(exportReportRecordResponses, response)
 	}
@@ -106,12 +148,24 @@ func (er *ExportReportController) CreateExportReportRecord(c *gin.Context) {
 		"export_report_type": reportType,
 	}).Info("received request to export report")
 
-	if reportType != string(models.ExportReportJson) {
+	if !isSupportedReportType(reportType) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "report type not supported"})
 		return
 	}
 
-	exportReportRecord, err := er.exportReportRecordClient.GetByReportType(reportRecordID, reportType)
+	filter, message := toExportFilter(exportReportRecordReq.Filter)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	if !er.validateBaselineReportRecord(c, reportRecordID, filter) {
+		return
+	}
+
+	// every filter of a report is its own export, asking for the same filter again returns the existing export
+	filterKey := filter.Key()
+	exportReportRecord, err := er.exportReportRecordClient.GetByFilter(reportRecordID, reportType, filterKey)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find export report record"})
 		return
@@ -122,24 +176,277 @@ func (er *ExportReportController) CreateExportReportRecord(c *gin.Context) {
 		return
 	}
 
-	savedFile, err := er.fileStorageClient.CreateFile(er.dirPath, fmt.Sprintf("report_%d.%s", reportRecordID, reportType))
+	savedFile, err := er.fileStorageClient.CreateFile(er.dirPath, exportFileName(reportRecordID, reportType, filterKey))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create report file"})
 		return
 	}
 
-	exportReportRecord, err = er.exportReportRecordClient.Create(reportRecordID, savedFile.Name(), reportType)
+	// the record is saved together with the job that writes the export
+	exportReportRecord, err = er.exportReportRecordClient.Create(reportRecordID, savedFile.Name(), reportType, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export report record"})
 		return
 	}
 
-	// start a go routine to export report
-	go er.exportReportServiceClient.ExportReport(exportReportRecord)
-
 	c.JSON(http.StatusOK, gin.H{"id": exportReportRecord.ID})
 }
 
+func isSupportedReportType(reportType string) bool {
+	switch models.ExportReportType(reportType) {
+	case models.ExportReportJson, models.ExportReportCsv, models.ExportReportXlsx, models.ExportReportPdf:
+		return true
+	default:
+		return false
+	}
+}
+
+// StreamReport streams a completed report straight into the response in the format of the query, without creating
+// an export. The report is read a batch at a time and sent with chunked transfer encoding. The query can filter the
+// report like an export, with repeated result and column parameters.
+func (er *ExportReportController) StreamReport(c *gin.Context) {
+	id := c.Param("id")
+	format := c.DefaultQuery("format", string(models.ExportReportJson))
+
+	log.WithFields(log.Fields{
+		"report_record_id": id,
+		"format":           format,
+	}).Info("received request to stream report")
+
+	reportRecordID, err := utilities.ToUint(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid report record id"})
+		return
+	}
+
+	contentType, ok := streamContentTypes[models.ExportReportType(format)]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format not supported"})
+		return
+	}
+
+	filterRequest, message := queryExportFilter(c)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	filter, message := toExportFilter(filterRequest)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	reportRecord, err := er.reportRecordClient.Get(reportRecordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find report record"})
+		return
+	}
+
+	if reportRecord.Status != models.Completed {
+		c.JSON(http.StatusAccepted, gin.H{"error": "report is not available for download"})
+		return
+	}
+
+	if !er.validateBaselineReportRecord(c, reportRecordID, filter) {
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=report_%d.%s", reportRecordID, format))
+	c.Header("Trailer", streamErrorTrailer)
+	c.Status(http.StatusOK)
+
+	err = er.reportStreamClient.StreamReport(reportRecordID, models.ExportReportType(format), filter, c.Writer)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"report_record_id": reportRecordID,
+		}).Errorf("failed to stream report, error: %v", err)
+
+		c.Writer.Header().Set(streamErrorTrailer, "failed to stream report")
+	}
+}
+
+// validateBaselineReportRecord writes the error response and returns false when the report can not be compared
+// against the baseline report of the filter. Only completed comparison reports of the same warehouse have
+// discrepancies to compare.
+func (er *ExportReportController) validateBaselineReportRecord(c *gin.Context, reportRecordID uint, filter models.ExportFilter) bool {
+	if filter.BaselineReportRecordID == nil {
+		return true
+	}
+
+	if *filter.BaselineReportRecordID == reportRecordID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a report can not be compared against itself"})
+		return false
+	}
+
+	var reportRecords []*models.ReportRecord
+	for _, id := range []uint{*filter.BaselineReportRecordID, reportRecordID} {
+		reportRecord, err := er.reportRecordClient.Get(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find report record"})
+			return false
+		}
+
+		if reportRecord.Mode == models.ScanDiffReport || reportRecord.Status != models.Completed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("report=%d is not a completed comparison report", id)})
+			return false
+		}
+		reportRecords = append(reportRecords, reportRecord)
+	}
+
+	if !reportRecords[0].SameWarehouse(*reportRecords[1]) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("report=%d is not of the same warehouse as baseline report=%d", reportRecordID, *filter.BaselineReportRecordID)})
+		return false
+	}
+
+	return true
+}
+
+// queryExportFilter reads the filter of a streamed report from the query.
+func queryExportFilter(c *gin.Context) (*exportFilter, string) {
+	filter := &exportFilter{
+		Results:        c.QueryArray("result"),
+		LocationPrefix: c.Query("locationPrefix"),
+		Columns:        c.QueryArray("column"),
+	}
+
+	if value, ok := c.GetQuery("baseline"); ok {
+		baselineReportRecordID, err := utilities.ToUint(value)
+		if err != nil {
+			return nil, "baseline must be a report id"
+		}
+		filter.BaselineReportRecordID = &baselineReportRecordID
+	}
+
+	discrepanciesOnly, message := queryFlag(c, "discrepanciesOnly")
+	if message != "" {
+		return nil, message
+	}
+	filter.DiscrepanciesOnly = discrepanciesOnly != nil && *discrepanciesOnly
+
+	filter.Scanned, message = queryFlag(c, "scanned")
+	if message != "" {
+		return nil, message
+	}
+
+	filter.Occupied, message = queryFlag(c, "occupied")
+	if message != "" {
+		return nil, message
+	}
+
+	return filter, ""
+}
+
+// queryFlag reads a true or false query parameter, nil when the query does not have it.
+func queryFlag(c *gin.Context, name string) (*bool, string) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return nil, ""
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Sprintf("%s must be true or false", name)
+	}
+
+	return &parsed, ""
+}
+
+// toExportFilter validates the filter of an export request. Results and columns are put in their canonical order so
+// equal filters have the same key, selecting every column is the same as selecting none.
+func toExportFilter(request *exportFilter) (models.ExportFilter, string) {
+	if request == nil {
+		return models.ExportFilter{}, ""
+	}
+
+	filter := models.ExportFilter{
+		DiscrepanciesOnly:      request.DiscrepanciesOnly,
+		LocationPrefix:         strings.TrimSpace(request.LocationPrefix),
+		Scanned:                request.Scanned,
+		Occupied:               request.Occupied,
+		BaselineReportRecordID: request.BaselineReportRecordID,
+	}
+
+	for _, result := range request.Results {
+		if !models.ScanComparisonOutcome(result).IsValid() {
+			return models.ExportFilter{}, fmt.Sprintf("unknown result=%s", result)
+		}
+	}
+	for _, outcome := range models.AllOutcomes() {
+		if containsString(request.Results, string(outcome)) {
+			filter.Results = append(filter.Results, string(outcome))
+		}
+	}
+
+	availableColumns := filter.AvailableColumns()
+	for _, column := range request.Columns {
+		if isExportColumn(availableColumns, column) {
+			continue
+		}
+		if isExportColumn(models.ReportDiffColumns, column) {
+			return models.ExportFilter{}, fmt.Sprintf("column=%s needs a baselineReportRecordId", column)
+		}
+		return models.ExportFilter{}, fmt.Sprintf("unknown column=%s", column)
+	}
+	for _, column := range availableColumns {
+		if containsString(request.Columns, string(column)) {
+			filter.Columns = append(filter.Columns, string(column))
+		}
+	}
+	if len(filter.Columns) == len(availableColumns) {
+		filter.Columns = nil
+	}
+
+	return filter, ""
+}
+
+func toExportFilterResponse(filter models.ExportFilter) *exportFilter {
+	if filter.IsEmpty() {
+		return nil
+	}
+
+	return &exportFilter{
+		Results:                filter.Results,
+		DiscrepanciesOnly:      filter.DiscrepanciesOnly,
+		LocationPrefix:         filter.LocationPrefix,
+		Scanned:                filter.Scanned,
+		Occupied:               filter.Occupied,
+		Columns:                filter.Columns,
+		BaselineReportRecordID: filter.BaselineReportRecordID,
+	}
+}
+
+// exportFileName names the file of an export, the files of filtered exports of a report are told apart by their
+// filter key.
+func exportFileName(reportRecordID uint, reportType string, filterKey string) string {
+	if filterKey == "" {
+		return fmt.Sprintf("report_%d.%s", reportRecordID, reportType)
+	}
+
+	return fmt.Sprintf("report_%d_%s.%s", reportRecordID, filterKey[:12], reportType)
+}
+
+func isExportColumn(columns []models.ExportColumn, name string) bool {
+	for _, column := range columns {
+		if string(column) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (er *ExportReportController) DownloadReport(c *gin.Context) {
 	id := c.Param("id")
 
@@ -1,28 +1,30 @@
 package export
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	mockexportreportcontroller "github.com/habbas99/dexory/generated/controllers/export"
 	"github.com/habbas99/dexory/internal/models"
 	"github.com/stretchr/testify/suite"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 	"testing"
 )
 
 type ExportReportControllerTestSuite struct {
 	suite.Suite
-	mockFileStorageClient         *mockexportreportcontroller.MockfileStorageClient
-	mockExportReportRecordClient  *mockexportreportcontroller.MockexportReportRecordClient
-	mockExportReportServiceClient *mockexportreportcontroller.MockexportReportServiceClient
-	exportReportController        *ExportReportController
-	ctrl                          *gomock.Controller
+	mockFileStorageClient        *mockexportreportcontroller.MockfileStorageClient
+	mockExportReportRecordClient *mockexportreportcontroller.MockexportReportRecordClient
+	mockReportRecordClient       *mockexportreportcontroller.MockreportRecordClient
+	mockReportStreamClient       *mockexportreportcontroller.MockreportStreamClient
+	exportReportController       *ExportReportController
+	ctrl                         *gomock.Controller
 }
 
 func TestExportReportControllerTestSuite(t *testing.T) {
@@ -35,7 +37,8 @@ func (suite *ExportReportControllerTestSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 	suite.mockFileStorageClient = mockexportreportcontroller.NewMockfileStorageClient(suite.ctrl)
 	suite.mockExportReportRecordClient = mockexportreportcontroller.NewMockexportReportRecordClient(suite.ctrl)
-	suite.mockExportReportServiceClient = mockexportreportcontroller.NewMockexportReportServiceClient(suite.ctrl)
+	suite.mockReportRecordClient = mockexportreportcontroller.NewMockreportRecordClient(suite.ctrl)
+	suite.mockReportStreamClient = mockexportreportcontroller.NewMockreportStreamClient(suite.ctrl)
 
 	tempDir, err := os.MkdirTemp("", "exports")
 	if err != nil {
@@ -44,7 +47,8 @@ func (suite *ExportReportControllerTestSuite) SetupTest() {
 	}
 
 	suite.exportReportController = NewExportReportController(
-		tempDir, suite.mockFileStorageClient, suite.mockExportReportRecordClient, suite.mockExportReportServiceClient,
+		tempDir, suite.mockFileStorageClient, suite.mockExportReportRecordClient, suite.mockReportRecordClient,
+		suite.mockReportStreamClient,
 	)
 }
 
@@ -80,6 +84,7 @@ func (suite *ExportReportControllerTestSuite) TestGetExportReportRecords() {
 	suite.JSONEq(`[{
 		"id":1,
 		"fileName":"report.json",
+		"reportType":"json",
 		"status":"completed"
 	}]`, recorder.Body.String())
 }
@@ -90,7 +95,7 @@ func (suite *ExportReportControllerTestSuite) TestCreateExportReportRecord() {
 	reportType := string(models.ExportReportJson)
 	requestBody := fmt.Sprintf(`{"reportRecordId": %d, "reportType": "%s"}`, reportRecordID, reportType)
 
-	suite.mockExportReportRecordClient.EXPECT().GetByReportType(reportRecordID, reportType).Return(nil, nil).Times(1)
+	suite.mockExportReportRecordClient.EXPECT().GetByFilter(reportRecordID, reportType, "").Return(nil, nil).Times(1)
 
 	testFileName := "report_1.json"
 	tempFile, err := os.CreateTemp("", testFileName)
@@ -105,13 +110,79 @@ func (suite *ExportReportControllerTestSuite) TestCreateExportReportRecord() {
 		Status:         models.Pending,
 	}
 	exportReportRecord.ID = uint(1)
-	suite.mockExportReportRecordClient.EXPECT().Create(reportRecordID, tempFile.Name(), reportType).Return(exportReportRecord, nil).Times(1)
+	suite.mockExportReportRecordClient.EXPECT().Create(reportRecordID, tempFile.Name(), reportType, models.ExportFilter{}).Return(exportReportRecord, nil).Times(1)
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	suite.mockExportReportServiceClient.EXPECT().ExportReport(exportReportRecord).Do(func(_ *models.ExportReportRecord) {
-		wg.Done() // mark as done when the method is called
-	}).Times(1)
+	router := gin.Default()
+	router.POST("/export-report-records", suite.exportReportController.CreateExportReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/export-report-records", strings.NewReader(requestBody))
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 1}`, recorder.Body.String())
+}
+
+func (suite *ExportReportControllerTestSuite) TestCreateFilteredExportReportRecord() {
+	// Given
+	reportRecordID := uint(1)
+	reportType := string(models.ExportReportCsv)
+	requestBody := fmt.Sprintf(`{
+		"reportRecordId": %d,
+		"reportType": "%s",
+		"filter": {
+			"results": ["%s", "%s"],
+			"locationPrefix": " A-01 ",
+			"scanned": true,
+			"columns": ["result", "location"]
+		}
+	}`, reportRecordID, reportType, models.LocationNotScanned, models.LocationEmptyButNotExpected)
+
+	// results and columns are stored in their canonical order
+	scanned := true
+	filter := models.ExportFilter{
+		Results:        []string{string(models.LocationEmptyButNotExpected), string(models.LocationNotScanned)},
+		LocationPrefix: "A-01",
+		Scanned:        &scanned,
+		Columns:        []string{"location", "result"},
+	}
+
+	suite.mockExportReportRecordClient.EXPECT().GetByFilter(reportRecordID, reportType, filter.Key()).Return(nil, nil).Times(1)
+
+	fileName := fmt.Sprintf("report_1_%s.csv", filter.Key()[:12])
+	tempFile, err := os.CreateTemp("", "report_1_*.csv")
+	suite.Require().NoError(err)
+	defer os.Remove(tempFile.Name())
+	suite.mockFileStorageClient.EXPECT().CreateFile(suite.exportReportController.dirPath, fileName).Return(tempFile, nil).Times(1)
+
+	exportReportRecord := &models.ExportReportRecord{
+		ReportRecordID: reportRecordID,
+		ReportType:     models.ExportReportCsv,
+		FilePath:       tempFile.Name(),
+		Status:         models.Pending,
+		Filter:         filter,
+	}
+	exportReportRecord.ID = uint(3)
+	suite.mockExportReportRecordClient.EXPECT().Create(reportRecordID, tempFile.Name(), reportType, filter).Return(exportReportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/export-report-records", suite.exportReportController.CreateExportReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/export-report-records", strings.NewReader(requestBody))
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 3}`, recorder.Body.String())
+}
+
+func (suite *ExportReportControllerTestSuite) TestCreateExportReportRecordWithUnknownColumn() {
+	// Given
+	requestBody := `{"reportRecordId": 1, "reportType": "csv", "filter": {"columns": ["location", "quantity"]}}`
 
 	router := gin.Default()
 	router.POST("/export-report-records", suite.exportReportController.CreateExportReportRecord)
@@ -121,17 +192,150 @@ func (suite *ExportReportControllerTestSuite) TestCreateExportReportRecord() {
 	request, _ := http.NewRequest("POST", "/export-report-records", strings.NewReader(requestBody))
 	router.ServeHTTP(recorder, request)
 
-	wg.Wait() // wait for the asynchronous call to finish
+	// Then
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{"error": "unknown column=quantity"}`, recorder.Body.String())
+}
+
+func (suite *ExportReportControllerTestSuite) TestCreateExportReportRecordComparedAgainstBaseline() {
+	// Given
+	reportRecordID := uint(2)
+	baselineReportRecordID := uint(1)
+	reportType := string(models.ExportReportXlsx)
+	requestBody := `{"reportRecordId": 2, "reportType": "xlsx", "filter": {"baselineReportRecordId": 1}}`
+
+	filter := models.ExportFilter{BaselineReportRecordID: &baselineReportRecordID}
+
+	baselineReportRecord := &models.ReportRecord{Mode: models.ComparisonReport, Status: models.Completed}
+	baselineReportRecord.ID = baselineReportRecordID
+	reportRecord := &models.ReportRecord{Mode: models.ComparisonReport, Status: models.Completed}
+	reportRecord.ID = reportRecordID
+	suite.mockReportRecordClient.EXPECT().Get(baselineReportRecordID).Return(baselineReportRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(reportRecord, nil).Times(1)
+
+	suite.mockExportReportRecordClient.EXPECT().GetByFilter(reportRecordID, reportType, filter.Key()).Return(nil, nil).Times(1)
+
+	fileName := fmt.Sprintf("report_2_%s.xlsx", filter.Key()[:12])
+	tempFile, err := os.CreateTemp("", "report_2_*.xlsx")
+	suite.Require().NoError(err)
+	defer os.Remove(tempFile.Name())
+	suite.mockFileStorageClient.EXPECT().CreateFile(suite.exportReportController.dirPath, fileName).Return(tempFile, nil).Times(1)
+
+	exportReportRecord := &models.ExportReportRecord{
+		ReportRecordID: reportRecordID,
+		ReportType:     models.ExportReportXlsx,
+		FilePath:       tempFile.Name(),
+		Status:         models.Pending,
+		Filter:         filter,
+	}
+	exportReportRecord.ID = uint(4)
+	suite.mockExportReportRecordClient.EXPECT().Create(reportRecordID, tempFile.Name(), reportType, filter).Return(exportReportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/export-report-records", suite.exportReportController.CreateExportReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/export-report-records", strings.NewReader(requestBody))
+	router.ServeHTTP(recorder, request)
 
 	// Then
 	suite.Equal(http.StatusOK, recorder.Code)
-	suite.JSONEq(`{"id": 1}`, recorder.Body.String())
+	suite.JSONEq(`{"id": 4}`, recorder.Body.String())
+}
+
+func (suite *ExportReportControllerTestSuite) TestCreateExportReportRecordWithInvalidBaseline() {
+	// Given
+	scanDiffReportRecord := &models.ReportRecord{Mode: models.ScanDiffReport, Status: models.Completed}
+	scanDiffReportRecord.ID = uint(3)
+	suite.mockReportRecordClient.EXPECT().Get(uint(3)).Return(scanDiffReportRecord, nil).AnyTimes()
+	suite.mockExportReportRecordClient.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.POST("/export-report-records", suite.exportReportController.CreateExportReportRecord)
+
+	requestBodies := map[string]string{
+		`{"reportRecordId": 1, "reportType": "csv", "filter": {"columns": ["location", "discrepancyChange"]}}`: `{"error": "column=discrepancyChange needs a baselineReportRecordId"}`,
+		`{"reportRecordId": 1, "reportType": "csv", "filter": {"baselineReportRecordId": 1}}`:                  `{"error": "a report can not be compared against itself"}`,
+		`{"reportRecordId": 1, "reportType": "csv", "filter": {"baselineReportRecordId": 3}}`:                  `{"error": "report=3 is not a completed comparison report"}`,
+	}
+
+	for requestBody, expectedResponse := range requestBodies {
+		// When
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest("POST", "/export-report-records", strings.NewReader(requestBody))
+		router.ServeHTTP(recorder, request)
+
+		// Then
+		suite.Equal(http.StatusBadRequest, recorder.Code, requestBody)
+		suite.JSONEq(expectedResponse, recorder.Body.String(), requestBody)
+	}
+}
+
+func (suite *ExportReportControllerTestSuite) TestCreateExportReportRecordAgainstBaselineOfDifferentWarehouse() {
+	// Given
+	baselineReportRecord := &models.ReportRecord{Mode: models.ComparisonReport, Status: models.Completed}
+	baselineReportRecord.ID = uint(1)
+	baselineReportRecord.BulkScanRecord.LocationPattern = `^(?P<zone>[A-Z]+)-(?P<aisle>[0-9]+)$`
+	reportRecord := &models.ReportRecord{Mode: models.ComparisonReport, Status: models.Completed}
+	reportRecord.ID = uint(2)
+
+	suite.mockReportRecordClient.EXPECT().Get(uint(1)).Return(baselineReportRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Get(uint(2)).Return(reportRecord, nil).Times(1)
+	suite.mockExportReportRecordClient.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.POST("/export-report-records", suite.exportReportController.CreateExportReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/export-report-records", strings.NewReader(`{"reportRecordId": 2, "reportType": "csv", "filter": {"baselineReportRecordId": 1}}`))
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{"error": "report=2 is not of the same warehouse as baseline report=1"}`, recorder.Body.String())
+}
+
+func (suite *ExportReportControllerTestSuite) TestCreateExportReportRecordAsCSV() {
+	// Given
+	reportRecordID := uint(1)
+	reportType := string(models.ExportReportCsv)
+	requestBody := fmt.Sprintf(`{"reportRecordId": %d, "reportType": "%s"}`, reportRecordID, reportType)
+
+	suite.mockExportReportRecordClient.EXPECT().GetByFilter(reportRecordID, reportType, "").Return(nil, nil).Times(1)
+
+	tempFile, err := os.CreateTemp("", "report_1.csv")
+	suite.Require().NoError(err)
+	defer os.Remove(tempFile.Name())
+	suite.mockFileStorageClient.EXPECT().CreateFile(suite.exportReportController.dirPath, "report_1.csv").Return(tempFile, nil).Times(1)
+
+	exportReportRecord := &models.ExportReportRecord{
+		ReportRecordID: reportRecordID,
+		ReportType:     models.ExportReportCsv,
+		FilePath:       tempFile.Name(),
+		Status:         models.Pending,
+	}
+	exportReportRecord.ID = uint(2)
+	suite.mockExportReportRecordClient.EXPECT().Create(reportRecordID, tempFile.Name(), reportType, models.ExportFilter{}).Return(exportReportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/export-report-records", suite.exportReportController.CreateExportReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/export-report-records", strings.NewReader(requestBody))
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 2}`, recorder.Body.String())
 }
 
 func (suite *ExportReportControllerTestSuite) TestCreateExportReportWithUnsupportedReportType() {
 	// Given
 	reportRecordID := uint(1)
-	reportType := "csv"
+	reportType := "xml"
 	requestBody := fmt.Sprintf(`{"reportRecordId": %d, "reportType": "%s"}`, reportRecordID, reportType)
 
 	router := gin.Default()
@@ -181,3 +385,95 @@ func (suite *ExportReportControllerTestSuite) TestDownloadReport() {
 	suite.Equal(fmt.Sprintf("attachment; filename=%s", filepath.Base(tempFile.Name())), recorder.Header().Get("Content-Disposition"))
 	suite.Equal(`[{"key":"value"}]`, recorder.Body.String())
 }
+
+func (suite *ExportReportControllerTestSuite) TestStreamReport() {
+	// Given
+	reportRecordID := uint(4)
+	reportRecord := &models.ReportRecord{Status: models.Completed}
+	reportRecord.ID = reportRecordID
+
+	discrepanciesOnly := models.ExportFilter{DiscrepanciesOnly: true, LocationPrefix: "A-"}
+
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(reportRecord, nil).Times(1)
+	suite.mockReportStreamClient.EXPECT().StreamReport(reportRecordID, models.ExportReportNdjson, discrepanciesOnly, gomock.Any()).
+		DoAndReturn(func(reportRecordID uint, reportType models.ExportReportType, filter models.ExportFilter, w io.Writer) error {
+			_, err := io.WriteString(w, `{"location":"A-1"}`+"\n")
+			return err
+		}).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/download", suite.exportReportController.StreamReport)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/4/download?format=ndjson&discrepanciesOnly=true&locationPrefix=A-", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("application/x-ndjson", recorder.Header().Get("Content-Type"))
+	suite.Equal("attachment; filename=report_4.ndjson", recorder.Header().Get("Content-Disposition"))
+	suite.Equal(`{"location":"A-1"}`+"\n", recorder.Body.String())
+	suite.Empty(recorder.Result().Trailer.Get(streamErrorTrailer))
+}
+
+func (suite *ExportReportControllerTestSuite) TestStreamReportReportsFailureInTrailer() {
+	// Given
+	reportRecordID := uint(4)
+	reportRecord := &models.ReportRecord{Status: models.Completed}
+	reportRecord.ID = reportRecordID
+
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(reportRecord, nil).Times(1)
+	suite.mockReportStreamClient.EXPECT().StreamReport(reportRecordID, models.ExportReportCsv, models.ExportFilter{}, gomock.Any()).
+		DoAndReturn(func(reportRecordID uint, reportType models.ExportReportType, filter models.ExportFilter, w io.Writer) error {
+			_, _ = io.WriteString(w, "location,scanned\n")
+			return errors.New("connection reset")
+		}).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/download", suite.exportReportController.StreamReport)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/4/download?format=csv", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("failed to stream report", recorder.Result().Trailer.Get(streamErrorTrailer))
+}
+
+func (suite *ExportReportControllerTestSuite) TestStreamReportWithUnsupportedFormat() {
+	// Given
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/download", suite.exportReportController.StreamReport)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/4/download?format=xlsx", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{"error": "format not supported"}`, recorder.Body.String())
+}
+
+func (suite *ExportReportControllerTestSuite) TestStreamReportOfIncompleteReport() {
+	// Given
+	reportRecord := &models.ReportRecord{Status: models.Processing}
+	reportRecord.ID = uint(4)
+
+	suite.mockReportRecordClient.EXPECT().Get(uint(4)).Return(reportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/download", suite.exportReportController.StreamReport)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/4/download", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusAccepted, recorder.Code)
+	suite.JSONEq(`{"error": "report is not available for download"}`, recorder.Body.String())
+}
@@ -0,0 +1,165 @@
+package mapping
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/services/location"
+	log "github.com/sirupsen/logrus"
+)
+
+type columnMappingProfileRequest struct {
+	Name            string `json:"name"`
+	LocationColumn  string `json:"locationColumn"`
+	BarcodeColumn   string `json:"barcodeColumn"`
+	Delimiter       string `json:"delimiter"`
+	Encoding        string `json:"encoding"`
+	LocationPattern string `json:"locationPattern"`
+}
+
+type columnMappingProfileResponse struct {
+	ID              uint   `json:"id"`
+	Name            string `json:"name"`
+	LocationColumn  string `json:"locationColumn"`
+	BarcodeColumn   string `json:"barcodeColumn"`
+	Delimiter       string `json:"delimiter"`
+	Encoding        string `json:"encoding"`
+	LocationPattern string `json:"locationPattern,omitempty"`
+}
+
+type columnMappingProfileClient interface {
+	GetAll() ([]models.ColumnMappingProfile, error)
+	Create(columnMappingProfile *models.ColumnMappingProfile) error
+	GetByName(name string) (*models.ColumnMappingProfile, error)
+}
+
+type ColumnMappingProfileController struct {
+	columnMappingProfileClient columnMappingProfileClient
+}
+
+func NewColumnMappingProfileController(columnMappingProfileClient columnMappingProfileClient) *ColumnMappingProfileController {
+	return &ColumnMappingProfileController{
+		columnMappingProfileClient: columnMappingProfileClient,
+	}
+}
+
+func (cm *ColumnMappingProfileController) GetColumnMappingProfiles(c *gin.Context) {
+	log.Info("received request to get all column mapping profiles")
+
+	columnMappingProfiles, err := cm.columnMappingProfileClient.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get column mapping profiles from database"})
+		return
+	}
+
+	columnMappingProfileResponses := []columnMappingProfileResponse{}
+	for _, columnMappingProfile := range columnMappingProfiles {
+		columnMappingProfileResponses = append(columnMappingProfileResponses, toColumnMappingProfileResponse(columnMappingProfile))
+	}
+
+	c.JSON(http.StatusOK, columnMappingProfileResponses)
+}
+
+func (cm *ColumnMappingProfileController) CreateColumnMappingProfile(c *gin.Context) {
+	log.Info("received request to create column mapping profile")
+
+	var columnMappingProfileReq columnMappingProfileRequest
+	if err := c.ShouldBindJSON(&columnMappingProfileReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	columnMappingProfile := models.ColumnMappingProfile{
+		Name:            strings.TrimSpace(columnMappingProfileReq.Name),
+		LocationColumn:  strings.TrimSpace(columnMappingProfileReq.LocationColumn),
+		BarcodeColumn:   strings.TrimSpace(columnMappingProfileReq.BarcodeColumn),
+		Delimiter:       columnMappingProfileReq.Delimiter,
+		Encoding:        models.FileEncoding(strings.ToLower(columnMappingProfileReq.Encoding)),
+		LocationPattern: strings.TrimSpace(columnMappingProfileReq.LocationPattern),
+	}
+	if columnMappingProfile.Delimiter == "" {
+		columnMappingProfile.Delimiter = ","
+	}
+	if columnMappingProfile.Encoding == "" {
+		columnMappingProfile.Encoding = models.UTF8Encoding
+	}
+
+	if errorMessage := validateColumnMappingProfile(columnMappingProfile); errorMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errorMessage})
+		return
+	}
+
+	existingColumnMappingProfile, err := cm.columnMappingProfileClient.GetByName(columnMappingProfile.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check for existing column mapping profile"})
+		return
+	}
+
+	if existingColumnMappingProfile != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "column mapping profile already exists"})
+		return
+	}
+
+	err = cm.columnMappingProfileClient.Create(&columnMappingProfile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create column mapping profile"})
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"column_mapping_profile_id":   columnMappingProfile.ID,
+		"column_mapping_profile_name": columnMappingProfile.Name,
+	}).Info("created column mapping profile")
+
+	c.JSON(http.StatusOK, toColumnMappingProfileResponse(columnMappingProfile))
+}
+
+// validateColumnMappingProfile returns why the profile cannot be used to read a reference file, or an empty string
+// when it can.
+func validateColumnMappingProfile(columnMappingProfile models.ColumnMappingProfile) string {
+	if columnMappingProfile.Name == "" {
+		return "name must not be empty"
+	}
+
+	if columnMappingProfile.LocationColumn == "" || columnMappingProfile.BarcodeColumn == "" {
+		return "location and barcode columns must not be empty"
+	}
+
+	if strings.EqualFold(columnMappingProfile.LocationColumn, columnMappingProfile.BarcodeColumn) {
+		return "location and barcode columns must be different"
+	}
+
+	delimiter, size := utf8.DecodeRuneInString(columnMappingProfile.Delimiter)
+	if size != len(columnMappingProfile.Delimiter) || delimiter == utf8.RuneError || strings.ContainsRune("\"\r\n", delimiter) {
+		return "delimiter must be a single character other than a quote or line break"
+	}
+
+	switch columnMappingProfile.Encoding {
+	case models.UTF8Encoding, models.UTF16Encoding, models.Windows1252Encoding, models.ISO88591Encoding:
+	default:
+		return "encoding not supported"
+	}
+
+	if columnMappingProfile.LocationPattern != "" {
+		if _, err := location.NewLocationGrammar(columnMappingProfile.LocationPattern); err != nil {
+			return err.Error()
+		}
+	}
+
+	return ""
+}
+
+func toColumnMappingProfileResponse(columnMappingProfile models.ColumnMappingProfile) columnMappingProfileResponse {
+	return columnMappingProfileResponse{
+		ID:              columnMappingProfile.ID,
+		Name:            columnMappingProfile.Name,
+		LocationColumn:  columnMappingProfile.LocationColumn,
+		BarcodeColumn:   columnMappingProfile.BarcodeColumn,
+		Delimiter:       columnMappingProfile.Delimiter,
+		Encoding:        string(columnMappingProfile.Encoding),
+		LocationPattern: columnMappingProfile.LocationPattern,
+	}
+}
@@ -0,0 +1,158 @@
+package mapping
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockcolumnmappingprofilecontroller "github.com/habbas99/dexory/generated/controllers/mapping"
+	"github.com/habbas99/dexory/internal/models"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type ColumnMappingProfileControllerTestSuite struct {
+	suite.Suite
+	mockColumnMappingProfileClient *mockcolumnmappingprofilecontroller.MockcolumnMappingProfileClient
+	columnMappingProfileController *ColumnMappingProfileController
+	ctrl                           *gomock.Controller
+}
+
+func TestColumnMappingProfileControllerTestSuite(t *testing.T) {
+	suite.Run(t, new(ColumnMappingProfileControllerTestSuite))
+}
+
+func (suite *ColumnMappingProfileControllerTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockColumnMappingProfileClient = mockcolumnmappingprofilecontroller.NewMockcolumnMappingProfileClient(suite.ctrl)
+	suite.columnMappingProfileController = NewColumnMappingProfileController(suite.mockColumnMappingProfileClient)
+}
+
+func (suite *ColumnMappingProfileControllerTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func (suite *ColumnMappingProfileControllerTestSuite) TestGetColumnMappingProfiles() {
+	// Given
+	columnMappingProfile := models.ColumnMappingProfile{
+		Name:           "acme",
+		LocationColumn: "Bin",
+		BarcodeColumn:  "LPN",
+		Delimiter:      ";",
+		Encoding:       models.Windows1252Encoding,
+	}
+	columnMappingProfile.ID = uint(1)
+
+	suite.mockColumnMappingProfileClient.EXPECT().GetAll().Return([]models.ColumnMappingProfile{columnMappingProfile}, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/column-mapping-profiles", suite.columnMappingProfileController.GetColumnMappingProfiles)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/column-mapping-profiles", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`[{
+		"id": 1,
+		"name": "acme",
+		"locationColumn": "Bin",
+		"barcodeColumn": "LPN",
+		"delimiter": ";",
+		"encoding": "windows-1252"
+	}]`, recorder.Body.String())
+}
+
+func (suite *ColumnMappingProfileControllerTestSuite) TestCreateColumnMappingProfile() {
+	// Given
+	suite.mockColumnMappingProfileClient.EXPECT().GetByName("acme").Return(nil, nil).Times(1)
+	suite.mockColumnMappingProfileClient.EXPECT().Create(&models.ColumnMappingProfile{
+		Name:            "acme",
+		LocationColumn:  "Bin",
+		BarcodeColumn:   "SKU",
+		Delimiter:       ",",
+		Encoding:        models.UTF8Encoding,
+		LocationPattern: "^(?P<zone>[A-Z]+)-(?P<aisle>[0-9]+)$",
+	}).DoAndReturn(func(columnMappingProfile *models.ColumnMappingProfile) error {
+		columnMappingProfile.ID = uint(2)
+		return nil
+	}).Times(1)
+
+	router := gin.Default()
+	router.POST("/column-mapping-profiles", suite.columnMappingProfileController.CreateColumnMappingProfile)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/column-mapping-profiles", strings.NewReader(`{"name": "acme", "locationColumn": "Bin", "barcodeColumn": "SKU", "locationPattern": "^(?P<zone>[A-Z]+)-(?P<aisle>[0-9]+)$"}`))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{
+		"id": 2,
+		"name": "acme",
+		"locationColumn": "Bin",
+		"barcodeColumn": "SKU",
+		"delimiter": ",",
+		"encoding": "utf-8",
+		"locationPattern": "^(?P<zone>[A-Z]+)-(?P<aisle>[0-9]+)$"
+	}`, recorder.Body.String())
+}
+
+func (suite *ColumnMappingProfileControllerTestSuite) TestCreateColumnMappingProfileAlreadyExists() {
+	// Given
+	suite.mockColumnMappingProfileClient.EXPECT().GetByName("acme").Return(&models.ColumnMappingProfile{Name: "acme"}, nil).Times(1)
+	suite.mockColumnMappingProfileClient.EXPECT().Create(gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.POST("/column-mapping-profiles", suite.columnMappingProfileController.CreateColumnMappingProfile)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/column-mapping-profiles", strings.NewReader(`{"name": "acme", "locationColumn": "Bin", "barcodeColumn": "SKU"}`))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.JSONEq(`{"error": "column mapping profile already exists"}`, recorder.Body.String())
+}
+
+func (suite *ColumnMappingProfileControllerTestSuite) TestCreateColumnMappingProfileWithInvalidProfile() {
+	testCases := []struct {
+		body          string
+		expectedError string
+	}{
+		{`{"locationColumn": "Bin", "barcodeColumn": "SKU"}`, "name must not be empty"},
+		{`{"name": "acme", "locationColumn": "Bin"}`, "location and barcode columns must not be empty"},
+		{`{"name": "acme", "locationColumn": "Bin", "barcodeColumn": "bin"}`, "location and barcode columns must be different"},
+		{`{"name": "acme", "locationColumn": "Bin", "barcodeColumn": "SKU", "delimiter": ";;"}`, "delimiter must be a single character other than a quote or line break"},
+		{`{"name": "acme", "locationColumn": "Bin", "barcodeColumn": "SKU", "delimiter": "\""}`, "delimiter must be a single character other than a quote or line break"},
+		{`{"name": "acme", "locationColumn": "Bin", "barcodeColumn": "SKU", "encoding": "ebcdic"}`, "encoding not supported"},
+		{`{"name": "acme", "locationColumn": "Bin", "barcodeColumn": "SKU", "locationPattern": "^(?P<row>[A-Z])$"}`, "location pattern=^(?P<row>[A-Z])$ has unknown group=row, groups are [zone aisle bay level]"},
+	}
+
+	suite.mockColumnMappingProfileClient.EXPECT().GetByName(gomock.Any()).Times(0)
+	suite.mockColumnMappingProfileClient.EXPECT().Create(gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.POST("/column-mapping-profiles", suite.columnMappingProfileController.CreateColumnMappingProfile)
+
+	for _, testCase := range testCases {
+		// When
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest("POST", "/column-mapping-profiles", strings.NewReader(testCase.body))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(recorder, request)
+
+		// Then
+		suite.Equal(http.StatusBadRequest, recorder.Code, testCase.body)
+		suite.JSONEq(`{"error": "`+testCase.expectedError+`"}`, recorder.Body.String(), testCase.body)
+	}
+}
@@ -1,64 +1,178 @@
 package report
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/services/comparison"
 	"github.com/habbas99/dexory/internal/utilities"
 )
 
 type reportRecordResponse struct {
-	ID                uint      `json:"id"`
-	BulkScanFileName  string    `json:"bulkScanFileName"`
-	ReferenceFileName string    `json:"referenceFileName"`
-	Status            string    `json:"status"`
-	CreatedAt         time.Time `json:"createdAt"`
-	UpdatedAt         time.Time `json:"updatedAt"`
+	ID                       uint      `json:"id"`
+	Mode                     string    `json:"mode"`
+	BulkScanFileName         string    `json:"bulkScanFileName"`
+	BaselineBulkScanFileName string    `json:"baselineBulkScanFileName,omitempty"`
+	ReferenceFileName        string    `json:"referenceFileName"`
+	ReferenceSheet           string    `json:"referenceSheet,omitempty"`
+	ColumnMappingProfile     string    `json:"columnMappingProfile,omitempty"`
+	Status                   string    `json:"status"`
+	ErrorCode                string    `json:"errorCode,omitempty"`
+	ErrorMessage             string    `json:"errorMessage,omitempty"`
+	FailedRecordNumber       int       `json:"failedRecordNumber,omitempty"`
+	CreatedAt                time.Time `json:"createdAt"`
+	UpdatedAt                time.Time `json:"updatedAt"`
+}
+
+type scanDiffRequest struct {
+	BaselineBulkScanRecordID uint `json:"baselineBulkScanRecordId" binding:"required"`
+	BulkScanRecordID         uint `json:"bulkScanRecordId" binding:"required"`
+	Regenerate               bool `json:"regenerate"`
 }
 
 type comparisonDataResponse struct {
-	Location         string   `json:"location"`
-	Scanned          bool     `json:"scanned"`
-	Occupied         bool     `json:"occupied"`
-	ActualBarcodes   []string `json:"actualBarcodes"`
-	ExpectedBarcodes []string `json:"expectedBarcodes"`
-	Result           string   `json:"result"`
+	Location           string   `json:"location"`
+	Scanned            bool     `json:"scanned"`
+	Occupied           bool     `json:"occupied"`
+	ActualBarcodes     []string `json:"actualBarcodes"`
+	ExpectedBarcodes   []string `json:"expectedBarcodes"`
+	MatchedBarcodes    []string `json:"matchedBarcodes"`
+	MissingBarcodes    []string `json:"missingBarcodes"`
+	UnexpectedBarcodes []string `json:"unexpectedBarcodes"`
+	Result             string   `json:"result"`
+}
+
+type comparisonDataPageResponse struct {
+	Data       []comparisonDataResponse `json:"data"`
+	Total      int64                    `json:"total"`
+	NextCursor string                   `json:"nextCursor,omitempty"`
+}
+
+type outcomeCountResponse struct {
+	Result     string  `json:"result"`
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+type summaryCountsResponse struct {
+	Outcomes      []outcomeCountResponse `json:"outcomes"`
+	Total         int64                  `json:"total"`
+	Scanned       int64                  `json:"scanned"`
+	Unscanned     int64                  `json:"unscanned"`
+	Occupied      int64                  `json:"occupied"`
+	Empty         int64                  `json:"empty"`
+	Matched       int64                  `json:"matched"`
+	Discrepancies int64                  `json:"discrepancies"`
+	Accuracy      float64                `json:"accuracy"`
+}
+
+type reportSummaryResponse struct {
+	ReportRecordID uint `json:"reportRecordId"`
+	summaryCountsResponse
+}
+
+type locationGroupResponse struct {
+	Zone     string `json:"zone,omitempty"`
+	Aisle    string `json:"aisle,omitempty"`
+	Bay      string `json:"bay,omitempty"`
+	Level    string `json:"level,omitempty"`
+	Unparsed bool   `json:"unparsed,omitempty"`
+	summaryCountsResponse
+}
+
+type locationSummaryResponse struct {
+	ReportRecordID uint                    `json:"reportRecordId"`
+	GroupBy        []models.LocationField  `json:"groupBy"`
+	Groups         []locationGroupResponse `json:"groups"`
 }
 
+type reportDiffEntryResponse struct {
+	Location       string `json:"location"`
+	Change         string `json:"change"`
+	PreviousResult string `json:"previousResult,omitempty"`
+	Result         string `json:"result,omitempty"`
+}
+
+type reportDiffResponse struct {
+	ReportRecordID         uint                      `json:"reportRecordId"`
+	BaselineReportRecordID uint                      `json:"baselineReportRecordId"`
+	Resolved               int64                     `json:"resolved"`
+	Persistent             int64                     `json:"persistent"`
+	New                    int64                     `json:"new"`
+	Changes                []reportDiffEntryResponse `json:"changes"`
+	NextCursor             string                    `json:"nextCursor,omitempty"`
+}
+
+// comparisonDataCursor is the cursor handed to clients, it keeps the sort it was taken from so it is never used to
+// read a page sorted another way.
+type comparisonDataCursor struct {
+	Sort       models.ComparisonDataSort `json:"s"`
+	Descending bool                      `json:"d,omitempty"`
+	Value      string                    `json:"v"`
+	ID         uint                      `json:"i"`
+}
+
+const (
+	defaultComparisonDataPageSize = 100
+	maxComparisonDataPageSize     = 1000
+)
+
 type fileStorageClient interface {
 	SaveFile(dirPath, fileName string, fileContent io.Reader) (*os.File, error)
 }
 
 type reportRecordClient interface {
 	GetAll() ([]models.ReportRecord, error)
-	Create(bulkScanRecord models.BulkScanRecord, referenceFilePath string) (*models.ReportRecord, error)
+	Create(bulkScanRecord models.BulkScanRecord, columnMappingProfile *models.ColumnMappingProfile, referenceFilePath, referenceSheet, referenceFileHash string) (*models.ReportRecord, error)
 	Get(reportRecordID uint) (*models.ReportRecord, error)
+	GetCompletedByReferenceFileHash(bulkScanRecordID uint, columnMappingProfileID *uint, referenceSheet, referenceFileHash string) (*models.ReportRecord, error)
+	CreateScanDiff(baselineBulkScanRecord, bulkScanRecord models.BulkScanRecord) (*models.ReportRecord, error)
+	GetCompletedScanDiff(baselineBulkScanRecordID, bulkScanRecordID uint) (*models.ReportRecord, error)
+}
+
+type columnMappingProfileClient interface {
+	GetByName(name string) (*models.ColumnMappingProfile, error)
 }
 
 type bulkScanRecordClient interface {
 	GetByFileName(fileName string) (*models.BulkScanRecord, error)
+	Get(bulkScanRecordID uint) (*models.BulkScanRecord, error)
 }
 
 type comparisonDataClient interface {
-	GetAllPaginated(reportRecordID uint, limit int, offset int) ([]models.ComparisonData, error)
+	GetPage(reportRecordID uint, pageQuery models.ComparisonDataPageQuery) ([]models.ComparisonData, error)
+	Count(reportRecordID uint, pageQuery models.ComparisonDataPageQuery) (int64, error)
+	GetSummary(reportRecordID uint) (*models.ReportSummary, error)
+	GetLocationSummary(reportRecordID uint, groupBy []models.LocationField, within models.LocationHierarchy) ([]models.LocationGroupSummary, error)
+	GetReportDiff(baselineReportRecordID uint, reportRecordID uint, diffQuery models.ReportDiffQuery) ([]models.ReportDiffEntry, error)
+	CountReportDiff(baselineReportRecordID uint, reportRecordID uint) (map[models.DiscrepancyChange]int64, error)
 }
 
-type comparisonDataServiceClient interface {
-	GenerateComparisonDataForReport(reportRecord *models.ReportRecord)
+type referenceFileValidationClient interface {
+	ValidateReferenceFile(bulkScanRecordID uint, file io.Reader, options comparison.ReferenceFileOptions) (*comparison.ReferenceFileValidation, error)
 }
 
 type ReportRecordController struct {
-	dirPath                     string
-	fileStorageClient           fileStorageClient
-	bulkScanRecordClient        bulkScanRecordClient
-	reportRecordClient          reportRecordClient
-	comparisonDataClient        comparisonDataClient
-	comparisonDataServiceClient comparisonDataServiceClient
+	dirPath                       string
+	fileStorageClient             fileStorageClient
+	bulkScanRecordClient          bulkScanRecordClient
+	reportRecordClient            reportRecordClient
+	columnMappingProfileClient    columnMappingProfileClient
+	comparisonDataClient          comparisonDataClient
+	referenceFileValidationClient referenceFileValidationClient
 }
 
 func NewReportRecordController(
@@ -66,16 +180,18 @@ func NewReportRecordController(
 	fileStorageClient fileStorageClient,
 	BulkScanRecordClient bulkScanRecordClient,
 	reportRecordClient reportRecordClient,
+	columnMappingProfileClient columnMappingProfileClient,
 	comparisonDataClient comparisonDataClient,
-	comparisonDataServiceClient comparisonDataServiceClient,
+	referenceFileValidationClient referenceFileValidationClient,
 ) *ReportRecordController {
 	return &ReportRecordController{
-		dirPath:                     dirPath,
-		fileStorageClient:           fileStorageClient,
-		bulkScanRecordClient:        BulkScanRecordClient,
-		reportRecordClient:          reportRecordClient,
-		comparisonDataClient:        comparisonDataClient,
-		comparisonDataServiceClient: comparisonDataServiceClient,
+		dirPath:                       dirPath,
+		fileStorageClient:             fileStorageClient,
+		bulkScanRecordClient:          BulkScanRecordClient,
+		reportRecordClient:            reportRecordClient,
+		columnMappingProfileClient:    columnMappingProfileClient,
+		comparisonDataClient:          comparisonDataClient,
+		referenceFileValidationClient: referenceFileValidationClient,
 	}
 }
 
@@ -91,12 +207,19 @@ func (rr *ReportRecordController) GetAllReportRecords(c *gin.Context) {
 	reportResponses := []reportRecordResponse{}
 	for _, reportRecord := range reportRecords {
 		reportResponse := reportRecordResponse{
-			ID:                reportRecord.ID,
-			BulkScanFileName:  reportRecord.BulkScanRecord.FileName,
-			ReferenceFileName: reportRecord.ReferenceFileName,
-			Status:            string(reportRecord.Status),
-			CreatedAt:         reportRecord.CreatedAt,
-			UpdatedAt:         reportRecord.UpdatedAt,
+			ID:                       reportRecord.ID,
+			Mode:                     string(reportRecord.Mode),
+			BulkScanFileName:         reportRecord.BulkScanRecord.FileName,
+			BaselineBulkScanFileName: baselineBulkScanFileName(reportRecord),
+			ReferenceFileName:        reportRecord.ReferenceFileName,
+			ReferenceSheet:           reportRecord.ReferenceSheet,
+			ColumnMappingProfile:     columnMappingProfileName(reportRecord),
+			Status:                   string(reportRecord.Status),
+			ErrorCode:                string(reportRecord.ErrorCode),
+			ErrorMessage:             reportRecord.ErrorMessage,
+			FailedRecordNumber:       reportRecord.FailedRecordNumber,
+			CreatedAt:                reportRecord.CreatedAt,
+			UpdatedAt:                reportRecord.UpdatedAt,
 		}
 		reportResponses = append(reportResponses, reportResponse)
 	}
@@ -107,7 +230,6 @@ func (rr *ReportRecordController) GetAllReportRecords(c *gin.Context) {
 func (rr *ReportRecordController) CreateReportRecord(c *gin.Context) {
 	log.Info("received request to create report record")
 
-	bulkScanFileName := c.PostForm("bulkScanFileName")
 	fileHeader, err := c.FormFile("csvFile")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no file is received"})
@@ -121,14 +243,50 @@ func (rr *ReportRecordController) CreateReportRecord(c *gin.Context) {
 	}
 	defer receivedFile.Close()
 
-	bulkScanRecord, err := rr.bulkScanRecordClient.GetByFileName(bulkScanFileName)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find bulk scan record"})
+	bulkScanRecord, ok := rr.getPostedBulkScanRecord(c)
+	if !ok {
 		return
 	}
 
-	if bulkScanRecord.Status != models.Completed {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "bulk scan record has not been processed"})
+	columnMappingProfile, ok := rr.getColumnMappingProfile(c)
+	if !ok {
+		return
+	}
+
+	referenceSheet, ok := getReferenceSheet(c, fileHeader.Filename)
+	if !ok {
+		return
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, receivedFile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read the csv file"})
+		return
+	}
+	referenceFileHash := hex.EncodeToString(hasher.Sum(nil))
+
+	// the same csv against the same bulk scan produces the same report, regeneration has to be requested explicitly
+	if c.PostForm("regenerate") != "true" {
+		existingReportRecord, err := rr.reportRecordClient.GetCompletedByReferenceFileHash(bulkScanRecord.ID, columnMappingProfileID(columnMappingProfile), referenceSheet, referenceFileHash)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check for existing report record"})
+			return
+		}
+
+		if existingReportRecord != nil {
+			log.WithFields(log.Fields{
+				"report_record_id":    existingReportRecord.ID,
+				"bulk_scan_file_name": bulkScanRecord.FileName,
+				"uploaded_file_name":  fileHeader.Filename,
+			}).Info("report has already been generated for reference file")
+
+			c.JSON(http.StatusOK, gin.H{"id": existingReportRecord.ID, "duplicate": true})
+			return
+		}
+	}
+
+	if _, err := receivedFile.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read the csv file"})
 		return
 	}
 
@@ -138,18 +296,207 @@ func (rr *ReportRecordController) CreateReportRecord(c *gin.Context) {
 		return
 	}
 
-	reportRecord, err := rr.reportRecordClient.Create(*bulkScanRecord, savedFile.Name())
+	// the record is saved together with the job that generates its comparison data
+	reportRecord, err := rr.reportRecordClient.Create(*bulkScanRecord, columnMappingProfile, savedFile.Name(), referenceSheet, referenceFileHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create report record"})
+		return
+	}
 
 	log.WithFields(log.Fields{
 		"report_record_id":    reportRecord.ID,
-		"bulk_scan_file_name": bulkScanFileName,
+		"bulk_scan_file_name": bulkScanRecord.FileName,
 		"uploaded_file_name":  fileHeader.Filename,
-	}).Info("trigger generate comparison data for report")
+	}).Info("queued comparison data generation for report")
+
+	c.JSON(http.StatusOK, gin.H{"id": reportRecord.ID, "duplicate": false})
+}
+
+// CreateScanDiff creates a report of what physically changed between two bulk scans, the bulk scan of the request is
+// compared against the baseline bulk scan, usually the robot run of the night before.
+func (rr *ReportRecordController) CreateScanDiff(c *gin.Context) {
+	log.Info("received request to create scan diff")
+
+	var scanDiffReq scanDiffRequest
+	if err := c.ShouldBindJSON(&scanDiffReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if scanDiffReq.BaselineBulkScanRecordID == scanDiffReq.BulkScanRecordID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a bulk scan can not be compared against itself"})
+		return
+	}
+
+	baselineBulkScanRecord, ok := rr.getCompletedBulkScanRecord(c, scanDiffReq.BaselineBulkScanRecordID)
+	if !ok {
+		return
+	}
+
+	bulkScanRecord, ok := rr.getCompletedBulkScanRecord(c, scanDiffReq.BulkScanRecordID)
+	if !ok {
+		return
+	}
+
+	// the same two bulk scans produce the same scan diff, regeneration has to be requested explicitly
+	if !scanDiffReq.Regenerate {
+		existingReportRecord, err := rr.reportRecordClient.GetCompletedScanDiff(baselineBulkScanRecord.ID, bulkScanRecord.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check for existing report record"})
+			return
+		}
+
+		if existingReportRecord != nil {
+			log.WithFields(log.Fields{
+				"report_record_id":             existingReportRecord.ID,
+				"bulk_scan_record_id":          bulkScanRecord.ID,
+				"baseline_bulk_scan_record_id": baselineBulkScanRecord.ID,
+			}).Info("scan diff has already been generated for bulk scans")
+
+			c.JSON(http.StatusOK, gin.H{"id": existingReportRecord.ID, "duplicate": true})
+			return
+		}
+	}
+
+	// scan diffs are generated by the same job as comparison data
+	reportRecord, err := rr.reportRecordClient.CreateScanDiff(*baselineBulkScanRecord, *bulkScanRecord)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create report record"})
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"report_record_id":             reportRecord.ID,
+		"bulk_scan_record_id":          bulkScanRecord.ID,
+		"baseline_bulk_scan_record_id": baselineBulkScanRecord.ID,
+	}).Info("queued scan diff generation for report")
+
+	c.JSON(http.StatusOK, gin.H{"id": reportRecord.ID, "duplicate": false})
+}
+
+// getCompletedBulkScanRecord writes the error response and returns false when the bulk scan can not be compared.
+func (rr *ReportRecordController) getCompletedBulkScanRecord(c *gin.Context, bulkScanRecordID uint) (*models.BulkScanRecord, bool) {
+	bulkScanRecord, err := rr.bulkScanRecordClient.Get(bulkScanRecordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find bulk scan record"})
+		return nil, false
+	}
+
+	if bulkScanRecord.Status != models.Completed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("bulk scan record=%d has not been processed", bulkScanRecordID)})
+		return nil, false
+	}
+
+	return bulkScanRecord, true
+}
+
+// getPostedBulkScanRecord resolves the bulk scan a reference file is uploaded against. Clients post the
+// bulkScanRecordId, a bulkScanFileName is still accepted and resolves to the latest completed upload of that file.
+func (rr *ReportRecordController) getPostedBulkScanRecord(c *gin.Context) (*models.BulkScanRecord, bool) {
+	if value := c.PostForm("bulkScanRecordId"); value != "" {
+		bulkScanRecordID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bulk scan record id"})
+			return nil, false
+		}
+
+		return rr.getCompletedBulkScanRecord(c, uint(bulkScanRecordID))
+	}
 
-	// start a go routine to generate comparison report data
-	go rr.comparisonDataServiceClient.GenerateComparisonDataForReport(reportRecord)
+	bulkScanRecord, err := rr.bulkScanRecordClient.GetByFileName(c.PostForm("bulkScanFileName"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find bulk scan record"})
+		return nil, false
+	}
 
-	c.JSON(http.StatusOK, gin.H{"id": reportRecord.ID})
+	if bulkScanRecord.Status != models.Completed {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "bulk scan record has not been processed"})
+		return nil, false
+	}
+
+	return bulkScanRecord, true
+}
+
+// ValidateReferenceFile is a dry run of CreateReportRecord, it validates the reference file against the bulk scan
+// without saving the file or creating a report record.
+func (rr *ReportRecordController) ValidateReferenceFile(c *gin.Context) {
+	log.Info("received request to validate reference file")
+
+	fileHeader, err := c.FormFile("csvFile")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no file is received"})
+		return
+	}
+
+	receivedFile, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open the csv file"})
+		return
+	}
+	defer receivedFile.Close()
+
+	bulkScanRecord, ok := rr.getPostedBulkScanRecord(c)
+	if !ok {
+		return
+	}
+
+	columnMappingProfile, ok := rr.getColumnMappingProfile(c)
+	if !ok {
+		return
+	}
+
+	referenceSheet, ok := getReferenceSheet(c, fileHeader.Filename)
+	if !ok {
+		return
+	}
+
+	validation, err := rr.referenceFileValidationClient.ValidateReferenceFile(bulkScanRecord.ID, receivedFile, comparison.ReferenceFileOptions{
+		FileName:             fileHeader.Filename,
+		Sheet:                referenceSheet,
+		ColumnMappingProfile: columnMappingProfile,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate the csv file"})
+		return
+	}
+
+	c.JSON(http.StatusOK, validation)
+}
+
+// getColumnMappingProfile looks up the column mapping profile named in the columnMappingProfile form field. The
+// profile is nil when the field is not set and the reference file is read with the default column mapping. When the
+// profile cannot be found the error response is written and false is returned.
+func (rr *ReportRecordController) getColumnMappingProfile(c *gin.Context) (*models.ColumnMappingProfile, bool) {
+	name := c.PostForm("columnMappingProfile")
+	if name == "" {
+		return nil, true
+	}
+
+	columnMappingProfile, err := rr.columnMappingProfileClient.GetByName(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find column mapping profile"})
+		return nil, false
+	}
+
+	if columnMappingProfile == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "column mapping profile not found"})
+		return nil, false
+	}
+
+	return columnMappingProfile, true
+}
+
+// getReferenceSheet reads the worksheet selected in the sheet form field, which only applies to xlsx reference files.
+// An xlsx file is read from its first worksheet when the field is not set. When a sheet is selected for another file
+// format the error response is written and false is returned.
+func getReferenceSheet(c *gin.Context, fileName string) (string, bool) {
+	sheet := c.PostForm("sheet")
+	if sheet != "" && !comparison.IsXLSXFile(fileName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sheet can only be selected for xlsx files"})
+		return "", false
+	}
+
+	return sheet, true
 }
 
 func (rr *ReportRecordController) GetReport(c *gin.Context) {
@@ -172,12 +519,19 @@ func (rr *ReportRecordController) GetReport(c *gin.Context) {
 	}
 
 	reportRecordResponse := reportRecordResponse{
-		ID:                reportRecord.ID,
-		BulkScanFileName:  reportRecord.BulkScanRecord.FileName,
-		ReferenceFileName: reportRecord.ReferenceFileName,
-		CreatedAt:         reportRecord.CreatedAt,
-		UpdatedAt:         reportRecord.UpdatedAt,
-		Status:            string(reportRecord.Status),
+		ID:                       reportRecord.ID,
+		Mode:                     string(reportRecord.Mode),
+		BulkScanFileName:         reportRecord.BulkScanRecord.FileName,
+		BaselineBulkScanFileName: baselineBulkScanFileName(*reportRecord),
+		ReferenceFileName:        reportRecord.ReferenceFileName,
+		ReferenceSheet:           reportRecord.ReferenceSheet,
+		ColumnMappingProfile:     columnMappingProfileName(*reportRecord),
+		CreatedAt:                reportRecord.CreatedAt,
+		UpdatedAt:                reportRecord.UpdatedAt,
+		Status:                   string(reportRecord.Status),
+		ErrorCode:                string(reportRecord.ErrorCode),
+		ErrorMessage:             reportRecord.ErrorMessage,
+		FailedRecordNumber:       reportRecord.FailedRecordNumber,
 	}
 
 	c.JSON(http.StatusOK, reportRecordResponse)
@@ -196,24 +550,490 @@ func (rr *ReportRecordController) GetComparisonData(c *gin.Context) {
 		return
 	}
 
-	comparisonDataList, err := rr.comparisonDataClient.GetAllPaginated(reportRecordId, 10000, 0)
+	pageQuery, message := comparisonDataPageQuery(c)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	total, err := rr.comparisonDataClient.Count(reportRecordId, pageQuery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get comparison data for report from database"})
+		return
+	}
+
+	// one location more than the page tells whether there is a next page
+	limit := pageQuery.Limit
+	pageQuery.Limit++
+	comparisonDataList, err := rr.comparisonDataClient.GetPage(reportRecordId, pageQuery)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get comparison data for report from database"})
 		return
 	}
 
-	comparisonDataResponses := []comparisonDataResponse{}
+	pageResponse := comparisonDataPageResponse{Data: []comparisonDataResponse{}, Total: total}
+	if len(comparisonDataList) > limit {
+		comparisonDataList = comparisonDataList[:limit]
+		pageResponse.NextCursor = encodeComparisonDataCursor(pageQuery, comparisonDataList[limit-1])
+	}
+
 	for _, comparisonData := range comparisonDataList {
 		comparisonDataResponse := comparisonDataResponse{
-			Location:         comparisonData.Location,
-			Scanned:          comparisonData.Scanned,
-			Occupied:         comparisonData.Occupied,
-			ActualBarcodes:   comparisonData.ActualBarcodes,
-			ExpectedBarcodes: comparisonData.ExpectedBarcodes,
-			Result:           string(comparisonData.Result),
+			Location:           comparisonData.Location,
+			Scanned:            comparisonData.Scanned,
+			Occupied:           comparisonData.Occupied,
+			ActualBarcodes:     comparisonData.ActualBarcodes,
+			ExpectedBarcodes:   comparisonData.ExpectedBarcodes,
+			MatchedBarcodes:    comparisonData.MatchedBarcodes,
+			MissingBarcodes:    comparisonData.MissingBarcodes,
+			UnexpectedBarcodes: comparisonData.UnexpectedBarcodes,
+			Result:             string(comparisonData.Result),
 		}
-		comparisonDataResponses = append(comparisonDataResponses, comparisonDataResponse)
+		pageResponse.Data = append(pageResponse.Data, comparisonDataResponse)
+	}
+
+	c.JSON(http.StatusOK, pageResponse)
+}
+
+func (rr *ReportRecordController) GetSummary(c *gin.Context) {
+	id := c.Param("id")
+
+	log.WithFields(log.Fields{
+		"report_record_id": id,
+	}).Info("received request to get report summary")
+
+	reportRecordId, err := utilities.ToUint(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid report id"})
+		return
+	}
+
+	reportRecord, err := rr.reportRecordClient.Get(reportRecordId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get report from database"})
+		return
+	}
+
+	if reportRecord.Status != models.Completed {
+		respondWithIncompleteReport(c, reportRecord, "report summary is not available")
+		return
+	}
+
+	summary, err := rr.comparisonDataClient.GetSummary(reportRecordId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get summary of report from database"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toReportSummaryResponse(reportRecordId, reportRecord.Mode, summary))
+}
+
+func (rr *ReportRecordController) GetLocationSummary(c *gin.Context) {
+	id := c.Param("id")
+
+	log.WithFields(log.Fields{
+		"report_record_id": id,
+	}).Info("received request to get location summary of report")
+
+	reportRecordId, err := utilities.ToUint(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid report id"})
+		return
+	}
+
+	groupBy, message := locationGroupBy(c)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	reportRecord, err := rr.reportRecordClient.Get(reportRecordId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get report from database"})
+		return
+	}
+
+	if reportRecord.Status != models.Completed {
+		respondWithIncompleteReport(c, reportRecord, "location summary is not available")
+		return
+	}
+
+	locationGroups, err := rr.comparisonDataClient.GetLocationSummary(reportRecordId, groupBy, queryLocationHierarchy(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get location summary of report from database"})
+		return
+	}
+
+	// the worst groups come first, groups with the same accuracy stay in location order
+	sort.SliceStable(locationGroups, func(i, j int) bool {
+		return locationGroups[i].Accuracy() < locationGroups[j].Accuracy()
+	})
+
+	response := locationSummaryResponse{ReportRecordID: reportRecordId, GroupBy: groupBy, Groups: []locationGroupResponse{}}
+	for _, locationGroup := range locationGroups {
+		response.Groups = append(response.Groups, locationGroupResponse{
+			Zone:                  locationGroup.Zone,
+			Aisle:                 locationGroup.Aisle,
+			Bay:                   locationGroup.Bay,
+			Level:                 locationGroup.Level,
+			Unparsed:              locationGroup.Unparsed,
+			summaryCountsResponse: toSummaryCountsResponse(reportRecord.Mode, locationGroup.ReportSummary),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetReportDiff compares the discrepancies of a report against those of an earlier baseline report of the same
+// warehouse, location by location. Discrepancies are resolved, persistent or new, and are listed a page at a time in
+// location order.
+func (rr *ReportRecordController) GetReportDiff(c *gin.Context) {
+	id := c.Param("id")
+	baseline := c.Query("baseline")
+
+	log.WithFields(log.Fields{
+		"report_record_id":          id,
+		"baseline_report_record_id": baseline,
+	}).Info("received request to get diff of reports")
+
+	reportRecordId, err := utilities.ToUint(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid report id"})
+		return
+	}
+
+	baselineReportRecordId, err := utilities.ToUint(baseline)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "baseline must be a report id"})
+		return
+	}
+
+	if baselineReportRecordId == reportRecordId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a report can not be compared against itself"})
+		return
+	}
+
+	diffQuery, message := reportDiffQuery(c)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	var reportRecords []*models.ReportRecord
+	for _, reportRecordID := range []uint{baselineReportRecordId, reportRecordId} {
+		reportRecord, err := rr.reportRecordClient.Get(reportRecordID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get report from database"})
+			return
+		}
+		reportRecords = append(reportRecords, reportRecord)
+
+		if reportRecord.Mode == models.ScanDiffReport {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("report=%d is a scan diff, only comparison reports have discrepancies", reportRecordID)})
+			return
+		}
+
+		if reportRecord.Status != models.Completed {
+			respondWithIncompleteReport(c, reportRecord, fmt.Sprintf("report=%d is not completed, report diff is not available", reportRecordID))
+			return
+		}
+	}
+
+	// locations of different warehouses would all be reported as added or removed
+	if !reportRecords[0].SameWarehouse(*reportRecords[1]) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("report=%d is not of the same warehouse as baseline report=%d", reportRecordId, baselineReportRecordId)})
+		return
+	}
+
+	counts, err := rr.comparisonDataClient.CountReportDiff(baselineReportRecordId, reportRecordId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get diff of reports from database"})
+		return
+	}
+
+	// one location more than the page tells whether there is a next page
+	limit := diffQuery.Limit
+	diffQuery.Limit++
+	entries, err := rr.comparisonDataClient.GetReportDiff(baselineReportRecordId, reportRecordId, diffQuery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get diff of reports from database"})
+		return
+	}
+
+	response := reportDiffResponse{
+		ReportRecordID:         reportRecordId,
+		BaselineReportRecordID: baselineReportRecordId,
+		Resolved:               counts[models.DiscrepancyResolved],
+		Persistent:             counts[models.DiscrepancyPersistent],
+		New:                    counts[models.DiscrepancyNew],
+		Changes:                []reportDiffEntryResponse{},
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		response.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(entries[limit-1].Location))
+	}
+
+	for _, entry := range entries {
+		response.Changes = append(response.Changes, reportDiffEntryResponse{
+			Location:       entry.Location,
+			Change:         string(entry.Change),
+			PreviousResult: string(entry.PreviousResult),
+			Result:         string(entry.Result),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func toReportSummaryResponse(reportRecordID uint, mode models.ReportMode, summary *models.ReportSummary) reportSummaryResponse {
+	return reportSummaryResponse{
+		ReportRecordID:        reportRecordID,
+		summaryCountsResponse: toSummaryCountsResponse(mode, *summary),
+	}
+}
+
+// toSummaryCountsResponse lists every outcome of the report mode in its order, outcomes no location had are counted
+// as zero.
+func toSummaryCountsResponse(mode models.ReportMode, summary models.ReportSummary) summaryCountsResponse {
+	response := summaryCountsResponse{
+		Outcomes:      []outcomeCountResponse{},
+		Total:         summary.Total,
+		Scanned:       summary.Scanned,
+		Unscanned:     summary.Total - summary.Scanned,
+		Occupied:      summary.Occupied,
+		Empty:         summary.Total - summary.Occupied,
+		Matched:       summary.Matched,
+		Discrepancies: summary.Discrepancies(),
+		Accuracy:      summary.Accuracy(),
+	}
+
+	for _, outcome := range mode.Outcomes() {
+		response.Outcomes = append(response.Outcomes, outcomeCountResponse{
+			Result:     string(outcome),
+			Count:      summary.Counts[outcome],
+			Percentage: summary.Share(outcome),
+		})
+	}
+
+	return response
+}
+
+// locationGroupBy reads the repeated groupBy parameters in the order of models.LocationFields, locations are grouped
+// by zone without any.
+func locationGroupBy(c *gin.Context) ([]models.LocationField, string) {
+	requested := c.QueryArray("groupBy")
+	if len(requested) == 0 {
+		return []models.LocationField{models.LocationZone}, ""
+	}
+
+	for _, field := range requested {
+		if !models.LocationField(field).IsValid() {
+			return nil, fmt.Sprintf("unknown groupBy=%s", field)
+		}
+	}
+
+	var groupBy []models.LocationField
+	for _, field := range models.LocationFields {
+		for _, value := range requested {
+			if models.LocationField(value) == field {
+				groupBy = append(groupBy, field)
+				break
+			}
+		}
+	}
+
+	return groupBy, ""
+}
+
+// queryLocationHierarchy reads the zone, aisle, bay and level parameters that narrow down the locations.
+func queryLocationHierarchy(c *gin.Context) models.LocationHierarchy {
+	var hierarchy models.LocationHierarchy
+	for _, field := range models.LocationFields {
+		hierarchy.Set(field, strings.TrimSpace(c.Query(string(field))))
+	}
+
+	return hierarchy
+}
+
+// comparisonDataPageQuery reads the filter, sort and page of the comparison data from the query. Results are
+// repeated result parameters, sort is one of the sort columns and order is asc or desc.
+func comparisonDataPageQuery(c *gin.Context) (models.ComparisonDataPageQuery, string) {
+	pageQuery := models.ComparisonDataPageQuery{
+		Filter: models.ExportFilter{
+			LocationPrefix: strings.TrimSpace(c.Query("locationPrefix")),
+		},
+		Barcode:   strings.TrimSpace(c.Query("barcode")),
+		Hierarchy: queryLocationHierarchy(c),
+		Sort:      models.ComparisonDataSort(c.DefaultQuery("sort", string(models.SortByLocation))),
+		Limit:     defaultComparisonDataPageSize,
+	}
+
+	for _, result := range c.QueryArray("result") {
+		if !models.ScanComparisonOutcome(result).IsValid() {
+			return pageQuery, fmt.Sprintf("unknown result=%s", result)
+		}
+		pageQuery.Filter.Results = append(pageQuery.Filter.Results, result)
+	}
+
+	var message string
+	var discrepanciesOnly *bool
+	if discrepanciesOnly, message = queryFlag(c, "discrepanciesOnly"); message != "" {
+		return pageQuery, message
+	}
+	pageQuery.Filter.DiscrepanciesOnly = discrepanciesOnly != nil && *discrepanciesOnly
+	if pageQuery.Filter.Scanned, message = queryFlag(c, "scanned"); message != "" {
+		return pageQuery, message
+	}
+	if pageQuery.Filter.Occupied, message = queryFlag(c, "occupied"); message != "" {
+		return pageQuery, message
+	}
+
+	switch pageQuery.Sort {
+	case models.SortByLocation, models.SortByResult, models.SortByScanned, models.SortByOccupied:
+	default:
+		return pageQuery, fmt.Sprintf("unknown sort=%s", pageQuery.Sort)
+	}
+
+	switch order := c.DefaultQuery("order", "asc"); order {
+	case "asc":
+	case "desc":
+		pageQuery.Descending = true
+	default:
+		return pageQuery, fmt.Sprintf("unknown order=%s", order)
+	}
+
+	if value, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxComparisonDataPageSize {
+			return pageQuery, fmt.Sprintf("limit must be between 1 and %d", maxComparisonDataPageSize)
+		}
+		pageQuery.Limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := decodeComparisonDataCursor(value)
+		if err != nil {
+			return pageQuery, "invalid cursor"
+		}
+		if cursor.Sort != pageQuery.Sort || cursor.Descending != pageQuery.Descending {
+			return pageQuery, "cursor does not match sort"
+		}
+		if cursor.Sort == models.SortByScanned || cursor.Sort == models.SortByOccupied {
+			if _, err := strconv.ParseBool(cursor.Value); err != nil {
+				return pageQuery, "invalid cursor"
+			}
+		}
+		pageQuery.After = &models.ComparisonDataCursor{Value: cursor.Value, ID: cursor.ID}
+	}
+
+	return pageQuery, ""
+}
+
+// reportDiffQuery reads the repeated change parameters, the page limit and the cursor of a report diff. The cursor
+// is the last location of the previous page.
+func reportDiffQuery(c *gin.Context) (models.ReportDiffQuery, string) {
+	diffQuery := models.ReportDiffQuery{Limit: defaultComparisonDataPageSize}
+
+	for _, change := range c.QueryArray("change") {
+		if !models.DiscrepancyChange(change).IsValid() {
+			return models.ReportDiffQuery{}, fmt.Sprintf("unknown change=%s", change)
+		}
+		diffQuery.Changes = append(diffQuery.Changes, models.DiscrepancyChange(change))
+	}
+
+	if value, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxComparisonDataPageSize {
+			return models.ReportDiffQuery{}, fmt.Sprintf("limit must be between 1 and %d", maxComparisonDataPageSize)
+		}
+		diffQuery.Limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		location, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil || len(location) == 0 {
+			return models.ReportDiffQuery{}, "invalid cursor"
+		}
+		diffQuery.After = string(location)
+	}
+
+	return diffQuery, ""
+}
+
+// queryFlag reads a true or false query parameter, nil when the query does not have it.
+func queryFlag(c *gin.Context, name string) (*bool, string) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return nil, ""
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Sprintf("%s must be true or false", name)
+	}
+
+	return &parsed, ""
+}
+
+func encodeComparisonDataCursor(pageQuery models.ComparisonDataPageQuery, comparisonData models.ComparisonData) string {
+	cursor := comparisonDataCursor{Sort: pageQuery.Sort, Descending: pageQuery.Descending, ID: comparisonData.ID}
+	switch pageQuery.Sort {
+	case models.SortByResult:
+		cursor.Value = string(comparisonData.Result)
+	case models.SortByScanned:
+		cursor.Value = strconv.FormatBool(comparisonData.Scanned)
+	case models.SortByOccupied:
+		cursor.Value = strconv.FormatBool(comparisonData.Occupied)
+	default:
+		cursor.Value = comparisonData.Location
+	}
+
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeComparisonDataCursor(value string) (*comparisonDataCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor comparisonDataCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+func columnMappingProfileID(columnMappingProfile *models.ColumnMappingProfile) *uint {
+	if columnMappingProfile == nil {
+		return nil
+	}
+	return &columnMappingProfile.ID
+}
+
+func columnMappingProfileName(reportRecord models.ReportRecord) string {
+	if reportRecord.ColumnMappingProfile == nil {
+		return ""
+	}
+	return reportRecord.ColumnMappingProfile.Name
+}
+
+func baselineBulkScanFileName(reportRecord models.ReportRecord) string {
+	if reportRecord.BaselineBulkScanRecord == nil {
+		return ""
+	}
+	return reportRecord.BaselineBulkScanRecord.FileName
+}
+
+// respondWithIncompleteReport tells the caller the report is still being generated, or that it failed and will not be
+// available until it is generated again.
+func respondWithIncompleteReport(c *gin.Context, reportRecord *models.ReportRecord, message string) {
+	code := http.StatusAccepted
+	if reportRecord.Status == models.Failed {
+		code = http.StatusConflict
 	}
 
-	c.JSON(http.StatusOK, comparisonDataResponses)
+	c.JSON(code, gin.H{"status": reportRecord.Status, "message": message})
 }
@@ -2,32 +2,38 @@ package report
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/habbas99/dexory/generated/controllers/report"
 	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/services/comparison"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
-	"sync"
 	"testing"
 	"time"
 )
 
 type ReportRecordControllerTestSuite struct {
 	suite.Suite
-	dirPath                         string
-	mockFileStorageClient           *mockreportrecordcontroller.MockfileStorageClient
-	mockBulkScanRecordClient        *mockreportrecordcontroller.MockbulkScanRecordClient
-	mockReportRecordClient          *mockreportrecordcontroller.MockreportRecordClient
-	mockComparisonDataClient        *mockreportrecordcontroller.MockcomparisonDataClient
-	mockComparisonDataServiceClient *mockreportrecordcontroller.MockcomparisonDataServiceClient
-	reportRecordController          *ReportRecordController
-	ctrl                            *gomock.Controller
+	dirPath                  string
+	mockFileStorageClient    *mockreportrecordcontroller.MockfileStorageClient
+	mockBulkScanRecordClient *mockreportrecordcontroller.MockbulkScanRecordClient
+	mockReportRecordClient   *mockreportrecordcontroller.MockreportRecordClient
+	mockProfileClient        *mockreportrecordcontroller.MockcolumnMappingProfileClient
+	mockComparisonDataClient *mockreportrecordcontroller.MockcomparisonDataClient
+	mockValidationClient     *mockreportrecordcontroller.MockreferenceFileValidationClient
+	reportRecordController   *ReportRecordController
+	ctrl                     *gomock.Controller
 }
 
 func TestReportRecordControllerTestSuite(t *testing.T) {
@@ -41,8 +47,9 @@ func (suite *ReportRecordControllerTestSuite) SetupTest() {
 	suite.mockFileStorageClient = mockreportrecordcontroller.NewMockfileStorageClient(suite.ctrl)
 	suite.mockBulkScanRecordClient = mockreportrecordcontroller.NewMockbulkScanRecordClient(suite.ctrl)
 	suite.mockReportRecordClient = mockreportrecordcontroller.NewMockreportRecordClient(suite.ctrl)
+	suite.mockProfileClient = mockreportrecordcontroller.NewMockcolumnMappingProfileClient(suite.ctrl)
 	suite.mockComparisonDataClient = mockreportrecordcontroller.NewMockcomparisonDataClient(suite.ctrl)
-	suite.mockComparisonDataServiceClient = mockreportrecordcontroller.NewMockcomparisonDataServiceClient(suite.ctrl)
+	suite.mockValidationClient = mockreportrecordcontroller.NewMockreferenceFileValidationClient(suite.ctrl)
 
 	tempDir, err := os.MkdirTemp("", "comparison-reports")
 	if err != nil {
@@ -57,8 +64,9 @@ func (suite *ReportRecordControllerTestSuite) SetupTest() {
 		suite.mockFileStorageClient,
 		suite.mockBulkScanRecordClient,
 		suite.mockReportRecordClient,
+		suite.mockProfileClient,
 		suite.mockComparisonDataClient,
-		suite.mockComparisonDataServiceClient,
+		suite.mockValidationClient,
 	)
 }
 
@@ -74,6 +82,7 @@ func (suite *ReportRecordControllerTestSuite) TestGetReportRecords() {
 	}
 	bulkScanRecord.ID = uint(1)
 	reportRecord := models.ReportRecord{
+		Mode:              models.ComparisonReport,
 		ReferenceFileName: "scans.csv",
 		Status:            "completed",
 		BulkScanRecord:    bulkScanRecord,
@@ -100,6 +109,7 @@ func (suite *ReportRecordControllerTestSuite) TestGetReportRecords() {
 	suite.Equal(http.StatusOK, recorder.Code)
 	suite.JSONEq(`[{
 		"id":1,
+		"mode":"comparison",
 		"bulkScanFileName":"scans_001.json",
 		"referenceFileName":"scans.csv",
 		"status":"completed",
@@ -126,8 +136,10 @@ func (suite *ReportRecordControllerTestSuite) TestCreateReportRecord() {
 	}
 	reportRecord.ID = uint(1)
 
+	referenceFileHash := suite.referenceFileHash(fileContent)
 	suite.mockBulkScanRecordClient.EXPECT().GetByFileName(bulkScanFileName).Return(bulkScanRecord, nil).Times(1)
-	suite.mockReportRecordClient.EXPECT().Create(*bulkScanRecord, gomock.Any()).Return(reportRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().GetCompletedByReferenceFileHash(uint(1), nil, "", referenceFileHash).Return(nil, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Create(*bulkScanRecord, nil, gomock.Any(), "", referenceFileHash).Return(reportRecord, nil).Times(1)
 
 	tempFile, err := os.CreateTemp("", uploadedFileName)
 	suite.Require().NoError(err)
@@ -139,34 +151,445 @@ func (suite *ReportRecordControllerTestSuite) TestCreateReportRecord() {
 
 	suite.mockFileStorageClient.EXPECT().SaveFile(suite.dirPath, uploadedFileName, gomock.Any()).Return(tempFile, nil).Times(1)
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	suite.mockComparisonDataServiceClient.EXPECT().GenerateComparisonDataForReport(reportRecord).Do(func(_ *models.ReportRecord) {
-		wg.Done() // mark as done when the method is called
-	}).Times(1)
+	router := gin.Default()
+	router.POST("/inventory-comparison-reports", suite.reportRecordController.CreateReportRecord)
+
+	// create a multipart form to simulate file upload
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	writer.WriteField("bulkScanFileName", bulkScanFileName)
+	part, _ := writer.CreateFormFile("csvFile", uploadedFileName)
+	part.Write([]byte(fileContent))
+	writer.Close()
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/inventory-comparison-reports", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 1, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateReportRecordAgainstReuploadedBulkScan() {
+	// Given
+	bulkScanFileName := "scans_001.json"
+	uploadedFileName := "scans.csv"
+	fileContent := "content does not matter"
+
+	// the first upload of the bulk scan failed to ingest, the file was uploaded again under the same name
+	reuploadedBulkScanRecord := &models.BulkScanRecord{
+		FileName: bulkScanFileName,
+		Status:   models.Completed,
+	}
+	reuploadedBulkScanRecord.ID = uint(2)
+
+	reportRecord := &models.ReportRecord{
+		ReferenceFileName: uploadedFileName,
+		Status:            "processing",
+	}
+	reportRecord.ID = uint(1)
+
+	referenceFileHash := suite.referenceFileHash(fileContent)
+	suite.mockBulkScanRecordClient.EXPECT().Get(uint(2)).Return(reuploadedBulkScanRecord, nil).Times(1)
+	suite.mockBulkScanRecordClient.EXPECT().GetByFileName(gomock.Any()).Times(0)
+	suite.mockReportRecordClient.EXPECT().GetCompletedByReferenceFileHash(uint(2), nil, "", referenceFileHash).Return(nil, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Create(*reuploadedBulkScanRecord, nil, gomock.Any(), "", referenceFileHash).Return(reportRecord, nil).Times(1)
+
+	tempFile, err := os.CreateTemp("", uploadedFileName)
+	suite.Require().NoError(err)
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write([]byte(fileContent))
+	suite.Require().NoError(err)
+	suite.Require().NoError(tempFile.Close())
+
+	suite.mockFileStorageClient.EXPECT().SaveFile(suite.dirPath, uploadedFileName, gomock.Any()).Return(tempFile, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/inventory-comparison-reports", suite.reportRecordController.CreateReportRecord)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	writer.WriteField("bulkScanRecordId", "2")
+	writer.WriteField("bulkScanFileName", bulkScanFileName)
+	part, _ := writer.CreateFormFile("csvFile", uploadedFileName)
+	part.Write([]byte(fileContent))
+	writer.Close()
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/inventory-comparison-reports", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 1, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateReportRecordAlreadyGenerated() {
+	// Given
+	bulkScanFileName := "scans_001.json"
+	uploadedFileName := "scans.csv"
+	fileContent := "LOCATION,ITEM\nZA001A,DX9850004338"
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FileName: bulkScanFileName,
+		Status:   models.Completed,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	existingReportRecord := &models.ReportRecord{
+		ReferenceFileName: uploadedFileName,
+		Status:            models.Completed,
+	}
+	existingReportRecord.ID = uint(3)
+
+	suite.mockBulkScanRecordClient.EXPECT().GetByFileName(bulkScanFileName).Return(bulkScanRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().GetCompletedByReferenceFileHash(uint(1), nil, "", suite.referenceFileHash(fileContent)).Return(existingReportRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.POST("/inventory-comparison-reports", suite.reportRecordController.CreateReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createReportRecordRequest(bulkScanFileName, uploadedFileName, fileContent, nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 3, "duplicate": true}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateReportRecordRegenerate() {
+	// Given
+	bulkScanFileName := "scans_001.json"
+	uploadedFileName := "scans.csv"
+	fileContent := "LOCATION,ITEM\nZA001A,DX9850004338"
+	referenceFileHash := suite.referenceFileHash(fileContent)
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FileName: bulkScanFileName,
+		Status:   models.Completed,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	reportRecord := &models.ReportRecord{
+		ReferenceFileName: uploadedFileName,
+		Status:            models.Pending,
+	}
+	reportRecord.ID = uint(4)
+
+	tempFile, err := os.CreateTemp("", uploadedFileName)
+	suite.Require().NoError(err)
+	defer os.Remove(tempFile.Name())
+
+	suite.mockBulkScanRecordClient.EXPECT().GetByFileName(bulkScanFileName).Return(bulkScanRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().GetCompletedByReferenceFileHash(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	suite.mockFileStorageClient.EXPECT().SaveFile(suite.dirPath, uploadedFileName, gomock.Any()).Return(tempFile, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Create(*bulkScanRecord, nil, tempFile.Name(), "", referenceFileHash).Return(reportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/inventory-comparison-reports", suite.reportRecordController.CreateReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createReportRecordRequest(bulkScanFileName, uploadedFileName, fileContent, map[string]string{"regenerate": "true"})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 4, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateReportRecordWithColumnMappingProfile() {
+	// Given
+	bulkScanFileName := "scans_001.json"
+	uploadedFileName := "scans.csv"
+	fileContent := "Bin;SKU;Qty\nZA001A;DX9850004338;1"
+	referenceFileHash := suite.referenceFileHash(fileContent)
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FileName: bulkScanFileName,
+		Status:   models.Completed,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	columnMappingProfile := &models.ColumnMappingProfile{
+		Name:           "acme",
+		LocationColumn: "Bin",
+		BarcodeColumn:  "SKU",
+		Delimiter:      ";",
+		Encoding:       models.UTF8Encoding,
+	}
+	columnMappingProfile.ID = uint(7)
+
+	reportRecord := &models.ReportRecord{
+		ReferenceFileName: uploadedFileName,
+		Status:            models.Pending,
+	}
+	reportRecord.ID = uint(5)
+
+	tempFile, err := os.CreateTemp("", uploadedFileName)
+	suite.Require().NoError(err)
+	defer os.Remove(tempFile.Name())
+
+	suite.mockBulkScanRecordClient.EXPECT().GetByFileName(bulkScanFileName).Return(bulkScanRecord, nil).Times(1)
+	suite.mockProfileClient.EXPECT().GetByName("acme").Return(columnMappingProfile, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().GetCompletedByReferenceFileHash(uint(1), &columnMappingProfile.ID, "", referenceFileHash).Return(nil, nil).Times(1)
+	suite.mockFileStorageClient.EXPECT().SaveFile(suite.dirPath, uploadedFileName, gomock.Any()).Return(tempFile, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Create(*bulkScanRecord, columnMappingProfile, tempFile.Name(), "", referenceFileHash).Return(reportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/inventory-comparison-reports", suite.reportRecordController.CreateReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createReportRecordRequest(bulkScanFileName, uploadedFileName, fileContent, map[string]string{"columnMappingProfile": "acme"})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 5, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateReportRecordWithUnknownColumnMappingProfile() {
+	// Given
+	bulkScanFileName := "scans_001.json"
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FileName: bulkScanFileName,
+		Status:   models.Completed,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	suite.mockBulkScanRecordClient.EXPECT().GetByFileName(bulkScanFileName).Return(bulkScanRecord, nil).Times(1)
+	suite.mockProfileClient.EXPECT().GetByName("unknown").Return(nil, nil).Times(1)
+	suite.mockFileStorageClient.EXPECT().SaveFile(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	suite.mockReportRecordClient.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.POST("/inventory-comparison-reports", suite.reportRecordController.CreateReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createReportRecordRequest(bulkScanFileName, "scans.csv", "Bin;SKU", map[string]string{"columnMappingProfile": "unknown"})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{"error": "column mapping profile not found"}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateReportRecordWithXLSXSheet() {
+	// Given
+	bulkScanFileName := "scans_001.json"
+	uploadedFileName := "inventory.xlsx"
+	fileContent := "workbook content does not matter"
+	referenceFileHash := suite.referenceFileHash(fileContent)
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FileName: bulkScanFileName,
+		Status:   models.Completed,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	reportRecord := &models.ReportRecord{
+		ReferenceFileName: uploadedFileName,
+		ReferenceSheet:    "Inventory",
+		Status:            models.Pending,
+	}
+	reportRecord.ID = uint(6)
+
+	tempFile, err := os.CreateTemp("", uploadedFileName)
+	suite.Require().NoError(err)
+	defer os.Remove(tempFile.Name())
+
+	suite.mockBulkScanRecordClient.EXPECT().GetByFileName(bulkScanFileName).Return(bulkScanRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().GetCompletedByReferenceFileHash(uint(1), nil, "Inventory", referenceFileHash).Return(nil, nil).Times(1)
+	suite.mockFileStorageClient.EXPECT().SaveFile(suite.dirPath, uploadedFileName, gomock.Any()).Return(tempFile, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Create(*bulkScanRecord, nil, tempFile.Name(), "Inventory", referenceFileHash).Return(reportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/inventory-comparison-reports", suite.reportRecordController.CreateReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createReportRecordRequest(bulkScanFileName, uploadedFileName, fileContent, map[string]string{"sheet": "Inventory"})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 6, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateReportRecordWithSheetForCSVFile() {
+	// Given
+	bulkScanFileName := "scans_001.json"
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FileName: bulkScanFileName,
+		Status:   models.Completed,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	suite.mockBulkScanRecordClient.EXPECT().GetByFileName(bulkScanFileName).Return(bulkScanRecord, nil).Times(1)
+	suite.mockFileStorageClient.EXPECT().SaveFile(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	suite.mockReportRecordClient.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.POST("/inventory-comparison-reports", suite.reportRecordController.CreateReportRecord)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createReportRecordRequest(bulkScanFileName, "scans.csv", "Location,Item", map[string]string{"sheet": "Inventory"})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{"error": "sheet can only be selected for xlsx files"}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateScanDiff() {
+	// Given
+	baselineBulkScanRecord, bulkScanRecord := suite.createCompletedBulkScanRecords()
+
+	reportRecord := &models.ReportRecord{
+		Mode:   models.ScanDiffReport,
+		Status: models.Pending,
+	}
+	reportRecord.ID = uint(5)
+
+	suite.mockBulkScanRecordClient.EXPECT().Get(uint(1)).Return(baselineBulkScanRecord, nil).Times(1)
+	suite.mockBulkScanRecordClient.EXPECT().Get(uint(2)).Return(bulkScanRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().GetCompletedScanDiff(uint(1), uint(2)).Return(nil, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().CreateScanDiff(*baselineBulkScanRecord, *bulkScanRecord).Return(reportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/scan-diff-reports", suite.reportRecordController.CreateScanDiff)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/scan-diff-reports", bytes.NewBufferString(`{"baselineBulkScanRecordId": 1, "bulkScanRecordId": 2}`))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 5, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateScanDiffAlreadyGenerated() {
+	// Given
+	baselineBulkScanRecord, bulkScanRecord := suite.createCompletedBulkScanRecords()
+
+	existingReportRecord := &models.ReportRecord{
+		Mode:   models.ScanDiffReport,
+		Status: models.Completed,
+	}
+	existingReportRecord.ID = uint(3)
+
+	suite.mockBulkScanRecordClient.EXPECT().Get(uint(1)).Return(baselineBulkScanRecord, nil).Times(1)
+	suite.mockBulkScanRecordClient.EXPECT().Get(uint(2)).Return(bulkScanRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().GetCompletedScanDiff(uint(1), uint(2)).Return(existingReportRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().CreateScanDiff(gomock.Any(), gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.POST("/scan-diff-reports", suite.reportRecordController.CreateScanDiff)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/scan-diff-reports", bytes.NewBufferString(`{"baselineBulkScanRecordId": 1, "bulkScanRecordId": 2}`))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 3, "duplicate": true}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestCreateScanDiffWithInvalidBulkScans() {
+	// Given
+	baselineBulkScanRecord := &models.BulkScanRecord{FileName: "scans_001.json", Status: models.Completed}
+	baselineBulkScanRecord.ID = uint(1)
+	unprocessedBulkScanRecord := &models.BulkScanRecord{FileName: "scans_003.json", Status: models.Processing}
+	unprocessedBulkScanRecord.ID = uint(3)
+
+	suite.mockBulkScanRecordClient.EXPECT().Get(uint(1)).Return(baselineBulkScanRecord, nil).AnyTimes()
+	suite.mockBulkScanRecordClient.EXPECT().Get(uint(3)).Return(unprocessedBulkScanRecord, nil).AnyTimes()
+	suite.mockReportRecordClient.EXPECT().CreateScanDiff(gomock.Any(), gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.POST("/scan-diff-reports", suite.reportRecordController.CreateScanDiff)
+
+	bodies := map[string]string{
+		`{"baselineBulkScanRecordId": 1, "bulkScanRecordId": 1}`: `{"error": "a bulk scan can not be compared against itself"}`,
+		`{"baselineBulkScanRecordId": 1, "bulkScanRecordId": 3}`: `{"error": "bulk scan record=3 has not been processed"}`,
+	}
+
+	for body, expectedResponse := range bodies {
+		// When
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest("POST", "/scan-diff-reports", bytes.NewBufferString(body))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(recorder, request)
+
+		// Then
+		suite.Equal(http.StatusBadRequest, recorder.Code, body)
+		suite.JSONEq(expectedResponse, recorder.Body.String(), body)
+	}
+}
+
+func (suite *ReportRecordControllerTestSuite) TestValidateReferenceFile() {
+	// Given
+	bulkScanFileName := "scans_001.json"
+	fileContent := "LOCATION,ITEM\nZA001A,DX9850004338\nZA009Z"
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FileName: bulkScanFileName,
+		Status:   models.Completed,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	validation := &comparison.ReferenceFileValidation{
+		Valid:                 false,
+		Headers:               []string{"LOCATION", "ITEM"},
+		TotalRows:             2,
+		RowsWithTooFewColumns: []int{3},
+		DuplicateLocations:    []comparison.DuplicateLocation{},
+		MalformedBarcodes:     []comparison.MalformedBarcode{},
+		UnknownLocations:      []string{},
+	}
 
-	router := gin.Default()
-	router.POST("/inventory-comparison-reports", suite.reportRecordController.CreateReportRecord)
+	suite.mockBulkScanRecordClient.EXPECT().GetByFileName(bulkScanFileName).Return(bulkScanRecord, nil).Times(1)
+	suite.mockValidationClient.EXPECT().ValidateReferenceFile(uint(1), gomock.Any(), comparison.ReferenceFileOptions{FileName: "scans.csv"}).Return(validation, nil).Times(1)
+	suite.mockFileStorageClient.EXPECT().SaveFile(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	suite.mockReportRecordClient.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
-	// create a multipart form to simulate file upload
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	writer.WriteField("bulkScanFileName", bulkScanFileName)
-	part, _ := writer.CreateFormFile("csvFile", uploadedFileName)
-	part.Write([]byte(fileContent))
-	writer.Close()
+	router := gin.Default()
+	router.POST("/inventory-comparison-reports/validate", suite.reportRecordController.ValidateReferenceFile)
 
 	// When
 	recorder := httptest.NewRecorder()
-	request, _ := http.NewRequest("POST", "/inventory-comparison-reports", body)
-	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request := suite.createReportRecordRequest(bulkScanFileName, "scans.csv", fileContent, nil)
+	request.URL.Path = "/inventory-comparison-reports/validate"
 	router.ServeHTTP(recorder, request)
 
-	wg.Wait() // wait for the asynchronous call to finish
-
 	// Then
 	suite.Equal(http.StatusOK, recorder.Code)
-	suite.JSONEq(`{"id": 1}`, recorder.Body.String())
+	suite.JSONEq(`{
+		"valid": false,
+		"headers": ["LOCATION", "ITEM"],
+		"totalRows": 2,
+		"rowsWithTooFewColumns": [3],
+		"duplicateLocations": [],
+		"malformedBarcodes": [],
+		"unknownLocations": []
+	}`, recorder.Body.String())
 }
 
 func (suite *ReportRecordControllerTestSuite) TestGetReport() {
@@ -179,6 +602,7 @@ func (suite *ReportRecordControllerTestSuite) TestGetReport() {
 	bulkScanRecord.ID = uint(1)
 
 	reportRecord := models.ReportRecord{
+		Mode:              models.ComparisonReport,
 		ReferenceFileName: "scans.csv",
 		Status:            models.Completed,
 		BulkScanRecord:    bulkScanRecord,
@@ -203,6 +627,7 @@ func (suite *ReportRecordControllerTestSuite) TestGetReport() {
 	suite.Equal(http.StatusOK, recorder.Code)
 	suite.JSONEq(`{
 		"id": 1,
+		"mode": "comparison",
 		"bulkScanFileName": "scans_001.json",
 		"referenceFileName": "scans.csv",
 		"status": "completed",
@@ -215,17 +640,23 @@ func (suite *ReportRecordControllerTestSuite) TestGetComparisonData() {
 	// Given
 	reportID := uint(1)
 	comparisonData := models.ComparisonData{
-		ReportRecordID:   uint(1),
-		Location:         "Location1",
-		Scanned:          true,
-		Occupied:         true,
-		ActualBarcodes:   []string{"Barcode1"},
-		ExpectedBarcodes: []string{"Barcode1"},
-		Result:           models.LocationOccupiedWithCorrectItems,
+		ReportRecordID:     uint(1),
+		Location:           "Location1",
+		Scanned:            true,
+		Occupied:           true,
+		ActualBarcodes:     []string{"Barcode1"},
+		ExpectedBarcodes:   []string{"Barcode1"},
+		MatchedBarcodes:    []string{"Barcode1"},
+		MissingBarcodes:    []string{},
+		UnexpectedBarcodes: []string{},
+		Result:             models.LocationOccupiedWithCorrectItems,
 	}
 	comparisonDataList := []models.ComparisonData{comparisonData}
 
-	suite.mockComparisonDataClient.EXPECT().GetAllPaginated(reportID, gomock.Any(), gomock.Any()).Return(comparisonDataList, nil).Times(1)
+	pageQuery := models.ComparisonDataPageQuery{Sort: models.SortByLocation, Limit: 100}
+	suite.mockComparisonDataClient.EXPECT().Count(reportID, pageQuery).Return(int64(1), nil).Times(1)
+	pageQuery.Limit = 101
+	suite.mockComparisonDataClient.EXPECT().GetPage(reportID, pageQuery).Return(comparisonDataList, nil).Times(1)
 
 	router := gin.Default()
 	router.GET("/inventory-comparison-reports/:id/data", suite.reportRecordController.GetComparisonData)
@@ -237,12 +668,547 @@ func (suite *ReportRecordControllerTestSuite) TestGetComparisonData() {
 
 	// Then
 	suite.Equal(http.StatusOK, recorder.Code)
-	suite.JSONEq(`[{
-		"location":"Location1",
-		"scanned":true,
-		"occupied":true,
-		"actualBarcodes":["Barcode1"],
-		"expectedBarcodes":["Barcode1"],
-		"result":"The location was occupied by the expected items"
-	}]`, recorder.Body.String())
+	suite.JSONEq(`{
+		"data":[{
+			"location":"Location1",
+			"scanned":true,
+			"occupied":true,
+			"actualBarcodes":["Barcode1"],
+			"expectedBarcodes":["Barcode1"],
+			"matchedBarcodes":["Barcode1"],
+			"missingBarcodes":[],
+			"unexpectedBarcodes":[],
+			"result":"The location was occupied by the expected items"
+		}],
+		"total":1
+	}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetFilteredComparisonDataPages() {
+	// Given
+	reportID := uint(1)
+	comparisonDataList := []models.ComparisonData{
+		{ID: 4, ReportRecordID: reportID, Location: "ZA001A", Result: models.LocationEmptyButNotExpected},
+		{ID: 7, ReportRecordID: reportID, Location: "ZA001B", Result: models.LocationEmptyButNotExpected},
+		{ID: 2, ReportRecordID: reportID, Location: "ZA001C", Result: models.LocationEmptyButNotExpected},
+	}
+
+	scanned := true
+	pageQuery := models.ComparisonDataPageQuery{
+		Filter: models.ExportFilter{
+			Results:        []string{string(models.LocationEmptyButNotExpected)},
+			LocationPrefix: "ZA",
+			Scanned:        &scanned,
+		},
+		Barcode:    "DX9850004338",
+		Hierarchy:  models.LocationHierarchy{Aisle: "A"},
+		Sort:       models.SortByLocation,
+		Descending: true,
+		Limit:      2,
+	}
+	suite.mockComparisonDataClient.EXPECT().Count(reportID, pageQuery).Return(int64(5), nil).Times(1)
+	pageQuery.Limit = 3
+	suite.mockComparisonDataClient.EXPECT().GetPage(reportID, pageQuery).Return(comparisonDataList, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/data", suite.reportRecordController.GetComparisonData)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/data?result="+
+		url.QueryEscape(string(models.LocationEmptyButNotExpected))+
+		"&locationPrefix=ZA&scanned=true&barcode=DX9850004338&aisle=A&order=desc&limit=2", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+
+	var response comparisonDataPageResponse
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal(int64(5), response.Total)
+	suite.Len(response.Data, 2)
+
+	cursor, err := decodeComparisonDataCursor(response.NextCursor)
+	suite.NoError(err)
+	suite.Equal(comparisonDataCursor{Sort: models.SortByLocation, Descending: true, Value: "ZA001B", ID: 7}, *cursor)
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetComparisonDataAfterCursor() {
+	// Given
+	reportID := uint(1)
+	cursor := encodeComparisonDataCursor(
+		models.ComparisonDataPageQuery{Sort: models.SortByScanned},
+		models.ComparisonData{ID: 12, Scanned: false},
+	)
+
+	pageQuery := models.ComparisonDataPageQuery{
+		Sort:  models.SortByScanned,
+		After: &models.ComparisonDataCursor{Value: "false", ID: 12},
+		Limit: 100,
+	}
+	suite.mockComparisonDataClient.EXPECT().Count(reportID, pageQuery).Return(int64(0), nil).Times(1)
+	pageQuery.Limit = 101
+	suite.mockComparisonDataClient.EXPECT().GetPage(reportID, pageQuery).Return(nil, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/data", suite.reportRecordController.GetComparisonData)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/data?sort=scanned&cursor="+cursor, nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"data":[],"total":0}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetComparisonDataWithInvalidQuery() {
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/data", suite.reportRecordController.GetComparisonData)
+
+	cursor := encodeComparisonDataCursor(models.ComparisonDataPageQuery{Sort: models.SortByResult}, models.ComparisonData{ID: 1})
+	queries := map[string]string{
+		"result=unknown":      "unknown result=unknown",
+		"scanned=maybe":       "scanned must be true or false",
+		"sort=barcode":        "unknown sort=barcode",
+		"order=up":            "unknown order=up",
+		"limit=0":             "limit must be between 1 and 1000",
+		"limit=1001":          "limit must be between 1 and 1000",
+		"cursor=not-a-cursor": "invalid cursor",
+		"cursor=" + cursor:    "cursor does not match sort",
+	}
+
+	for query, message := range queries {
+		// When
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/data?"+query, nil)
+		router.ServeHTTP(recorder, request)
+
+		// Then
+		suite.Equal(http.StatusBadRequest, recorder.Code, query)
+		suite.JSONEq(`{"error":"`+message+`"}`, recorder.Body.String(), query)
+	}
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetSummary() {
+	// Given
+	reportRecordID := uint(1)
+	reportRecord := models.ReportRecord{Status: models.Completed}
+	reportRecord.ID = reportRecordID
+
+	summary := models.ReportSummary{
+		Counts: map[models.ScanComparisonOutcome]int64{
+			models.LocationEmptyAsExpected:          2,
+			models.LocationOccupiedWithCorrectItems: 3,
+			models.LocationOccupiedWithWrongItems:   1,
+			models.LocationNotScanned:               2,
+		},
+		Total:    8,
+		Scanned:  6,
+		Occupied: 4,
+		Matched:  5,
+	}
+
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(&reportRecord, nil).Times(1)
+	suite.mockComparisonDataClient.EXPECT().GetSummary(reportRecordID).Return(&summary, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/summary", suite.reportRecordController.GetSummary)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/summary", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+
+	var response reportSummaryResponse
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal(reportSummaryResponse{
+		ReportRecordID: reportRecordID,
+		summaryCountsResponse: summaryCountsResponse{
+			Outcomes:      response.Outcomes,
+			Total:         8,
+			Scanned:       6,
+			Unscanned:     2,
+			Occupied:      4,
+			Empty:         4,
+			Matched:       5,
+			Discrepancies: 3,
+			Accuracy:      62.5,
+		},
+	}, response)
+
+	suite.Len(response.Outcomes, len(models.ScanComparisonOutcomes))
+	suite.Equal(outcomeCountResponse{Result: string(models.LocationEmptyAsExpected), Count: 2, Percentage: 25}, response.Outcomes[0])
+	suite.Equal(outcomeCountResponse{Result: string(models.LocationOccupiedWithCorrectItems), Count: 3, Percentage: 37.5}, response.Outcomes[1])
+	suite.Contains(response.Outcomes, outcomeCountResponse{Result: string(models.LocationNotScanned), Count: 2, Percentage: 25})
+	suite.Contains(response.Outcomes, outcomeCountResponse{Result: string(models.LocationMissingFromRobotData)})
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetSummaryOfScanDiff() {
+	// Given
+	reportRecordID := uint(5)
+	reportRecord := models.ReportRecord{Mode: models.ScanDiffReport, Status: models.Completed}
+	reportRecord.ID = reportRecordID
+
+	summary := models.ReportSummary{
+		Counts: map[models.ScanComparisonOutcome]int64{
+			models.LocationUnchanged:      3,
+			models.LocationNewlyOccupied:  1,
+			models.LocationBarcodeChanged: 1,
+		},
+		Total:    5,
+		Scanned:  5,
+		Occupied: 4,
+		Matched:  3,
+	}
+
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(&reportRecord, nil).Times(1)
+	suite.mockComparisonDataClient.EXPECT().GetSummary(reportRecordID).Return(&summary, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/summary", suite.reportRecordController.GetSummary)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/5/summary", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+
+	var response reportSummaryResponse
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal(int64(2), response.Discrepancies)
+	suite.Equal(60.0, response.Accuracy)
+	suite.Len(response.Outcomes, len(models.ScanDiffOutcomes))
+	suite.Equal(outcomeCountResponse{Result: string(models.LocationUnchanged), Count: 3, Percentage: 60}, response.Outcomes[0])
+	suite.Contains(response.Outcomes, outcomeCountResponse{Result: string(models.LocationBarcodeChanged), Count: 1, Percentage: 20})
+	suite.Contains(response.Outcomes, outcomeCountResponse{Result: string(models.LocationNewlyEmptied)})
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetSummaryOfIncompleteReport() {
+	// Given
+	reportRecordID := uint(1)
+	reportRecord := models.ReportRecord{Status: models.Pending}
+	reportRecord.ID = reportRecordID
+
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(&reportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/summary", suite.reportRecordController.GetSummary)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/summary", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusAccepted, recorder.Code)
+	suite.JSONEq(`{"status": "pending", "message": "report summary is not available"}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetSummaryOfFailedReport() {
+	// Given
+	reportRecordID := uint(1)
+	reportRecord := models.ReportRecord{Status: models.Failed}
+	reportRecord.ID = reportRecordID
+
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(&reportRecord, nil).Times(1)
+	suite.mockComparisonDataClient.EXPECT().GetSummary(gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/summary", suite.reportRecordController.GetSummary)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/summary", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.JSONEq(`{"status": "failed", "message": "report summary is not available"}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetLocationSummaryOfIncompleteReport() {
+	// Given
+	reportRecordID := uint(1)
+	reportRecord := models.ReportRecord{Status: models.Processing}
+	reportRecord.ID = reportRecordID
+
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(&reportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/summary/locations", suite.reportRecordController.GetLocationSummary)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/summary/locations?groupBy=zone", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusAccepted, recorder.Code)
+	suite.JSONEq(`{"status": "processing", "message": "location summary is not available"}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetReportDiffOfIncompleteReport() {
+	// Given
+	baselineReportRecord := models.ReportRecord{Mode: models.ComparisonReport, Status: models.Completed}
+	baselineReportRecord.ID = uint(1)
+	reportRecord := models.ReportRecord{Mode: models.ComparisonReport, Status: models.Processing}
+	reportRecord.ID = uint(2)
+
+	suite.mockReportRecordClient.EXPECT().Get(uint(1)).Return(&baselineReportRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Get(uint(2)).Return(&reportRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/diff", suite.reportRecordController.GetReportDiff)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/2/diff?baseline=1", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusAccepted, recorder.Code)
+	suite.JSONEq(`{"status": "processing", "message": "report=2 is not completed, report diff is not available"}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetLocationSummary() {
+	// Given
+	reportRecordID := uint(1)
+	reportRecord := models.ReportRecord{Status: models.Completed}
+	reportRecord.ID = reportRecordID
+
+	aisleA := models.LocationGroupSummary{LocationHierarchy: models.LocationHierarchy{Zone: "Z", Aisle: "A"}}
+	aisleA.Add(models.LocationOccupiedWithCorrectItems, true, true, 9)
+	aisleA.Add(models.LocationOccupiedWithWrongItems, true, true, 1)
+	aisleB := models.LocationGroupSummary{LocationHierarchy: models.LocationHierarchy{Zone: "Z", Aisle: "B"}}
+	aisleB.Add(models.LocationEmptyAsExpected, true, false, 2)
+	aisleB.Add(models.LocationNotScanned, false, false, 2)
+
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(&reportRecord, nil).Times(1)
+	suite.mockComparisonDataClient.EXPECT().GetLocationSummary(
+		reportRecordID, []models.LocationField{models.LocationZone, models.LocationAisle}, models.LocationHierarchy{Zone: "Z"},
+	).Return([]models.LocationGroupSummary{aisleA, aisleB}, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/summary/locations", suite.reportRecordController.GetLocationSummary)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/summary/locations?groupBy=aisle&groupBy=zone&zone=Z", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+
+	var response locationSummaryResponse
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal([]models.LocationField{models.LocationZone, models.LocationAisle}, response.GroupBy)
+	suite.Require().Len(response.Groups, 2)
+	suite.Equal("B", response.Groups[0].Aisle)
+	suite.Equal(float64(50), response.Groups[0].Accuracy)
+	suite.Equal(int64(2), response.Groups[0].Unscanned)
+	suite.Equal("A", response.Groups[1].Aisle)
+	suite.Equal(float64(90), response.Groups[1].Accuracy)
+	suite.Equal(int64(1), response.Groups[1].Discrepancies)
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetLocationSummaryWithUnparsedLocations() {
+	// Given
+	reportRecordID := uint(1)
+	reportRecord := models.ReportRecord{Status: models.Completed}
+	reportRecord.ID = reportRecordID
+
+	unparsed := models.LocationGroupSummary{Unparsed: true}
+	unparsed.Add(models.LocationNotInExpectedInventory, true, true, 3)
+	zoneZ := models.LocationGroupSummary{LocationHierarchy: models.LocationHierarchy{Zone: "Z"}}
+	zoneZ.Add(models.LocationOccupiedWithCorrectItems, true, true, 5)
+
+	suite.mockReportRecordClient.EXPECT().Get(reportRecordID).Return(&reportRecord, nil).Times(1)
+	suite.mockComparisonDataClient.EXPECT().GetLocationSummary(
+		reportRecordID, []models.LocationField{models.LocationZone}, models.LocationHierarchy{},
+	).Return([]models.LocationGroupSummary{unparsed, zoneZ}, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/summary/locations", suite.reportRecordController.GetLocationSummary)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/summary/locations?groupBy=zone", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+
+	var response locationSummaryResponse
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Require().Len(response.Groups, 2)
+	suite.True(response.Groups[0].Unparsed)
+	suite.Empty(response.Groups[0].Zone)
+	suite.Equal(int64(3), response.Groups[0].Discrepancies)
+	suite.False(response.Groups[1].Unparsed)
+	suite.Equal("Z", response.Groups[1].Zone)
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetLocationSummaryWithUnknownGroup() {
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/summary/locations", suite.reportRecordController.GetLocationSummary)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/1/summary/locations?groupBy=row", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{"error":"unknown groupBy=row"}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetReportDiff() {
+	// Given
+	baselineReportRecord := models.ReportRecord{Mode: models.ComparisonReport, Status: models.Completed}
+	baselineReportRecord.ID = uint(1)
+	reportRecord := models.ReportRecord{Mode: models.ComparisonReport, Status: models.Completed}
+	reportRecord.ID = uint(2)
+
+	suite.mockReportRecordClient.EXPECT().Get(uint(1)).Return(&baselineReportRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Get(uint(2)).Return(&reportRecord, nil).Times(1)
+	suite.mockComparisonDataClient.EXPECT().CountReportDiff(uint(1), uint(2)).Return(map[models.DiscrepancyChange]int64{
+		models.DiscrepancyResolved:   4,
+		models.DiscrepancyPersistent: 1,
+		models.DiscrepancyNew:        2,
+	}, nil).Times(1)
+
+	// one location more than the limit is read to find out whether there is a next page
+	diffQuery := models.ReportDiffQuery{
+		Changes: []models.DiscrepancyChange{models.DiscrepancyResolved, models.DiscrepancyNew},
+		After:   "ZA001A",
+		Limit:   3,
+	}
+	suite.mockComparisonDataClient.EXPECT().GetReportDiff(uint(1), uint(2), diffQuery).Return([]models.ReportDiffEntry{
+		{Location: "ZA002A", Change: models.DiscrepancyResolved, PreviousResult: models.LocationNotScanned, Result: models.LocationEmptyAsExpected},
+		{Location: "ZA003A", Change: models.DiscrepancyNew, PreviousResult: models.LocationEmptyAsExpected, Result: models.LocationOccupiedButExpectedEmpty},
+		{Location: "ZA004A", Change: models.DiscrepancyNew, Result: models.LocationNotInExpectedInventory},
+	}, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/diff", suite.reportRecordController.GetReportDiff)
+
+	// When
+	recorder := httptest.NewRecorder()
+	cursor := base64.RawURLEncoding.EncodeToString([]byte("ZA001A"))
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/2/diff?baseline=1&change=resolved&change=new&limit=2&cursor="+cursor, nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(fmt.Sprintf(`{
+		"reportRecordId": 2,
+		"baselineReportRecordId": 1,
+		"resolved": 4,
+		"persistent": 1,
+		"new": 2,
+		"changes": [
+			{"location": "ZA002A", "change": "resolved", "previousResult": "%s", "result": "%s"},
+			{"location": "ZA003A", "change": "new", "previousResult": "%s", "result": "%s"}
+		],
+		"nextCursor": "%s"
+	}`, models.LocationNotScanned, models.LocationEmptyAsExpected, models.LocationEmptyAsExpected,
+		models.LocationOccupiedButExpectedEmpty, base64.RawURLEncoding.EncodeToString([]byte("ZA003A"))), recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetReportDiffWithInvalidQuery() {
+	// Given
+	scanDiffReportRecord := models.ReportRecord{Mode: models.ScanDiffReport, Status: models.Completed}
+	scanDiffReportRecord.ID = uint(3)
+	suite.mockReportRecordClient.EXPECT().Get(uint(3)).Return(&scanDiffReportRecord, nil).AnyTimes()
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/diff", suite.reportRecordController.GetReportDiff)
+
+	queries := map[string]string{
+		"":                        "baseline must be a report id",
+		"?baseline=2":             "a report can not be compared against itself",
+		"?baseline=1&change=open": "unknown change=open",
+		"?baseline=1&limit=0":     "limit must be between 1 and 1000",
+		"?baseline=1&cursor=%25":  "invalid cursor",
+		"?baseline=3":             "report=3 is a scan diff, only comparison reports have discrepancies",
+	}
+
+	for query, message := range queries {
+		// When
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", "/inventory-comparison-reports/2/diff"+query, nil)
+		router.ServeHTTP(recorder, request)
+
+		// Then
+		suite.Equal(http.StatusBadRequest, recorder.Code, query)
+		suite.JSONEq(fmt.Sprintf(`{"error": %q}`, message), recorder.Body.String(), query)
+	}
+}
+
+func (suite *ReportRecordControllerTestSuite) TestGetReportDiffOfDifferentWarehouses() {
+	// Given
+	acmeProfileID := uint(4)
+	baselineReportRecord := models.ReportRecord{Mode: models.ComparisonReport, Status: models.Completed}
+	baselineReportRecord.ID = uint(1)
+	reportRecord := models.ReportRecord{
+		Mode:                   models.ComparisonReport,
+		Status:                 models.Completed,
+		ColumnMappingProfileID: &acmeProfileID,
+		ColumnMappingProfile:   &models.ColumnMappingProfile{LocationPattern: `^(?P<zone>[A-Z]+)-(?P<aisle>[0-9]+)$`},
+	}
+	reportRecord.ID = uint(2)
+
+	suite.mockReportRecordClient.EXPECT().Get(uint(1)).Return(&baselineReportRecord, nil).Times(1)
+	suite.mockReportRecordClient.EXPECT().Get(uint(2)).Return(&reportRecord, nil).Times(1)
+	suite.mockComparisonDataClient.EXPECT().CountReportDiff(gomock.Any(), gomock.Any()).Times(0)
+
+	router := gin.Default()
+	router.GET("/inventory-comparison-reports/:id/diff", suite.reportRecordController.GetReportDiff)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/inventory-comparison-reports/2/diff?baseline=1", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{"error": "report=2 is not of the same warehouse as baseline report=1"}`, recorder.Body.String())
+}
+
+func (suite *ReportRecordControllerTestSuite) createReportRecordRequest(bulkScanFileName, uploadedFileName, fileContent string, formFields map[string]string) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	writer.WriteField("bulkScanFileName", bulkScanFileName)
+	for name, value := range formFields {
+		writer.WriteField(name, value)
+	}
+	part, _ := writer.CreateFormFile("csvFile", uploadedFileName)
+	part.Write([]byte(fileContent))
+	writer.Close()
+
+	request, _ := http.NewRequest("POST", "/inventory-comparison-reports", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return request
+}
+
+func (suite *ReportRecordControllerTestSuite) createCompletedBulkScanRecords() (*models.BulkScanRecord, *models.BulkScanRecord) {
+	baselineBulkScanRecord := &models.BulkScanRecord{FileName: "scans_001.json", Status: models.Completed}
+	baselineBulkScanRecord.ID = uint(1)
+	bulkScanRecord := &models.BulkScanRecord{FileName: "scans_002.json", Status: models.Completed}
+	bulkScanRecord.ID = uint(2)
+
+	return baselineBulkScanRecord, bulkScanRecord
+}
+
+func (suite *ReportRecordControllerTestSuite) referenceFileHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
 }
@@ -1,18 +1,31 @@
 package scan
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/habbas99/dexory/internal"
 	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/services/location"
+	"github.com/habbas99/dexory/internal/utilities"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 )
 
 type bulkScanRecordResponse struct {
-	ID       uint   `json:"id"`
-	FileName string `json:"fileName"`
-	Status   string `json:"status"`
+	ID                 uint   `json:"id"`
+	FileName           string `json:"fileName"`
+	Status             string `json:"status"`
+	ValidationPolicy   string `json:"validationPolicy,omitempty"`
+	LocationPattern    string `json:"locationPattern,omitempty"`
+	InvalidRecordCount int    `json:"invalidRecordCount,omitempty"`
+	ErrorCode          string `json:"errorCode,omitempty"`
+	ErrorMessage       string `json:"errorMessage,omitempty"`
+	FailedRecordNumber int    `json:"failedRecordNumber,omitempty"`
 }
 
 type fileStorageClient interface {
@@ -21,31 +34,26 @@ type fileStorageClient interface {
 
 type bulkScanRecordClient interface {
 	GetAll() ([]models.BulkScanRecord, error)
-	Create(filePath string) (*models.BulkScanRecord, error)
-}
-
-type scanServiceClient interface {
-	ProcessFile(bulkScanRecord *models.BulkScanRecord)
+	Get(bulkScanRecordID uint) (*models.BulkScanRecord, error)
+	Create(filePath, contentHash string, allowDuplicate bool, validationPolicy models.ValidationPolicy, locationPattern string) (*models.BulkScanRecord, error)
+	GetByContentHash(contentHash string) (*models.BulkScanRecord, error)
 }
 
 type ScanController struct {
 	dirPath              string
 	fileStorageClient    fileStorageClient
 	bulkScanRecordClient bulkScanRecordClient
-	scanServiceClient    scanServiceClient
 }
 
 func NewScanController(
 	dirPath string,
 	fileStorageClient fileStorageClient,
 	bulkScanRecordClient bulkScanRecordClient,
-	scanServiceClient scanServiceClient,
 ) *ScanController {
 	return &ScanController{
 		dirPath:              dirPath,
 		fileStorageClient:    fileStorageClient,
 		bulkScanRecordClient: bulkScanRecordClient,
-		scanServiceClient:    scanServiceClient,
 	}
 }
 
@@ -61,9 +69,15 @@ func (sc *ScanController) GetBulkScanRecords(c *gin.Context) {
 	bulkScanRecordResponses := []bulkScanRecordResponse{}
 	for _, bulkScanRecord := range bulkScanRecords {
 		bulkScanRecordResponse := bulkScanRecordResponse{
-			ID:       bulkScanRecord.ID,
-			FileName: bulkScanRecord.FileName,
-			Status:   string(bulkScanRecord.Status),
+			ID:                 bulkScanRecord.ID,
+			FileName:           bulkScanRecord.FileName,
+			Status:             string(bulkScanRecord.Status),
+			ValidationPolicy:   string(bulkScanRecord.ValidationPolicy),
+			LocationPattern:    bulkScanRecord.LocationPattern,
+			InvalidRecordCount: bulkScanRecord.InvalidRecordCount,
+			ErrorCode:          string(bulkScanRecord.ErrorCode),
+			ErrorMessage:       bulkScanRecord.ErrorMessage,
+			FailedRecordNumber: bulkScanRecord.FailedRecordNumber,
 		}
 		bulkScanRecordResponses = append(bulkScanRecordResponses, bulkScanRecordResponse)
 	}
@@ -82,6 +96,23 @@ func (sc *ScanController) UploadBulkScanFile(c *gin.Context) {
 		"filename": fileHeader.Filename,
 	}).Info("received upload bulk scan file from robot")
 
+	// an empty policy leaves the decision to the configured default of the scan service
+	validationPolicy := models.ValidationPolicy(c.PostForm("validationPolicy"))
+	if validationPolicy != "" && validationPolicy != models.RejectInvalidScans && validationPolicy != models.QuarantineInvalidScans {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation policy not supported"})
+		return
+	}
+
+	// an empty pattern parses the locations with the default grammar, the warehouse of the robot may name them
+	// differently
+	locationPattern := c.PostForm("locationPattern")
+	if locationPattern != "" {
+		if _, err := location.NewLocationGrammar(locationPattern); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// open the file for reading
 	receivedFile, err := fileHeader.Open()
 	if err != nil {
@@ -90,20 +121,98 @@ func (sc *ScanController) UploadBulkScanFile(c *gin.Context) {
 	}
 	defer receivedFile.Close()
 
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, receivedFile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read the uploaded file"})
+		return
+	}
+	contentHash := hex.EncodeToString(hasher.Sum(nil))
+
+	// the robot may re-upload the same nightly file, re-ingestion has to be requested explicitly
+	allowDuplicate := c.PostForm("allowDuplicate") == "true"
+	if !allowDuplicate {
+		existingBulkScanRecord, err := sc.bulkScanRecordClient.GetByContentHash(contentHash)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check for duplicate bulk scan file"})
+			return
+		}
+
+		if existingBulkScanRecord != nil {
+			sc.respondWithDuplicate(c, fileHeader.Filename, existingBulkScanRecord)
+			return
+		}
+	}
+
+	if _, err := receivedFile.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read the uploaded file"})
+		return
+	}
+
 	savedFile, err := sc.fileStorageClient.SaveFile(sc.dirPath, fileHeader.Filename, receivedFile)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save the file"})
 		return
 	}
 
-	bulkScanRecord, err := sc.bulkScanRecordClient.Create(savedFile.Name())
+	// the record is saved together with the job that parses the JSON file
+	bulkScanRecord, err := sc.bulkScanRecordClient.Create(savedFile.Name(), contentHash, allowDuplicate, validationPolicy, locationPattern)
+	if errors.Is(err, internal.ErrDuplicateEntity) {
+		// the same file was uploaded at the same time and its record was saved first
+		os.Remove(savedFile.Name())
+
+		existingBulkScanRecord, err := sc.bulkScanRecordClient.GetByContentHash(contentHash)
+		if err != nil || existingBulkScanRecord == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check for duplicate bulk scan file"})
+			return
+		}
+
+		sc.respondWithDuplicate(c, fileHeader.Filename, existingBulkScanRecord)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start file processing"})
 		return
 	}
 
-	// start a go routine to parse the JSON file
-	go sc.scanServiceClient.ProcessFile(bulkScanRecord)
+	c.JSON(http.StatusOK, gin.H{"id": bulkScanRecord.ID, "duplicate": false})
+}
+
+func (sc *ScanController) respondWithDuplicate(c *gin.Context, fileName string, existingBulkScanRecord *models.BulkScanRecord) {
+	log.WithFields(log.Fields{
+		"filename":            fileName,
+		"bulk_scan_record_id": existingBulkScanRecord.ID,
+	}).Info("bulk scan file has already been uploaded")
+
+	c.JSON(http.StatusOK, gin.H{"id": existingBulkScanRecord.ID, "duplicate": true})
+}
+
+func (sc *ScanController) DownloadValidationReport(c *gin.Context) {
+	id := c.Param("id")
+
+	log.WithFields(log.Fields{
+		"bulk_scan_record_id": id,
+	}).Info("received request to download bulk scan validation report")
+
+	bulkScanRecordID, err := utilities.ToUint(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bulk scan record id"})
+		return
+	}
+
+	bulkScanRecord, err := sc.bulkScanRecordClient.Get(bulkScanRecordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find bulk scan record"})
+		return
+	}
+
+	if bulkScanRecord.ValidationReportPath == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bulk scan record has no validation report"})
+		return
+	}
+
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Content-Disposition", "attachment; filename="+filepath.Base(bulkScanRecord.ValidationReportPath))
 
-	c.JSON(http.StatusOK, gin.H{"id": bulkScanRecord.ID})
+	c.File(bulkScanRecord.ValidationReportPath)
 }
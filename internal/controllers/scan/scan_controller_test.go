@@ -2,10 +2,13 @@ package scan
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	mockscancontroller "github.com/habbas99/dexory/generated/controllers/scan"
+	"github.com/habbas99/dexory/internal"
 	"github.com/habbas99/dexory/internal/models"
 	"github.com/stretchr/testify/suite"
 	"io"
@@ -14,15 +17,13 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
-	"sync"
 	"testing"
 )
 
 type ScanControllerTestSuite struct {
 	suite.Suite
-	mockFileStorageClient    *mockscancontroller.MockFileStorageClient
-	mockBulkScanRecordClient *mockscancontroller.MockBulkScanRecordClient
-	mockScanServiceClient    *mockscancontroller.MockScanServiceClient
+	mockFileStorageClient    *mockscancontroller.MockfileStorageClient
+	mockBulkScanRecordClient *mockscancontroller.MockbulkScanRecordClient
 	scanController           *ScanController
 	ctrl                     *gomock.Controller
 }
@@ -36,9 +37,8 @@ func (suite *ScanControllerTestSuite) SetupTest() {
 
 	suite.ctrl = gomock.NewController(suite.T())
 
-	suite.mockFileStorageClient = mockscancontroller.NewMockFileStorageClient(suite.ctrl)
-	suite.mockBulkScanRecordClient = mockscancontroller.NewMockBulkScanRecordClient(suite.ctrl)
-	suite.mockScanServiceClient = mockscancontroller.NewMockScanServiceClient(suite.ctrl)
+	suite.mockFileStorageClient = mockscancontroller.NewMockfileStorageClient(suite.ctrl)
+	suite.mockBulkScanRecordClient = mockscancontroller.NewMockbulkScanRecordClient(suite.ctrl)
 
 	tempDir, err := os.MkdirTemp("", "scans")
 	if err != nil {
@@ -47,7 +47,7 @@ func (suite *ScanControllerTestSuite) SetupTest() {
 	}
 
 	suite.scanController = NewScanController(
-		tempDir, suite.mockFileStorageClient, suite.mockBulkScanRecordClient, suite.mockScanServiceClient,
+		tempDir, suite.mockFileStorageClient, suite.mockBulkScanRecordClient,
 	)
 }
 
@@ -83,6 +83,41 @@ func (suite *ScanControllerTestSuite) TestGetBulkScanRecords() {
 	}]`, recorder.Body.String())
 }
 
+func (suite *ScanControllerTestSuite) TestGetBulkScanRecordsWithFailure() {
+	// Given
+	bulkScanRecord := models.BulkScanRecord{
+		FileName: "scans_001.json",
+		Status:   models.Failed,
+		Failure: models.Failure{
+			ErrorCode:          models.ErrorCodeMalformedFile,
+			ErrorMessage:       "failed to decode json data",
+			FailedRecordNumber: 8000,
+		},
+	}
+	bulkScanRecord.ID = uint(1)
+
+	suite.mockBulkScanRecordClient.EXPECT().GetAll().Return([]models.BulkScanRecord{bulkScanRecord}, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/bulk-scan-records", suite.scanController.GetBulkScanRecords)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/bulk-scan-records", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`[{
+		"id":1,
+		"fileName":"scans_001.json",
+		"status":"failed",
+		"errorCode":"malformed_file",
+		"errorMessage":"failed to decode json data",
+		"failedRecordNumber":8000
+	}]`, recorder.Body.String())
+}
+
 func (suite *ScanControllerTestSuite) TestUploadBulkScanFile() {
 	// Given
 	testFileName := "scans_002.json"
@@ -103,15 +138,12 @@ func (suite *ScanControllerTestSuite) TestUploadBulkScanFile() {
 
 	suite.mockFileStorageClient.EXPECT().SaveFile(gomock.Any(), gomock.Any(), gomock.Any()).Return(tempFile, nil).Times(1)
 
-	bulkScanRecord := models.BulkScanRecord{FilePath: tempFile.Name(), Status: models.Pending}
-	bulkScanRecord.ID = uint(1)
-	suite.mockBulkScanRecordClient.EXPECT().Create(tempFile.Name()).Return(&bulkScanRecord, nil).Times(1)
+	contentHash := suite.contentHash(testFileContent)
+	suite.mockBulkScanRecordClient.EXPECT().GetByContentHash(contentHash).Return(nil, nil).Times(1)
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	suite.mockScanServiceClient.EXPECT().ProcessFile(&bulkScanRecord).Do(func(_ *models.BulkScanRecord) {
-		wg.Done() // mark as done when the method is called
-	}).Times(1)
+	bulkScanRecord := models.BulkScanRecord{FilePath: tempFile.Name(), ContentHash: contentHash, Status: models.Pending}
+	bulkScanRecord.ID = uint(1)
+	suite.mockBulkScanRecordClient.EXPECT().Create(tempFile.Name(), contentHash, false, models.ValidationPolicy(""), "").Return(&bulkScanRecord, nil).Times(1)
 
 	router := gin.Default()
 	router.POST("/upload-bulk-scan-file", suite.scanController.UploadBulkScanFile)
@@ -135,9 +167,245 @@ func (suite *ScanControllerTestSuite) TestUploadBulkScanFile() {
 
 	router.ServeHTTP(recorder, request)
 
-	wg.Wait() // wait for the asynchronous call to finish
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 1, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ScanControllerTestSuite) TestUploadBulkScanFileDuplicate() {
+	// Given
+	testFileContent := `[{"name": "test_location", "scanned": true, "occupied": false, "detected_barcodes": []}]`
+
+	existingBulkScanRecord := models.BulkScanRecord{FileName: "scans_001.json", Status: models.Completed}
+	existingBulkScanRecord.ID = uint(5)
+	suite.mockBulkScanRecordClient.EXPECT().GetByContentHash(suite.contentHash(testFileContent)).Return(&existingBulkScanRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/upload-bulk-scan-file", suite.scanController.UploadBulkScanFile)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createUploadRequest("scans_003.json", testFileContent, map[string]string{})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 5, "duplicate": true}`, recorder.Body.String())
+}
+
+func (suite *ScanControllerTestSuite) TestUploadBulkScanFileDuplicateUploadedConcurrently() {
+	// Given
+	testFileContent := `[{"name": "test_location", "scanned": true, "occupied": false, "detected_barcodes": []}]`
+	contentHash := suite.contentHash(testFileContent)
+
+	savedFile, err := os.CreateTemp("", "scans_003.json")
+	suite.Require().NoError(err)
+	defer os.Remove(savedFile.Name())
+	suite.mockFileStorageClient.EXPECT().SaveFile(gomock.Any(), "scans_003.json", gomock.Any()).Return(savedFile, nil).Times(1)
+
+	existingBulkScanRecord := models.BulkScanRecord{FileName: "scans_001.json", ContentHash: contentHash, Status: models.Pending}
+	existingBulkScanRecord.ID = uint(5)
+	gomock.InOrder(
+		suite.mockBulkScanRecordClient.EXPECT().GetByContentHash(contentHash).Return(nil, nil).Times(1),
+		suite.mockBulkScanRecordClient.EXPECT().Create(savedFile.Name(), contentHash, false, models.ValidationPolicy(""), "").
+			Return(nil, fmt.Errorf("bulk scan record with content hash=%s, error: %w", contentHash, internal.ErrDuplicateEntity)).Times(1),
+		suite.mockBulkScanRecordClient.EXPECT().GetByContentHash(contentHash).Return(&existingBulkScanRecord, nil).Times(1),
+	)
+
+	router := gin.Default()
+	router.POST("/upload-bulk-scan-file", suite.scanController.UploadBulkScanFile)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createUploadRequest("scans_003.json", testFileContent, map[string]string{})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 5, "duplicate": true}`, recorder.Body.String())
+	suite.NoFileExists(savedFile.Name())
+}
+
+func (suite *ScanControllerTestSuite) TestUploadBulkScanFileAllowDuplicate() {
+	// Given
+	testFileContent := `[{"name": "test_location", "scanned": true, "occupied": false, "detected_barcodes": []}]`
+	contentHash := suite.contentHash(testFileContent)
+
+	savedFile, err := os.CreateTemp("", "scans_003.json")
+	suite.Require().NoError(err)
+	defer os.Remove(savedFile.Name())
+	suite.mockFileStorageClient.EXPECT().SaveFile(gomock.Any(), "scans_003.json", gomock.Any()).Return(savedFile, nil).Times(1)
+
+	bulkScanRecord := models.BulkScanRecord{FilePath: savedFile.Name(), ContentHash: contentHash, Status: models.Pending}
+	bulkScanRecord.ID = uint(6)
+	suite.mockBulkScanRecordClient.EXPECT().GetByContentHash(gomock.Any()).Times(0)
+	suite.mockBulkScanRecordClient.EXPECT().Create(savedFile.Name(), contentHash, true, models.ValidationPolicy(""), "").Return(&bulkScanRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/upload-bulk-scan-file", suite.scanController.UploadBulkScanFile)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createUploadRequest("scans_003.json", testFileContent, map[string]string{"allowDuplicate": "true"})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 6, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ScanControllerTestSuite) TestUploadBulkScanFileWithValidationPolicy() {
+	// Given
+	testFileContent := `[{"name": "test_location", "scanned": true, "occupied": false, "detected_barcodes": []}]`
+	contentHash := suite.contentHash(testFileContent)
+
+	savedFile, err := os.CreateTemp("", "scans_003.json")
+	suite.Require().NoError(err)
+	defer os.Remove(savedFile.Name())
+
+	bulkScanRecord := models.BulkScanRecord{FilePath: savedFile.Name(), Status: models.Pending}
+	bulkScanRecord.ID = uint(6)
+	suite.mockBulkScanRecordClient.EXPECT().GetByContentHash(contentHash).Return(nil, nil).Times(1)
+	suite.mockFileStorageClient.EXPECT().SaveFile(gomock.Any(), "scans_003.json", gomock.Any()).Return(savedFile, nil).Times(1)
+	suite.mockBulkScanRecordClient.EXPECT().Create(savedFile.Name(), contentHash, false, models.QuarantineInvalidScans, "").Return(&bulkScanRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/upload-bulk-scan-file", suite.scanController.UploadBulkScanFile)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createUploadRequest("scans_003.json", testFileContent, map[string]string{"validationPolicy": "quarantine"})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 6, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ScanControllerTestSuite) TestUploadBulkScanFileWithUnsupportedValidationPolicy() {
+	// Given
+	router := gin.Default()
+	router.POST("/upload-bulk-scan-file", suite.scanController.UploadBulkScanFile)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createUploadRequest("scans_003.json", `[]`, map[string]string{"validationPolicy": "ignore"})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{"error": "validation policy not supported"}`, recorder.Body.String())
+}
+
+func (suite *ScanControllerTestSuite) TestUploadBulkScanFileWithLocationPattern() {
+	// Given
+	testFileContent := `[{"name": "Z-01-02", "scanned": true, "occupied": false, "detected_barcodes": []}]`
+	contentHash := suite.contentHash(testFileContent)
+	locationPattern := `^(?P<zone>[A-Z]+)-(?P<aisle>[0-9]+)-(?P<level>[0-9]+)$`
+
+	savedFile, err := os.CreateTemp("", "scans_003.json")
+	suite.Require().NoError(err)
+	defer os.Remove(savedFile.Name())
+
+	bulkScanRecord := models.BulkScanRecord{FilePath: savedFile.Name(), Status: models.Pending, LocationPattern: locationPattern}
+	bulkScanRecord.ID = uint(6)
+	suite.mockBulkScanRecordClient.EXPECT().GetByContentHash(contentHash).Return(nil, nil).Times(1)
+	suite.mockFileStorageClient.EXPECT().SaveFile(gomock.Any(), "scans_003.json", gomock.Any()).Return(savedFile, nil).Times(1)
+	suite.mockBulkScanRecordClient.EXPECT().Create(savedFile.Name(), contentHash, false, models.ValidationPolicy(""), locationPattern).Return(&bulkScanRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.POST("/upload-bulk-scan-file", suite.scanController.UploadBulkScanFile)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createUploadRequest("scans_003.json", testFileContent, map[string]string{"locationPattern": locationPattern})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"id": 6, "duplicate": false}`, recorder.Body.String())
+}
+
+func (suite *ScanControllerTestSuite) TestUploadBulkScanFileWithInvalidLocationPattern() {
+	// Given
+	router := gin.Default()
+	router.POST("/upload-bulk-scan-file", suite.scanController.UploadBulkScanFile)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request := suite.createUploadRequest("scans_003.json", `[]`, map[string]string{"locationPattern": `^(?P<row>[A-Z])$`})
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.Contains(recorder.Body.String(), "unknown group=row")
+}
+
+func (suite *ScanControllerTestSuite) TestDownloadValidationReport() {
+	// Given
+	reportFile, err := os.CreateTemp("", "validation_report_*.json")
+	suite.Require().NoError(err)
+	defer os.Remove(reportFile.Name())
+	_, err = reportFile.WriteString(`{"invalidRecords": 1}`)
+	suite.Require().NoError(err)
+	suite.Require().NoError(reportFile.Close())
+
+	bulkScanRecord := models.BulkScanRecord{Status: models.Completed, InvalidRecordCount: 1, ValidationReportPath: reportFile.Name()}
+	bulkScanRecord.ID = uint(1)
+	suite.mockBulkScanRecordClient.EXPECT().Get(uint(1)).Return(&bulkScanRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/bulk-scan-records/:id/validation-report", suite.scanController.DownloadValidationReport)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/bulk-scan-records/1/validation-report", nil)
+	router.ServeHTTP(recorder, request)
 
 	// Then
 	suite.Equal(http.StatusOK, recorder.Code)
-	suite.JSONEq(`{"id": 1}`, recorder.Body.String())
+	suite.Equal("attachment; filename="+filepath.Base(reportFile.Name()), recorder.Header().Get("Content-Disposition"))
+	suite.JSONEq(`{"invalidRecords": 1}`, recorder.Body.String())
+}
+
+func (suite *ScanControllerTestSuite) TestDownloadValidationReportNotAvailable() {
+	// Given
+	bulkScanRecord := models.BulkScanRecord{Status: models.Completed}
+	bulkScanRecord.ID = uint(1)
+	suite.mockBulkScanRecordClient.EXPECT().Get(uint(1)).Return(&bulkScanRecord, nil).Times(1)
+
+	router := gin.Default()
+	router.GET("/bulk-scan-records/:id/validation-report", suite.scanController.DownloadValidationReport)
+
+	// When
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/bulk-scan-records/1/validation-report", nil)
+	router.ServeHTTP(recorder, request)
+
+	// Then
+	suite.Equal(http.StatusNotFound, recorder.Code)
+}
+
+func (suite *ScanControllerTestSuite) createUploadRequest(fileName, content string, fields map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, value := range fields {
+		suite.Require().NoError(writer.WriteField(key, value))
+	}
+	part, err := writer.CreateFormFile("file", fileName)
+	suite.Require().NoError(err)
+	_, err = part.Write([]byte(content))
+	suite.Require().NoError(err)
+	suite.Require().NoError(writer.Close())
+
+	request, err := http.NewRequest("POST", "/upload-bulk-scan-file", body)
+	suite.Require().NoError(err)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return request
+}
+
+func (suite *ScanControllerTestSuite) contentHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
 }
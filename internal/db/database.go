@@ -32,14 +32,26 @@ func (db *Database) Migrate() error {
 	err := db.DB.AutoMigrate(
 		&models.BulkScanRecord{},
 		&models.Scan{},
+		&models.ColumnMappingProfile{},
 		&models.ReportRecord{},
 		&models.ComparisonData{},
 		&models.ExportReportRecord{},
+		&models.Job{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to run migration in database, error: %w", err)
 	}
 
+	// the duplicate check of an upload does not see a concurrent upload of the same file, the index settles it. Records
+	// saved before content hashes were stored have none and are left out.
+	createActiveContentHashIndexQuery := fmt.Sprintf(
+		"CREATE UNIQUE INDEX IF NOT EXISTS %s ON bulk_scan_records (content_hash) WHERE content_hash <> '' AND status <> '%s' AND NOT allow_duplicate AND deleted_at IS NULL",
+		models.ActiveContentHashIndex, models.Failed,
+	)
+	if err := db.DB.Exec(createActiveContentHashIndexQuery).Error; err != nil {
+		return fmt.Errorf("failed to create index=%s in database, error: %w", models.ActiveContentHashIndex, err)
+	}
+
 	return nil
 }
 
@@ -2,7 +2,69 @@ package internal
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/habbas99/dexory/internal/models"
 )
 
 var ErrEntityNotFound = errors.New("entity not found in database")
+var ErrDuplicateEntity = errors.New("entity already exists in database")
 var ErrComparisonCaseNotSupported = errors.New("comparison case not supported")
+
+// ProcessingError ties a processing failure to the record of the file it happened on, so the reason can be
+// persisted on the failed record.
+type ProcessingError struct {
+	Code         models.ErrorCode
+	RecordNumber int
+	Err          error
+}
+
+func (e *ProcessingError) Error() string {
+	return fmt.Sprintf("record=%d, error: %v", e.RecordNumber, e.Err)
+}
+
+func (e *ProcessingError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError marks a failure retrying can not fix, the job worker fails the job of such a failure without
+// retrying it.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// FailureError returns the error of a failed record, wrapped in a PermanentError when the failure is permanent.
+func FailureError(failure models.Failure, err error) error {
+	if failure.ErrorCode.IsPermanent() {
+		return &PermanentError{Err: err}
+	}
+
+	return err
+}
+
+// NewFailure describes the failure of a record, preferring the code and record number of a ProcessingError wrapped
+// in err over the code given by the caller.
+func NewFailure(code models.ErrorCode, err error) models.Failure {
+	failure := models.Failure{
+		ErrorCode:    code,
+		ErrorMessage: err.Error(),
+	}
+
+	var processingErr *ProcessingError
+	if errors.As(err, &processingErr) {
+		if processingErr.Code != "" {
+			failure.ErrorCode = processingErr.Code
+		}
+		failure.FailedRecordNumber = processingErr.RecordNumber
+	}
+
+	return failure
+}
@@ -8,3 +8,34 @@ const (
 	Completed  Status = "completed"
 	Failed     Status = "failed"
 )
+
+type ErrorCode string
+
+const (
+	ErrorCodeFileUnreadable ErrorCode = "file_unreadable"
+	ErrorCodeMalformedFile  ErrorCode = "malformed_file"
+	ErrorCodeInvalidHeaders ErrorCode = "invalid_headers"
+	ErrorCodeInvalidRecords ErrorCode = "invalid_records"
+	ErrorCodeDatabase       ErrorCode = "database_error"
+	ErrorCodeWriteFailed    ErrorCode = "write_failed"
+	ErrorCodeInternal       ErrorCode = "internal_error"
+)
+
+// IsPermanent reports whether a failure with the code happens again when the job is retried, the file has to be
+// fixed and uploaded again instead.
+func (code ErrorCode) IsPermanent() bool {
+	switch code {
+	case ErrorCodeMalformedFile, ErrorCodeInvalidHeaders, ErrorCodeInvalidRecords:
+		return true
+	default:
+		return false
+	}
+}
+
+// Failure records why processing a record failed. FailedRecordNumber is the 1-based object, line or row the failure
+// happened on, or 0 when the failure is not tied to a single record of the file.
+type Failure struct {
+	ErrorCode          ErrorCode
+	ErrorMessage       string
+	FailedRecordNumber int
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type JobType string
+
+const (
+	ScanIngestionJob  JobType = "scan_ingestion"
+	ComparisonDataJob JobType = "comparison_data"
+	ExportReportJob   JobType = "export_report"
+)
+
+type Job struct {
+	gorm.Model
+	Type      JobType `gorm:"index"`
+	RecordID  uint
+	Status    Status    `gorm:"index"`
+	RunAt     time.Time `gorm:"index"`
+	Attempts  int
+	LockedBy  string
+	LockedAt  *time.Time
+	LastError string
+}
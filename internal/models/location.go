@@ -0,0 +1,73 @@
+package models
+
+// LocationField is a level of the warehouse hierarchy encoded in a location code.
+type LocationField string
+
+const (
+	LocationZone  LocationField = "zone"
+	LocationAisle LocationField = "aisle"
+	LocationBay   LocationField = "bay"
+	LocationLevel LocationField = "level"
+)
+
+// LocationFields are the levels of the warehouse hierarchy, from the widest to the narrowest.
+var LocationFields = []LocationField{LocationZone, LocationAisle, LocationBay, LocationLevel}
+
+// IsValid reports whether the field is one of LocationFields.
+func (f LocationField) IsValid() bool {
+	for _, field := range LocationFields {
+		if field == f {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LocationHierarchy is where a location sits in the warehouse, as parsed from its location code. Fields the location
+// grammar does not define, and every field of a location the grammar does not match, are empty.
+type LocationHierarchy struct {
+	Zone  string
+	Aisle string
+	Bay   string
+	Level string
+}
+
+// Get returns the value of the field.
+func (h LocationHierarchy) Get(field LocationField) string {
+	switch field {
+	case LocationZone:
+		return h.Zone
+	case LocationAisle:
+		return h.Aisle
+	case LocationBay:
+		return h.Bay
+	case LocationLevel:
+		return h.Level
+	default:
+		return ""
+	}
+}
+
+// Set sets the value of the field.
+func (h *LocationHierarchy) Set(field LocationField, value string) {
+	switch field {
+	case LocationZone:
+		h.Zone = value
+	case LocationAisle:
+		h.Aisle = value
+	case LocationBay:
+		h.Bay = value
+	case LocationLevel:
+		h.Level = value
+	}
+}
+
+// LocationGroupSummary is the summary of the locations of a report sharing the grouped fields of their hierarchy,
+// fields that are not grouped are empty. Locations the location grammar did not match are grouped apart as unparsed
+// instead of with the locations whose grouped fields are empty.
+type LocationGroupSummary struct {
+	LocationHierarchy
+	Unparsed bool
+	ReportSummary
+}
@@ -1,6 +1,11 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+
 	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
@@ -14,33 +19,425 @@ const (
 	LocationOccupiedWithWrongItems          ScanComparisonOutcome = "The location was occupied by the wrong items"
 	LocationOccupiedButExpectedEmpty        ScanComparisonOutcome = "The location was occupied by an item, but should have been empty"
 	LocationOccupiedButBarcodeNotIdentified ScanComparisonOutcome = "The location was occupied, but no barcode could be identified"
+	LocationNotScanned                      ScanComparisonOutcome = "The location was not scanned"
+	LocationMissingFromRobotData            ScanComparisonOutcome = "The location was missing from the robot data"
+	LocationNotInExpectedInventory          ScanComparisonOutcome = "The location was not in the expected inventory"
+	LocationMissingExpectedItems            ScanComparisonOutcome = "The location was occupied, but some expected items were missing"
+	LocationOccupiedWithUnexpectedItems     ScanComparisonOutcome = "The location was occupied by the expected items, along with unexpected items"
+	LocationOccupiedWithSomeExpectedItems   ScanComparisonOutcome = "The location was occupied by some of the expected items, along with unexpected items"
+
+	// outcomes of a scan diff, the change of a location between the baseline bulk scan and the later bulk scan
+	LocationUnchanged       ScanComparisonOutcome = "The location did not change"
+	LocationNewlyOccupied   ScanComparisonOutcome = "The location was empty, but is now occupied"
+	LocationNewlyEmptied    ScanComparisonOutcome = "The location was occupied, but is now empty"
+	LocationBarcodeChanged  ScanComparisonOutcome = "The location is occupied by different items"
+	LocationNewlyUnreadable ScanComparisonOutcome = "The location could be read, but now can not be"
+	LocationNewlyReadable   ScanComparisonOutcome = "The location could not be read, but now can be"
+	LocationAdded           ScanComparisonOutcome = "The location was not in the baseline scan, but is in the later scan"
+	LocationRemoved         ScanComparisonOutcome = "The location was in the baseline scan, but is not in the later scan"
+)
+
+// ScanComparisonOutcomes lists every outcome, the outcomes where the location matched the expected inventory first.
+var ScanComparisonOutcomes = []ScanComparisonOutcome{
+	LocationEmptyAsExpected,
+	LocationOccupiedWithCorrectItems,
+	LocationEmptyButNotExpected,
+	LocationOccupiedWithWrongItems,
+	LocationOccupiedButExpectedEmpty,
+	LocationOccupiedButBarcodeNotIdentified,
+	LocationNotScanned,
+	LocationMissingFromRobotData,
+	LocationNotInExpectedInventory,
+	LocationMissingExpectedItems,
+	LocationOccupiedWithUnexpectedItems,
+	LocationOccupiedWithSomeExpectedItems,
+}
+
+// ScanDiffOutcomes lists every outcome of a scan diff, the unchanged locations first.
+var ScanDiffOutcomes = []ScanComparisonOutcome{
+	LocationUnchanged,
+	LocationNewlyOccupied,
+	LocationNewlyEmptied,
+	LocationBarcodeChanged,
+	LocationNewlyUnreadable,
+	LocationNewlyReadable,
+	LocationAdded,
+	LocationRemoved,
+}
+
+// IsValid reports whether the outcome is one of ScanComparisonOutcomes or ScanDiffOutcomes.
+func (o ScanComparisonOutcome) IsValid() bool {
+	for _, outcome := range AllOutcomes() {
+		if outcome == o {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsMatch reports whether the location matched the expected inventory, or did not change in a scan diff. Every
+// other outcome is a discrepancy.
+func (o ScanComparisonOutcome) IsMatch() bool {
+	return o == LocationEmptyAsExpected || o == LocationOccupiedWithCorrectItems || o == LocationUnchanged
+}
+
+// DiscrepancyChange is what became of the discrepancy of a location between a baseline report and a later report
+// of the same warehouse.
+type DiscrepancyChange string
+
+const (
+	// DiscrepancyResolved is a discrepancy of the baseline report the later report no longer has.
+	DiscrepancyResolved DiscrepancyChange = "resolved"
+	// DiscrepancyPersistent is a discrepancy both reports have, whether or not its outcome changed.
+	DiscrepancyPersistent DiscrepancyChange = "persistent"
+	// DiscrepancyNew is a discrepancy of the later report the baseline report did not have.
+	DiscrepancyNew DiscrepancyChange = "new"
+)
+
+// DiscrepancyChanges lists every change of a discrepancy.
+var DiscrepancyChanges = []DiscrepancyChange{
+	DiscrepancyResolved,
+	DiscrepancyPersistent,
+	DiscrepancyNew,
+}
+
+func (c DiscrepancyChange) IsValid() bool {
+	for _, change := range DiscrepancyChanges {
+		if change == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DiscrepancyChangeOf returns the change of a location from its outcome in the baseline report to its outcome in the
+// later report, an empty outcome being a location the report does not have. A location without a discrepancy in
+// either report has no change.
+func DiscrepancyChangeOf(previousResult, result ScanComparisonOutcome) DiscrepancyChange {
+	wasDiscrepancy := previousResult != "" && !previousResult.IsMatch()
+	isDiscrepancy := result != "" && !result.IsMatch()
+
+	switch {
+	case wasDiscrepancy && isDiscrepancy:
+		return DiscrepancyPersistent
+	case wasDiscrepancy:
+		return DiscrepancyResolved
+	case isDiscrepancy:
+		return DiscrepancyNew
+	default:
+		return ""
+	}
+}
+
+// ReportDiffEntry is a location with a discrepancy in a baseline report, a later report or both, with its outcome in
+// each. The outcome is empty in a report that does not have the location.
+type ReportDiffEntry struct {
+	Location       string
+	Change         DiscrepancyChange
+	PreviousResult ScanComparisonOutcome
+	Result         ScanComparisonOutcome
+}
+
+// ReportDiffQuery selects a page of the diff of two reports, ordered by location. Changes keeps the locations with one
+// of the changes, every change without any. The page holds up to Limit locations after the location After, or from
+// the start without one.
+type ReportDiffQuery struct {
+	Changes []DiscrepancyChange
+	After   string
+	Limit   int
+}
+
+// AllOutcomes lists the outcomes of every report mode.
+func AllOutcomes() []ScanComparisonOutcome {
+	outcomes := make([]ScanComparisonOutcome, 0, len(ScanComparisonOutcomes)+len(ScanDiffOutcomes))
+	outcomes = append(outcomes, ScanComparisonOutcomes...)
+	return append(outcomes, ScanDiffOutcomes...)
+}
+
+// ReportMode is what the bulk scan of a report is compared against.
+type ReportMode string
+
+const (
+	// ComparisonReport compares a bulk scan against the reference file of a customer, reports created before scan
+	// diffs existed are migrated to it by the column default.
+	ComparisonReport ReportMode = "comparison"
+	// ScanDiffReport compares a bulk scan against an earlier baseline bulk scan of the same warehouse.
+	ScanDiffReport ReportMode = "scan_diff"
 )
 
+// Outcomes lists the outcomes a report of the mode can have.
+func (m ReportMode) Outcomes() []ScanComparisonOutcome {
+	if m == ScanDiffReport {
+		return ScanDiffOutcomes
+	}
+
+	return ScanComparisonOutcomes
+}
+
 type ExportReportType string
 
 const (
 	ExportReportJson ExportReportType = "json"
 	ExportReportCsv  ExportReportType = "csv"
+	ExportReportXlsx ExportReportType = "xlsx"
+	ExportReportPdf  ExportReportType = "pdf"
+	// ExportReportNdjson is only streamed, it is never stored as an export file
+	ExportReportNdjson ExportReportType = "ndjson"
+)
+
+// ExportColumn is a column of an exported report.
+type ExportColumn string
+
+const (
+	ExportColumnLocation           ExportColumn = "location"
+	ExportColumnScanned            ExportColumn = "scanned"
+	ExportColumnOccupied           ExportColumn = "occupied"
+	ExportColumnActualBarcodes     ExportColumn = "actualBarcodes"
+	ExportColumnExpectedBarcodes   ExportColumn = "expectedBarcodes"
+	ExportColumnMatchedBarcodes    ExportColumn = "matchedBarcodes"
+	ExportColumnMissingBarcodes    ExportColumn = "missingBarcodes"
+	ExportColumnUnexpectedBarcodes ExportColumn = "unexpectedBarcodes"
+	ExportColumnResult             ExportColumn = "result"
+	ExportColumnPreviousResult     ExportColumn = "previousResult"
+	ExportColumnDiscrepancyChange  ExportColumn = "discrepancyChange"
 )
 
+// ExportColumns lists every column of an exported report in the order they are exported.
+var ExportColumns = []ExportColumn{
+	ExportColumnLocation,
+	ExportColumnScanned,
+	ExportColumnOccupied,
+	ExportColumnActualBarcodes,
+	ExportColumnExpectedBarcodes,
+	ExportColumnMatchedBarcodes,
+	ExportColumnMissingBarcodes,
+	ExportColumnUnexpectedBarcodes,
+	ExportColumnResult,
+}
+
+// ReportDiffColumns are the columns added after ExportColumns when a report is exported compared against a baseline
+// report.
+var ReportDiffColumns = []ExportColumn{
+	ExportColumnPreviousResult,
+	ExportColumnDiscrepancyChange,
+}
+
+// ExportFilter selects the locations and the columns of an exported report, an empty filter exports every location
+// with every column. Results keeps the locations with one of the outcomes, DiscrepanciesOnly leaves out the
+// locations that matched the expected inventory, LocationPrefix keeps the locations starting with it and Scanned and
+// Occupied keep the locations with that flag when set. Columns selects the exported columns. BaselineReportRecordID
+// compares every location against its outcome in an earlier report, which adds the ReportDiffColumns.
+type ExportFilter struct {
+	Results                pq.StringArray `gorm:"type:text[]"`
+	DiscrepanciesOnly      bool
+	LocationPrefix         string
+	Scanned                *bool
+	Occupied               *bool
+	Columns                pq.StringArray `gorm:"type:text[]"`
+	BaselineReportRecordID *uint          `json:",omitempty"`
+}
+
+func (f ExportFilter) IsEmpty() bool {
+	return len(f.Results) == 0 && !f.DiscrepanciesOnly && f.LocationPrefix == "" && f.Scanned == nil &&
+		f.Occupied == nil && len(f.Columns) == 0 && f.BaselineReportRecordID == nil
+}
+
+// AvailableColumns lists the columns the filter can select in the order they are exported, the ReportDiffColumns
+// are only available when the report is compared against a baseline report.
+func (f ExportFilter) AvailableColumns() []ExportColumn {
+	if f.BaselineReportRecordID == nil {
+		return ExportColumns
+	}
+
+	columns := make([]ExportColumn, 0, len(ExportColumns)+len(ReportDiffColumns))
+	columns = append(columns, ExportColumns...)
+	return append(columns, ReportDiffColumns...)
+}
+
+// Key identifies the filter among the exports of a report, exports with an equal filter are the same export. The
+// key of an empty filter is empty. Results and Columns are expected in a canonical order, a different order is a
+// different key.
+func (f ExportFilter) Key() string {
+	if f.IsEmpty() {
+		return ""
+	}
+
+	definition, _ := json.Marshal(f)
+	hash := sha256.Sum256(definition)
+	return hex.EncodeToString(hash[:])
+}
+
+// FileEncoding is the character encoding of a customer reference file.
+type FileEncoding string
+
+const (
+	UTF8Encoding        FileEncoding = "utf-8"
+	UTF16Encoding       FileEncoding = "utf-16"
+	Windows1252Encoding FileEncoding = "windows-1252"
+	ISO88591Encoding    FileEncoding = "iso-8859-1"
+)
+
+// ColumnMappingProfile describes the reference file format of a customer, the WMS exports of customers name their
+// columns differently and do not all use commas or UTF-8.
+type ColumnMappingProfile struct {
+	gorm.Model
+	Name           string `gorm:"uniqueIndex"`
+	LocationColumn string
+	BarcodeColumn  string
+	Delimiter      string
+	Encoding       FileEncoding
+	// LocationPattern is the location grammar of the warehouse the profile reads reference files of, empty for the
+	// default grammar
+	LocationPattern string
+}
+
 type ReportRecord struct {
 	gorm.Model
-	BulkScanRecordID  uint           `gorm:"index"`
-	BulkScanRecord    BulkScanRecord `gorm:"foreignKey:BulkScanRecordID;references:ID"`
-	ReferenceFileName string
-	ReferenceFilePath string
-	Status            Status
+	Mode                     ReportMode      `gorm:"default:comparison"`
+	BulkScanRecordID         uint            `gorm:"index"`
+	BulkScanRecord           BulkScanRecord  `gorm:"foreignKey:BulkScanRecordID;references:ID"`
+	BaselineBulkScanRecordID *uint           `gorm:"index"`
+	BaselineBulkScanRecord   *BulkScanRecord `gorm:"foreignKey:BaselineBulkScanRecordID;references:ID"`
+	ColumnMappingProfileID   *uint
+	ColumnMappingProfile     *ColumnMappingProfile `gorm:"foreignKey:ColumnMappingProfileID;references:ID"`
+	ReferenceFileName        string
+	ReferenceFilePath        string
+	ReferenceSheet           string
+	ReferenceFileHash        string `gorm:"index"`
+	Status                   Status
+	Failure
+}
+
+// LocationPattern returns the location grammar of the warehouse of the report, the location pattern of its column
+// mapping profile or else the one its bulk scan was uploaded with. It is empty for the default grammar.
+func (r ReportRecord) LocationPattern() string {
+	if r.ColumnMappingProfile != nil && r.ColumnMappingProfile.LocationPattern != "" {
+		return r.ColumnMappingProfile.LocationPattern
+	}
+
+	return r.BulkScanRecord.LocationPattern
+}
+
+// SameWarehouse reports whether the reports are of the same warehouse, their reference files read with the same
+// column mapping profile and their locations parsed with the same location pattern.
+func (r ReportRecord) SameWarehouse(other ReportRecord) bool {
+	sameProfile := r.ColumnMappingProfileID == nil && other.ColumnMappingProfileID == nil ||
+		r.ColumnMappingProfileID != nil && other.ColumnMappingProfileID != nil && *r.ColumnMappingProfileID == *other.ColumnMappingProfileID
+
+	return sameProfile && r.LocationPattern() == other.LocationPattern()
 }
 
 type ComparisonData struct {
-	Location         string
-	Scanned          bool
-	Occupied         bool
-	ActualBarcodes   pq.StringArray `gorm:"type:text[]"`
-	ExpectedBarcodes pq.StringArray `gorm:"type:text[]"`
-	Result           ScanComparisonOutcome
-	ReportRecordID   uint
-	ReportRecord     ReportRecord `gorm:"foreignKey:ReportRecordID;references:ID"`
+	ID        uint              `gorm:"primaryKey"`
+	Location  string            `gorm:"index:idx_comparison_data_report_location,priority:2"`
+	Hierarchy LocationHierarchy `gorm:"embedded;embeddedPrefix:location_"`
+	// LocationParsed is whether the location grammar matched the location, nil for comparison data stored before it
+	// was recorded
+	LocationParsed     *bool
+	Scanned            bool
+	Occupied           bool
+	ActualBarcodes     pq.StringArray `gorm:"type:text[]"`
+	ExpectedBarcodes   pq.StringArray `gorm:"type:text[]"`
+	MatchedBarcodes    pq.StringArray `gorm:"type:text[]"`
+	MissingBarcodes    pq.StringArray `gorm:"type:text[]"`
+	UnexpectedBarcodes pq.StringArray `gorm:"type:text[]"`
+	Result             ScanComparisonOutcome
+	ReportRecordID     uint         `gorm:"index:idx_comparison_data_report_location,priority:1"`
+	ReportRecord       ReportRecord `gorm:"foreignKey:ReportRecordID;references:ID"`
+	// PreviousResult is the outcome of the location in the baseline report an export is compared against, it is
+	// not stored
+	PreviousResult ScanComparisonOutcome `gorm:"-"`
+}
+
+// DiscrepancyChange is the change of the discrepancy of the location since the baseline report.
+func (d ComparisonData) DiscrepancyChange() DiscrepancyChange {
+	return DiscrepancyChangeOf(d.PreviousResult, d.Result)
+}
+
+// ReportSummary counts the locations of a report by outcome, by whether they were scanned and by whether they were
+// occupied.
+type ReportSummary struct {
+	Counts   map[ScanComparisonOutcome]int64
+	Total    int64
+	Scanned  int64
+	Occupied int64
+	Matched  int64
+}
+
+// Add counts locations of the outcome that share whether they were scanned and whether they were occupied.
+func (s *ReportSummary) Add(outcome ScanComparisonOutcome, scanned bool, occupied bool, count int64) {
+	if s.Counts == nil {
+		s.Counts = make(map[ScanComparisonOutcome]int64)
+	}
+
+	s.Counts[outcome] += count
+	s.Total += count
+	if scanned {
+		s.Scanned += count
+	}
+	if occupied {
+		s.Occupied += count
+	}
+	if outcome.IsMatch() {
+		s.Matched += count
+	}
+}
+
+// Discrepancies counts the locations that did not match the expected inventory.
+func (s ReportSummary) Discrepancies() int64 {
+	return s.Total - s.Matched
+}
+
+// Accuracy is the share of locations that matched the expected inventory, as a percentage.
+func (s ReportSummary) Accuracy() float64 {
+	return percentage(s.Matched, s.Total)
+}
+
+// Share is the share of locations with the outcome, as a percentage.
+func (s ReportSummary) Share(outcome ScanComparisonOutcome) float64 {
+	return percentage(s.Counts[outcome], s.Total)
+}
+
+// percentage is rounded to two decimals.
+func percentage(count int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return math.Round(float64(count)*10000/float64(total)) / 100
+}
+
+// ComparisonDataSort is the column the comparison data of a report is sorted by.
+type ComparisonDataSort string
+
+const (
+	SortByLocation ComparisonDataSort = "location"
+	SortByResult   ComparisonDataSort = "result"
+	SortByScanned  ComparisonDataSort = "scanned"
+	SortByOccupied ComparisonDataSort = "occupied"
+)
+
+// ComparisonDataCursor is the position of a location in the sorted comparison data, the value of the sort column and
+// the id that breaks ties between locations with the same value.
+type ComparisonDataCursor struct {
+	Value string
+	ID    uint
+}
+
+// ComparisonDataPageQuery selects a page of the comparison data of a report. Filter selects the locations the same
+// way as for an export, Barcode keeps the locations where the barcode was scanned or expected and Hierarchy keeps the
+// locations with its non-empty fields. The page holds up to Limit locations after the After cursor, or from the start
+// without one.
+type ComparisonDataPageQuery struct {
+	Filter     ExportFilter
+	Barcode    string
+	Hierarchy  LocationHierarchy
+	Sort       ComparisonDataSort
+	Descending bool
+	After      *ComparisonDataCursor
+	Limit      int
 }
 
 type ExportReportRecord struct {
@@ -51,4 +448,7 @@ type ExportReportRecord struct {
 	Status         Status
 	ReportRecordID uint
 	ReportRecord   ReportRecord `gorm:"foreignKey:ReportRecordID;references:ID"`
+	Filter         ExportFilter `gorm:"embedded;embeddedPrefix:filter_"`
+	FilterKey      string       `gorm:"index"`
+	Failure
 }
@@ -5,16 +5,43 @@ import (
 	"gorm.io/gorm"
 )
 
+// ValidationPolicy decides what happens to a bulk scan file that contains invalid records.
+type ValidationPolicy string
+
+const (
+	// RejectInvalidScans fails the whole file when any record is invalid.
+	RejectInvalidScans ValidationPolicy = "reject"
+	// QuarantineInvalidScans ingests the valid records and sets the invalid ones aside in the validation report.
+	QuarantineInvalidScans ValidationPolicy = "quarantine"
+)
+
+// ActiveContentHashIndex is the unique index of the content hashes of the bulk scan records that have not failed, so
+// two uploads of the same file can not both be ingested.
+const ActiveContentHashIndex = "idx_bulk_scan_records_active_content_hash"
+
 type BulkScanRecord struct {
 	gorm.Model
-	FileName string
-	FilePath string
-	Status   Status
+	FileName    string
+	FilePath    string
+	ContentHash string `gorm:"index"`
+	// AllowDuplicate is set when the content was deliberately ingested again, such a record is left out of
+	// ActiveContentHashIndex
+	AllowDuplicate       bool
+	Status               Status
+	ValidationPolicy     ValidationPolicy
+	InvalidRecordCount   int
+	ValidationReportPath string
+	// LocationPattern is the location grammar of the warehouse the file was scanned in, empty for the default grammar
+	LocationPattern string
+	Failure
 }
 
 type Scan struct {
 	gorm.Model
-	Location         string
+	Location  string
+	Hierarchy LocationHierarchy `gorm:"embedded;embeddedPrefix:location_"`
+	// LocationParsed is whether the location grammar matched the location, nil for scans stored before it was recorded
+	LocationParsed   *bool
 	Scanned          bool
 	Occupied         bool
 	Barcodes         pq.StringArray `gorm:"type:text[]"`
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/habbas99/dexory/internal"
 	"github.com/habbas99/dexory/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
@@ -34,16 +36,37 @@ func (bs *BulkScanRecordRepository) GetAll() ([]models.BulkScanRecord, error) {
 	return bulkScanRecords, nil
 }
 
-func (bs *BulkScanRecordRepository) Create(filePath string) (*models.BulkScanRecord, error) {
+// uniqueViolationCode is the postgres error code of a row violating a unique index.
+const uniqueViolationCode = "23505"
+
+// Create saves the bulk scan record together with the job that ingests the file. It returns an error wrapping
+// internal.ErrDuplicateEntity when a bulk scan record of the same content that has not failed already exists, unless
+// allowDuplicate is set.
+func (bs *BulkScanRecordRepository) Create(filePath, contentHash string, allowDuplicate bool, validationPolicy models.ValidationPolicy, locationPattern string) (*models.BulkScanRecord, error) {
 	bulkScanRecord := models.BulkScanRecord{
-		FileName: filepath.Base(filePath),
-		FilePath: filePath,
-		Status:   models.Pending,
+		FileName:         filepath.Base(filePath),
+		FilePath:         filePath,
+		ContentHash:      contentHash,
+		AllowDuplicate:   allowDuplicate,
+		Status:           models.Pending,
+		ValidationPolicy: validationPolicy,
+		LocationPattern:  locationPattern,
 	}
 
-	result := bs.DB.Create(&bulkScanRecord)
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to create bulk scan record, error: %w", result.Error)
+	err := bs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&bulkScanRecord).Error; err != nil {
+			return err
+		}
+
+		return enqueueJob(tx, models.ScanIngestionJob, bulkScanRecord.ID)
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode && pgErr.ConstraintName == models.ActiveContentHashIndex {
+			return nil, fmt.Errorf("bulk scan record with content hash=%s, error: %w", contentHash, internal.ErrDuplicateEntity)
+		}
+
+		return nil, fmt.Errorf("failed to create bulk scan record, error: %w", err)
 	}
 
 	return &bulkScanRecord, nil
@@ -59,9 +82,15 @@ func (bs *BulkScanRecordRepository) Get(bulkScanRecordID uint) (*models.BulkScan
 	return &bulkScanRecord, nil
 }
 
+// GetByFileName returns the most recent completed bulk scan record uploaded with the given file name. A file
+// that failed to ingest and was uploaded again shares its name with the earlier record, so the latest record is
+// only returned when none of them has completed.
 func (bs *BulkScanRecordRepository) GetByFileName(fileName string) (*models.BulkScanRecord, error) {
 	var bulkScanRecord models.BulkScanRecord
-	result := bs.DB.Where(&models.BulkScanRecord{FileName: fileName}).First(&bulkScanRecord)
+	result := bs.DB.Where(&models.BulkScanRecord{FileName: fileName, Status: models.Completed}).Order("id desc").First(&bulkScanRecord)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		result = bs.DB.Where(&models.BulkScanRecord{FileName: fileName}).Order("id desc").First(&bulkScanRecord)
+	}
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -74,6 +103,23 @@ func (bs *BulkScanRecordRepository) GetByFileName(fileName string) (*models.Bulk
 	return &bulkScanRecord, nil
 }
 
+// GetByContentHash returns the most recent bulk scan record uploaded with the given content hash that has not
+// failed, or nil when the content has not been uploaded before or every upload of it failed.
+func (bs *BulkScanRecordRepository) GetByContentHash(contentHash string) (*models.BulkScanRecord, error) {
+	var bulkScanRecord models.BulkScanRecord
+	result := bs.DB.Where("content_hash = ? AND status <> ?", contentHash, models.Failed).Order("id desc").First(&bulkScanRecord)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to retrieve bulk scan record by content hash, error: %w", result.Error)
+	}
+
+	return &bulkScanRecord, nil
+}
+
 func (bs *BulkScanRecordRepository) Update(bulkScanRecord *models.BulkScanRecord) error {
 	result := bs.DB.Save(bulkScanRecord)
 	if result.Error != nil {
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/habbas99/dexory/internal/models"
+	"gorm.io/gorm"
+)
+
+type ColumnMappingProfileRepository struct {
+	DB *gorm.DB
+}
+
+func NewColumnMappingProfileRepository(db *gorm.DB) *ColumnMappingProfileRepository {
+	return &ColumnMappingProfileRepository{
+		DB: db,
+	}
+}
+
+func (cm *ColumnMappingProfileRepository) GetAll() ([]models.ColumnMappingProfile, error) {
+	var columnMappingProfiles []models.ColumnMappingProfile
+
+	result := cm.DB.Order("name").Find(&columnMappingProfiles)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get all column mapping profiles, error: %w", result.Error)
+	}
+
+	return columnMappingProfiles, nil
+}
+
+func (cm *ColumnMappingProfileRepository) Create(columnMappingProfile *models.ColumnMappingProfile) error {
+	result := cm.DB.Create(columnMappingProfile)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create column mapping profile with name=%s, error: %w", columnMappingProfile.Name, result.Error)
+	}
+
+	return nil
+}
+
+// GetByName returns the column mapping profile with the given name, or nil when there is none.
+func (cm *ColumnMappingProfileRepository) GetByName(name string) (*models.ColumnMappingProfile, error) {
+	var columnMappingProfile models.ColumnMappingProfile
+	result := cm.DB.Where(&models.ColumnMappingProfile{Name: name}).First(&columnMappingProfile)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to retrieve column mapping profile by name=%s, error: %w", name, result.Error)
+	}
+
+	return &columnMappingProfile, nil
+}
@@ -2,6 +2,9 @@ package repositories
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/habbas99/dexory/internal/models"
 	"gorm.io/gorm"
 )
@@ -16,17 +19,304 @@ func NewComparisonDataRepository(db *gorm.DB) *ComparisonDataRepository {
 	}
 }
 
-func (rr *ComparisonDataRepository) GetAllPaginated(reportRecordID uint, limit int, offset int) ([]models.ComparisonData, error) {
+// GetPage returns a page of the comparison data of a report. Pages are read by keyset on the sort column and the id,
+// so a page is found through the index and stays stable while the report is read.
+func (rr *ComparisonDataRepository) GetPage(reportRecordID uint, pageQuery models.ComparisonDataPageQuery) ([]models.ComparisonData, error) {
 	var comparisonDataList []models.ComparisonData
 
-	result := rr.DB.Where(&models.ComparisonData{ReportRecordID: reportRecordID}).Limit(limit).Offset(offset).Find(&comparisonDataList)
+	column, err := sortColumn(pageQuery.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	direction := "ASC"
+	comparison := ">"
+	if pageQuery.Descending {
+		direction = "DESC"
+		comparison = "<"
+	}
+
+	query := rr.filterPage(reportRecordID, pageQuery)
+	if pageQuery.After != nil {
+		value, err := cursorValue(pageQuery.Sort, pageQuery.After.Value)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), value, pageQuery.After.ID)
+	}
+
+	result := query.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).Limit(pageQuery.Limit).Find(&comparisonDataList)
 	if result.Error != nil {
-		return nil, fmt.Errorf("failed to get paginated comparison data, error: %w", result.Error)
+		return nil, fmt.Errorf("failed to get page of comparison data, error: %w", result.Error)
 	}
 
 	return comparisonDataList, nil
 }
 
+// Count returns the number of locations of a report that pass the filter of the page query, whatever page it is on.
+func (rr *ComparisonDataRepository) Count(reportRecordID uint, pageQuery models.ComparisonDataPageQuery) (int64, error) {
+	var count int64
+
+	result := rr.filterPage(reportRecordID, pageQuery).Model(&models.ComparisonData{}).Count(&count)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to count comparison data, error: %w", result.Error)
+	}
+
+	return count, nil
+}
+
+// GetSummary counts the locations of a report in the database, so the summary does not need the locations to be read.
+func (rr *ComparisonDataRepository) GetSummary(reportRecordID uint) (*models.ReportSummary, error) {
+	var groups []struct {
+		Result   models.ScanComparisonOutcome
+		Scanned  bool
+		Occupied bool
+		Count    int64
+	}
+
+	result := rr.DB.Model(&models.ComparisonData{}).
+		Select("result, scanned, occupied, COUNT(*) AS count").
+		Where("report_record_id = ?", reportRecordID).
+		Group("result, scanned, occupied").
+		Scan(&groups)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get summary of comparison data, error: %w", result.Error)
+	}
+
+	summary := &models.ReportSummary{Counts: make(map[models.ScanComparisonOutcome]int64)}
+	for _, group := range groups {
+		summary.Add(group.Result, group.Scanned, group.Occupied, group.Count)
+	}
+
+	return summary, nil
+}
+
+// GetLocationSummary counts the locations of a report by the grouped fields of their hierarchy, ordered by those
+// fields. Locations outside of within, a hierarchy of non-empty fields to match, are not counted.
+func (rr *ComparisonDataRepository) GetLocationSummary(reportRecordID uint, groupBy []models.LocationField, within models.LocationHierarchy) ([]models.LocationGroupSummary, error) {
+	var groups []struct {
+		LocationZone  string
+		LocationAisle string
+		LocationBay   string
+		LocationLevel string
+		Unparsed      bool
+		Result        models.ScanComparisonOutcome
+		Scanned       bool
+		Occupied      bool
+		Count         int64
+	}
+
+	if len(groupBy) == 0 {
+		return nil, fmt.Errorf("locations must be grouped by at least one of=%v", models.LocationFields)
+	}
+
+	columns := make([]string, 0, len(groupBy))
+	for _, field := range groupBy {
+		if !field.IsValid() {
+			return nil, fmt.Errorf("locations can not be grouped by=%s", field)
+		}
+		columns = append(columns, hierarchyColumn(field))
+	}
+	columns = append(columns, unparsedLocation)
+	groupColumns := strings.Join(columns, ", ")
+
+	query := filterHierarchy(rr.DB.Model(&models.ComparisonData{}).Where("report_record_id = ?", reportRecordID), within)
+	result := query.
+		Select(groupColumns + " AS unparsed, result, scanned, occupied, COUNT(*) AS count").
+		Group(groupColumns + ", result, scanned, occupied").
+		Order(groupColumns).
+		Scan(&groups)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get location summary of comparison data, error: %w", result.Error)
+	}
+
+	// rows of a group are next to each other, they are ordered by the group columns
+	locationGroups := []models.LocationGroupSummary{}
+	for _, group := range groups {
+		hierarchy := models.LocationHierarchy{
+			Zone:  group.LocationZone,
+			Aisle: group.LocationAisle,
+			Bay:   group.LocationBay,
+			Level: group.LocationLevel,
+		}
+
+		last := len(locationGroups) - 1
+		if last < 0 || locationGroups[last].LocationHierarchy != hierarchy || locationGroups[last].Unparsed != group.Unparsed {
+			locationGroups = append(locationGroups, models.LocationGroupSummary{LocationHierarchy: hierarchy, Unparsed: group.Unparsed})
+		}
+		locationGroups[len(locationGroups)-1].Add(group.Result, group.Scanned, group.Occupied, group.Count)
+	}
+
+	return locationGroups, nil
+}
+
+// GetResults returns the outcome of each of the locations of a report, locations the report does not have are left
+// out.
+func (rr *ComparisonDataRepository) GetResults(reportRecordID uint, locations []string) (map[string]models.ScanComparisonOutcome, error) {
+	var rows []struct {
+		Location string
+		Result   models.ScanComparisonOutcome
+	}
+
+	result := rr.DB.Model(&models.ComparisonData{}).
+		Select("location, result").
+		Where("report_record_id = ? AND location IN ?", reportRecordID, locations).
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get results of comparison data, error: %w", result.Error)
+	}
+
+	results := make(map[string]models.ScanComparisonOutcome, len(rows))
+	for _, row := range rows {
+		results[row.Location] = row.Result
+	}
+
+	return results, nil
+}
+
+// GetReportDiff returns a page of the locations with a discrepancy in the baseline report, the report or both, with
+// their outcome in each report.
+func (rr *ComparisonDataRepository) GetReportDiff(baselineReportRecordID uint, reportRecordID uint, diffQuery models.ReportDiffQuery) ([]models.ReportDiffEntry, error) {
+	var entries []models.ReportDiffEntry
+
+	query := rr.DB.Table("(?) AS report_diff", rr.reportDiff(baselineReportRecordID, reportRecordID))
+	if len(diffQuery.Changes) > 0 {
+		query = query.Where("change IN ?", diffQuery.Changes)
+	}
+	if diffQuery.After != "" {
+		query = query.Where("location > ?", diffQuery.After)
+	}
+
+	result := query.Order("location").Limit(diffQuery.Limit).Scan(&entries)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get diff of reports, error: %w", result.Error)
+	}
+
+	return entries, nil
+}
+
+// CountReportDiff counts the locations of the diff of two reports by change.
+func (rr *ComparisonDataRepository) CountReportDiff(baselineReportRecordID uint, reportRecordID uint) (map[models.DiscrepancyChange]int64, error) {
+	var groups []struct {
+		Change models.DiscrepancyChange
+		Count  int64
+	}
+
+	result := rr.DB.Table("(?) AS report_diff", rr.reportDiff(baselineReportRecordID, reportRecordID)).
+		Select("change, COUNT(*) AS count").
+		Group("change").
+		Scan(&groups)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to count diff of reports, error: %w", result.Error)
+	}
+
+	counts := make(map[models.DiscrepancyChange]int64, len(models.DiscrepancyChanges))
+	for _, group := range groups {
+		counts[group.Change] = group.Count
+	}
+
+	return counts, nil
+}
+
+// reportDiff joins the locations of two reports, a location only one of the reports has is joined with nothing. The
+// change is worked out the same way as models.DiscrepancyChangeOf, a comparison with the missing outcome of a location
+// is never true.
+func (rr *ComparisonDataRepository) reportDiff(baselineReportRecordID uint, reportRecordID uint) *gorm.DB {
+	matched := matchedOutcomes()
+
+	return rr.DB.Raw(`SELECT COALESCE(later.location, baseline.location) AS location,
+			COALESCE(baseline.result, '') AS previous_result,
+			COALESCE(later.result, '') AS result,
+			CASE
+				WHEN baseline.result NOT IN ? AND later.result NOT IN ? THEN ?
+				WHEN baseline.result NOT IN ? THEN ?
+				ELSE ?
+			END AS change
+		FROM (SELECT location, result FROM comparison_data WHERE report_record_id = ?) AS baseline
+		FULL OUTER JOIN (SELECT location, result FROM comparison_data WHERE report_record_id = ?) AS later
+			ON later.location = baseline.location
+		WHERE baseline.result NOT IN ? OR later.result NOT IN ?`,
+		matched, matched, models.DiscrepancyPersistent,
+		matched, models.DiscrepancyResolved,
+		models.DiscrepancyNew,
+		baselineReportRecordID, reportRecordID,
+		matched, matched,
+	)
+}
+
+func (rr *ComparisonDataRepository) filterPage(reportRecordID uint, pageQuery models.ComparisonDataPageQuery) *gorm.DB {
+	query := filterComparisonData(rr.DB.Where("report_record_id = ?", reportRecordID), pageQuery.Filter)
+	if pageQuery.Barcode != "" {
+		query = query.Where("(? = ANY(actual_barcodes) OR ? = ANY(expected_barcodes))", pageQuery.Barcode, pageQuery.Barcode)
+	}
+
+	return filterHierarchy(query, pageQuery.Hierarchy)
+}
+
+func filterHierarchy(query *gorm.DB, hierarchy models.LocationHierarchy) *gorm.DB {
+	for _, field := range models.LocationFields {
+		if value := hierarchy.Get(field); value != "" {
+			query = query.Where(hierarchyColumn(field)+" = ?", value)
+		}
+	}
+
+	return query
+}
+
+// unparsedLocation is true for a location the grammar did not match. A location the grammar matched without any of
+// its optional groups has an empty hierarchy too, but is parsed. Comparison data stored before it was recorded whether
+// the grammar matched is unparsed when it has none of the fields of the hierarchy, as is comparison data stored before
+// locations were parsed.
+const unparsedLocation = "(NOT COALESCE(location_parsed, COALESCE(location_zone, '') || COALESCE(location_aisle, '') || COALESCE(location_bay, '') || COALESCE(location_level, '') <> ''))"
+
+func hierarchyColumn(field models.LocationField) string {
+	return "location_" + string(field)
+}
+
+func filterComparisonData(query *gorm.DB, filter models.ExportFilter) *gorm.DB {
+	if len(filter.Results) > 0 {
+		query = query.Where("result IN ?", []string(filter.Results))
+	}
+	if filter.DiscrepanciesOnly {
+		query = query.Where("result NOT IN ?", matchedOutcomes())
+	}
+	if filter.LocationPrefix != "" {
+		query = query.Where("location LIKE ?", escapeLike(filter.LocationPrefix)+"%")
+	}
+	if filter.Scanned != nil {
+		query = query.Where("scanned = ?", *filter.Scanned)
+	}
+	if filter.Occupied != nil {
+		query = query.Where("occupied = ?", *filter.Occupied)
+	}
+
+	return query
+}
+
+func sortColumn(sort models.ComparisonDataSort) (string, error) {
+	switch sort {
+	case "", models.SortByLocation:
+		return "location", nil
+	case models.SortByResult:
+		return "result", nil
+	case models.SortByScanned:
+		return "scanned", nil
+	case models.SortByOccupied:
+		return "occupied", nil
+	default:
+		return "", fmt.Errorf("comparison data can not be sorted by=%s", sort)
+	}
+}
+
+// cursorValue converts the value of a cursor to the type of the sort column.
+func cursorValue(sort models.ComparisonDataSort, value string) (interface{}, error) {
+	if sort == models.SortByScanned || sort == models.SortByOccupied {
+		return strconv.ParseBool(value)
+	}
+
+	return value, nil
+}
+
 func (cd *ComparisonDataRepository) Create(comparisonData *models.ComparisonData) error {
 	if comparisonData == nil {
 		return fmt.Errorf("comparison data cannot be nil")
@@ -39,3 +329,48 @@ func (cd *ComparisonDataRepository) Create(comparisonData *models.ComparisonData
 
 	return nil
 }
+
+// CreateAllInTransaction runs fn inside a single transaction, so the comparison data created through createAll is
+// either all committed or, when fn or the commit fails, all rolled back. Comparison data an earlier attempt committed
+// for the report record is deleted first, so a redelivered job does not write the report twice.
+func (cd *ComparisonDataRepository) CreateAllInTransaction(reportRecordID uint, fn func(createAll func(comparisonDataList []models.ComparisonData) error) error) error {
+	return cd.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("report_record_id = ?", reportRecordID).Delete(&models.ComparisonData{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete comparison data of report record id=%d, error: %w", reportRecordID, result.Error)
+		}
+
+		return fn(func(comparisonDataList []models.ComparisonData) error {
+			return createComparisonDataList(tx, comparisonDataList)
+		})
+	})
+}
+
+func createComparisonDataList(tx *gorm.DB, comparisonDataList []models.ComparisonData) error {
+	if comparisonDataList == nil {
+		return fmt.Errorf("comparison data list cannot be nil")
+	}
+
+	result := tx.Create(comparisonDataList)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create comparison data list, error: %w", result.Error)
+	}
+
+	return nil
+}
+
+func matchedOutcomes() []string {
+	var outcomes []string
+	for _, outcome := range models.AllOutcomes() {
+		if outcome.IsMatch() {
+			outcomes = append(outcomes, string(outcome))
+		}
+	}
+
+	return outcomes
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, so a prefix is matched literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -44,9 +44,12 @@ func (er *ExportReportRecordRepository) Get(exportReportRecordID uint) (*models.
 	return &exportReportRecord, nil
 }
 
-func (er *ExportReportRecordRepository) GetByReportType(reportRecordID uint, reportType string) (*models.ExportReportRecord, error) {
+// GetByFilter returns the export of the report with the report type and the filter key, or nil when the report was
+// not exported that way yet. Exports created before filters existed have the key of an empty filter.
+func (er *ExportReportRecordRepository) GetByFilter(reportRecordID uint, reportType string, filterKey string) (*models.ExportReportRecord, error) {
 	var exportReportRecord models.ExportReportRecord
-	result := er.DB.Where(&models.ExportReportRecord{ReportRecordID: reportRecordID, ReportType: models.ExportReportType(reportType)}).First(&exportReportRecord)
+	result := er.DB.Where("report_record_id = ? AND report_type = ? AND COALESCE(filter_key, '') = ?", reportRecordID, reportType, filterKey).
+		First(&exportReportRecord)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -59,18 +62,27 @@ func (er *ExportReportRecordRepository) GetByReportType(reportRecordID uint, rep
 	return &exportReportRecord, nil
 }
 
-func (er *ExportReportRecordRepository) Create(reportRecordID uint, filePath, reportType string) (*models.ExportReportRecord, error) {
+// Create saves the export report record together with the job that writes the export.
+func (er *ExportReportRecordRepository) Create(reportRecordID uint, filePath, reportType string, filter models.ExportFilter) (*models.ExportReportRecord, error) {
 	exportReportRecord := models.ExportReportRecord{
 		ReportType:     models.ExportReportType(reportType),
 		FileName:       filepath.Base(filePath),
 		FilePath:       filePath,
 		Status:         models.Pending,
 		ReportRecordID: reportRecordID,
+		Filter:         filter,
+		FilterKey:      filter.Key(),
 	}
 
-	result := er.DB.Create(&exportReportRecord)
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to create export report record, error: %w", result.Error)
+	err := er.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&exportReportRecord).Error; err != nil {
+			return err
+		}
+
+		return enqueueJob(tx, models.ExportReportJob, exportReportRecord.ID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export report record, error: %w", err)
 	}
 
 	return &exportReportRecord, nil
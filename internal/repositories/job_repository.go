@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/habbas99/dexory/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository struct {
+	DB *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{
+		DB: db,
+	}
+}
+
+// enqueueJob adds the job that processes a record. Record repositories call it inside the transaction that
+// creates the record, so a record is never left pending without a job to pick it up.
+func enqueueJob(tx *gorm.DB, jobType models.JobType, recordID uint) error {
+	job := models.Job{
+		Type:     jobType,
+		RecordID: recordID,
+		Status:   models.Pending,
+		RunAt:    time.Now(),
+	}
+
+	result := tx.Create(&job)
+	if result.Error != nil {
+		return fmt.Errorf("failed to enqueue job type=%s for record id=%d, error: %w", jobType, recordID, result.Error)
+	}
+
+	return nil
+}
+
+// Claim locks the next runnable job for the given worker. Pending jobs become runnable once their run_at
+// has passed, and processing jobs whose lease has expired are reclaimed so work held by a crashed replica
+// is picked up again. A job whose lease expired on its last attempt is failed instead, so a record that
+// crashes every replica working on it is not retried forever. SKIP LOCKED lets several replicas poll the
+// same table without blocking each other.
+func (jr *JobRepository) Claim(workerID string, leaseTimeout time.Duration, maxAttempts int) (*models.Job, error) {
+	var job models.Job
+	now := time.Now()
+	leaseExpiredAt := now.Add(-leaseTimeout)
+
+	err := jr.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Job{}).
+			Where("status = ? AND locked_at < ? AND attempts >= ?", models.Processing, leaseExpiredAt, maxAttempts).
+			Updates(map[string]interface{}{
+				"status":     models.Failed,
+				"locked_by":  "",
+				"locked_at":  nil,
+				"last_error": "lease expired on the last attempt",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		result = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_at < ? AND attempts < ?)", models.Pending, now, models.Processing, leaseExpiredAt, maxAttempts).
+			Order("run_at, id").
+			Take(&job)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		job.Status = models.Processing
+		job.Attempts++
+		job.LockedBy = workerID
+		job.LockedAt = &now
+
+		return tx.Save(&job).Error
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to claim job for worker=%s, error: %w", workerID, err)
+	}
+
+	return &job, nil
+}
+
+func (jr *JobRepository) Heartbeat(job *models.Job) error {
+	now := time.Now()
+	err := jr.updateLocked(job, map[string]interface{}{"locked_at": now})
+	if err != nil {
+		return err
+	}
+
+	job.LockedAt = &now
+	return nil
+}
+
+func (jr *JobRepository) Complete(job *models.Job) error {
+	err := jr.updateLocked(job, map[string]interface{}{
+		"status":     models.Completed,
+		"locked_by":  "",
+		"locked_at":  nil,
+		"last_error": "",
+	})
+	if err != nil {
+		return err
+	}
+
+	job.Status = models.Completed
+	return nil
+}
+
+func (jr *JobRepository) Retry(job *models.Job, runAt time.Time, lastError string) error {
+	err := jr.updateLocked(job, map[string]interface{}{
+		"status":     models.Pending,
+		"run_at":     runAt,
+		"locked_by":  "",
+		"locked_at":  nil,
+		"last_error": lastError,
+	})
+	if err != nil {
+		return err
+	}
+
+	job.Status = models.Pending
+	job.RunAt = runAt
+	job.LastError = lastError
+	return nil
+}
+
+func (jr *JobRepository) Fail(job *models.Job, lastError string) error {
+	err := jr.updateLocked(job, map[string]interface{}{
+		"status":     models.Failed,
+		"locked_by":  "",
+		"locked_at":  nil,
+		"last_error": lastError,
+	})
+	if err != nil {
+		return err
+	}
+
+	job.Status = models.Failed
+	job.LastError = lastError
+	return nil
+}
+
+// updateLocked only touches the job while this worker still holds its lease, so a worker that lost the
+// lease to another replica cannot overwrite the new owner's state.
+func (jr *JobRepository) updateLocked(job *models.Job, values map[string]interface{}) error {
+	result := jr.DB.Model(&models.Job{}).
+		Where("id = ? AND locked_by = ?", job.ID, job.LockedBy).
+		Updates(values)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update job with id=%d, error: %w", job.ID, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("job with id=%d is no longer locked by worker=%s", job.ID, job.LockedBy)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 
@@ -21,7 +22,7 @@ func NewReportRecordRepository(db *gorm.DB) *ReportRecordRepository {
 func (rr *ReportRecordRepository) GetAll() ([]models.ReportRecord, error) {
 	var reportRecords []models.ReportRecord
 
-	result := rr.DB.Preload("BulkScanRecord").Find(&reportRecords)
+	result := rr.DB.Preload("BulkScanRecord").Preload("BaselineBulkScanRecord").Preload("ColumnMappingProfile").Find(&reportRecords)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to get all report records, error: %w", result.Error)
 	}
@@ -29,25 +30,56 @@ func (rr *ReportRecordRepository) GetAll() ([]models.ReportRecord, error) {
 	return reportRecords, nil
 }
 
-func (rr *ReportRecordRepository) Create(bulkScanRecord models.BulkScanRecord, referenceFilePath string) (*models.ReportRecord, error) {
+// Create saves a comparison report record together with the job that generates its comparison data.
+func (rr *ReportRecordRepository) Create(bulkScanRecord models.BulkScanRecord, columnMappingProfile *models.ColumnMappingProfile, referenceFilePath, referenceSheet, referenceFileHash string) (*models.ReportRecord, error) {
 	reportRecord := models.ReportRecord{
-		BulkScanRecord:    bulkScanRecord,
-		ReferenceFileName: filepath.Base(referenceFilePath),
-		ReferenceFilePath: referenceFilePath,
-		Status:            models.Pending,
+		Mode:                 models.ComparisonReport,
+		BulkScanRecord:       bulkScanRecord,
+		ColumnMappingProfile: columnMappingProfile,
+		ReferenceFileName:    filepath.Base(referenceFilePath),
+		ReferenceFilePath:    referenceFilePath,
+		ReferenceSheet:       referenceSheet,
+		ReferenceFileHash:    referenceFileHash,
+		Status:               models.Pending,
 	}
 
-	result := rr.DB.Create(&reportRecord)
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to create report record, error: %w", result.Error)
+	if err := rr.createWithJob(&reportRecord); err != nil {
+		return nil, fmt.Errorf("failed to create report record, error: %w", err)
 	}
 
 	return &reportRecord, nil
 }
 
+// CreateScanDiff creates a report comparing the bulk scan record against the earlier baseline bulk scan record.
+func (rr *ReportRecordRepository) CreateScanDiff(baselineBulkScanRecord models.BulkScanRecord, bulkScanRecord models.BulkScanRecord) (*models.ReportRecord, error) {
+	reportRecord := models.ReportRecord{
+		Mode:                   models.ScanDiffReport,
+		BulkScanRecord:         bulkScanRecord,
+		BaselineBulkScanRecord: &baselineBulkScanRecord,
+		Status:                 models.Pending,
+	}
+
+	if err := rr.createWithJob(&reportRecord); err != nil {
+		return nil, fmt.Errorf("failed to create scan diff report record, error: %w", err)
+	}
+
+	return &reportRecord, nil
+}
+
+// createWithJob saves the report record and enqueues its job in one transaction.
+func (rr *ReportRecordRepository) createWithJob(reportRecord *models.ReportRecord) error {
+	return rr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(reportRecord).Error; err != nil {
+			return err
+		}
+
+		return enqueueJob(tx, models.ComparisonDataJob, reportRecord.ID)
+	})
+}
+
 func (rr *ReportRecordRepository) Get(reportRecordID uint) (*models.ReportRecord, error) {
 	var reportRecord models.ReportRecord
-	result := rr.DB.Preload("BulkScanRecord").First(&reportRecord, reportRecordID)
+	result := rr.DB.Preload("BulkScanRecord").Preload("BaselineBulkScanRecord").Preload("ColumnMappingProfile").First(&reportRecord, reportRecordID)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to fnd report record by id=%d, error: %w", reportRecordID, result.Error)
 	}
@@ -55,6 +87,52 @@ func (rr *ReportRecordRepository) Get(reportRecordID uint) (*models.ReportRecord
 	return &reportRecord, nil
 }
 
+// GetCompletedByReferenceFileHash returns the most recent completed report generated for the bulk scan record
+// from a reference file with the given hash read with the given column mapping profile and sheet, or nil when there
+// is none. A nil profile id matches reports read with the default column mapping.
+func (rr *ReportRecordRepository) GetCompletedByReferenceFileHash(bulkScanRecordID uint, columnMappingProfileID *uint, referenceSheet, referenceFileHash string) (*models.ReportRecord, error) {
+	var reportRecord models.ReportRecord
+	result := rr.DB.Preload("BulkScanRecord").Preload("BaselineBulkScanRecord").Preload("ColumnMappingProfile").Where(&models.ReportRecord{
+		BulkScanRecordID:  bulkScanRecordID,
+		ReferenceFileHash: referenceFileHash,
+		Status:            models.Completed,
+	}).Where("column_mapping_profile_id IS NOT DISTINCT FROM ?", columnMappingProfileID).
+		Where("COALESCE(reference_sheet, '') = ?", referenceSheet).
+		Order("id desc").First(&reportRecord)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to retrieve report record by reference file hash, error: %w", result.Error)
+	}
+
+	return &reportRecord, nil
+}
+
+// GetCompletedScanDiff returns the most recent completed scan diff of the bulk scan record against the baseline bulk
+// scan record, or nil when there is none.
+func (rr *ReportRecordRepository) GetCompletedScanDiff(baselineBulkScanRecordID uint, bulkScanRecordID uint) (*models.ReportRecord, error) {
+	var reportRecord models.ReportRecord
+	result := rr.DB.Preload("BulkScanRecord").Preload("BaselineBulkScanRecord").Where(&models.ReportRecord{
+		Mode:                     models.ScanDiffReport,
+		BulkScanRecordID:         bulkScanRecordID,
+		BaselineBulkScanRecordID: &baselineBulkScanRecordID,
+		Status:                   models.Completed,
+	}).Order("id desc").First(&reportRecord)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to retrieve scan diff report record, error: %w", result.Error)
+	}
+
+	return &reportRecord, nil
+}
+
 func (rr *ReportRecordRepository) Update(reportRecord *models.ReportRecord) error {
 	result := rr.DB.Save(reportRecord)
 	if result.Error != nil {
@@ -1,13 +1,21 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/habbas99/dexory/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/gorm"
-	"log"
+	"time"
 )
 
+var scanCopyColumns = []string{
+	"created_at", "updated_at", "location", "location_zone", "location_aisle", "location_bay", "location_level",
+	"location_parsed", "scanned", "occupied", "barcodes", "bulk_scan_record_id",
+}
+
 type ScanRepository struct {
 	DB *gorm.DB
 }
@@ -25,19 +33,92 @@ func NewScanRepository(db *gorm.DB) *ScanRepository {
 	}
 }
 
-func (s *ScanRepository) CreateAll(scans []models.Scan) error {
+// CopyAllInTransaction runs fn inside a single transaction, so the scans copied through copyAll are either all
+// committed or, when fn or the commit fails, all rolled back. Scans are inserted with the PostgreSQL COPY protocol,
+// which is considerably faster than a multi-row insert for the size of a nightly bulk scan. The ids of the inserted
+// scans are not populated. The transaction starts by deleting the scans an earlier attempt committed for the bulk scan
+// record, so a job redelivered before the record was marked completed does not ingest the file twice.
+func (s *ScanRepository) CopyAllInTransaction(bulkScanRecordID uint, fn func(copyAll func(scans []models.Scan) (int64, error)) error) error {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get sql.DB from GORM DB, error: %w", err)
+	}
+
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection, error: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("database connection of type=%T does not support copy", driverConn)
+		}
+
+		tx, err := pgxConn.Conn().Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin scans transaction, error: %w", err)
+		}
+		// rolling back a committed transaction is a no-op
+		defer tx.Rollback(ctx)
+
+		_, err = tx.Exec(ctx, "DELETE FROM scans WHERE bulk_scan_record_id = $1", bulkScanRecordID)
+		if err != nil {
+			return fmt.Errorf("failed to delete scans of bulk scan record id=%d, error: %w", bulkScanRecordID, err)
+		}
+
+		err = fn(func(scans []models.Scan) (int64, error) {
+			return copyScans(ctx, tx, scans)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to commit scans transaction, error: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func copyScans(ctx context.Context, tx pgx.Tx, scans []models.Scan) (int64, error) {
 	if scans == nil {
-		return fmt.Errorf("scans cannot be nil")
+		return 0, fmt.Errorf("scans cannot be nil")
 	}
 
-	result := s.DB.Create(scans)
+	now := time.Now()
+	copied, err := tx.CopyFrom(ctx, pgx.Identifier{"scans"}, scanCopyColumns, pgx.CopyFromSlice(len(scans), func(i int) ([]any, error) {
+		scan := scans[i]
+		return []any{
+			now, now, scan.Location, scan.Hierarchy.Zone, scan.Hierarchy.Aisle, scan.Hierarchy.Bay, scan.Hierarchy.Level,
+			scan.LocationParsed, scan.Scanned, scan.Occupied, []string(scan.Barcodes), scan.BulkScanRecordID,
+		}, nil
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy scans, error: %w", err)
+	}
+
+	return copied, nil
+}
+
+// GetBatch returns up to limit scans of the bulk scan record with an id greater than afterID, ordered by id so
+// callers can page through large bulk scans without the cost of an offset.
+func (s *ScanRepository) GetBatch(bulkScanRecordID uint, afterID uint, limit int) ([]models.Scan, error) {
+	var scans []models.Scan
+
+	result := s.DB.Where("bulk_scan_record_id = ? AND id > ?", bulkScanRecordID, afterID).Order("id").Limit(limit).Find(&scans)
 	if result.Error != nil {
-		return fmt.Errorf("failed to create scans, error: %w", result.Error)
+		return nil, fmt.Errorf("failed to get batch of scans, error: %w", result.Error)
 	}
 
-	return nil
+	return scans, nil
 }
 
+// Get returns the scan for the location, or nil when the robot data does not contain the location.
 func (s *ScanRepository) Get(bulkScanRecordID uint, location string) (*models.Scan, error) {
 	var scan models.Scan
 	result := s.DB.Where(&models.Scan{
@@ -47,8 +128,7 @@ func (s *ScanRepository) Get(bulkScanRecordID uint, location string) (*models.Sc
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			log.Println("scan record not found")
-			return nil, fmt.Errorf("scan not found for bulk scan record id=%d and location=%s", bulkScanRecordID, location)
+			return nil, nil
 		}
 
 		return nil, fmt.Errorf("failed to retrieve scan, error: %w", result.Error)
@@ -1,21 +1,23 @@
 package comparison
 
 import (
-	"encoding/csv"
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"os"
-	"strings"
 
 	"github.com/habbas99/dexory/internal"
 	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/services/location"
 )
 
 type ComparisonDataService struct {
 	scanClient           scanClient
 	comparisonDataClient comparisonDataClient
 	reportRecordClient   reportRecordClient
+	locationParser       locationParser
+	batchSize            int
 }
 
 type Record struct {
@@ -23,127 +25,298 @@ type Record struct {
 	Barcode  string
 }
 
+// ExpectedLocation holds every barcode the reference file expects in a location.
+type ExpectedLocation struct {
+	Location string
+	Barcodes []string
+}
+
 type scanClient interface {
-	Get(bulkScanRecordID uint, location string) (*models.Scan, error)
+	GetBatch(bulkScanRecordID uint, afterID uint, limit int) ([]models.Scan, error)
 }
 
 type comparisonDataClient interface {
-	Create(comparisonData *models.ComparisonData) error
+	CreateAllInTransaction(reportRecordID uint, fn func(createAll func(comparisonDataList []models.ComparisonData) error) error) error
 }
 
 type reportRecordClient interface {
+	Get(reportRecordID uint) (*models.ReportRecord, error)
 	Update(reportRecord *models.ReportRecord) error
 }
 
-func NewComparisonDataService(scanClient scanClient, comparisonDataClient comparisonDataClient, reportRecordClient reportRecordClient) *ComparisonDataService {
+type locationParser interface {
+	Parse(location string) (models.LocationHierarchy, bool)
+}
+
+func NewComparisonDataService(scanClient scanClient, comparisonDataClient comparisonDataClient, reportRecordClient reportRecordClient, locationParser locationParser, batchSize int) *ComparisonDataService {
 	return &ComparisonDataService{
 		scanClient:           scanClient,
 		comparisonDataClient: comparisonDataClient,
 		reportRecordClient:   reportRecordClient,
+		locationParser:       locationParser,
+		batchSize:            batchSize,
+	}
+}
+
+func (rg *ComparisonDataService) GenerateComparisonDataForReportRecord(reportRecordID uint) error {
+	reportRecord, err := rg.reportRecordClient.Get(reportRecordID)
+	if err != nil {
+		return fmt.Errorf("failed to get report record with id=%d, error: %w", reportRecordID, err)
+	}
+
+	// a job can be redelivered after a crash, never generate the same report twice
+	if reportRecord.Status == models.Completed {
+		return nil
+	}
+
+	if reportRecord.Mode == models.ScanDiffReport {
+		return rg.GenerateScanDiffForReport(reportRecord)
 	}
+
+	return rg.GenerateComparisonDataForReport(reportRecord)
 }
 
-func (rg *ComparisonDataService) GenerateComparisonDataForReport(reportRecord *models.ReportRecord) {
+func (rg *ComparisonDataService) GenerateComparisonDataForReport(reportRecord *models.ReportRecord) error {
 	log.WithFields(log.Fields{
 		"report_record_id":    reportRecord.ID,
 		"reference_file_name": reportRecord.ReferenceFileName,
 		"reference_file_path": reportRecord.ReferenceFilePath,
 	}).Info("starting process to create comparison data for report record")
 
+	// a retried record must not keep the reason of its previous failed attempt
+	reportRecord.Failure = models.Failure{}
 	rg.updateReportRecord(reportRecord, models.Processing)
 
 	file, err := os.Open(reportRecord.ReferenceFilePath)
 	if err != nil {
-		rg.updateReportRecordWithStatusFailed(reportRecord, fmt.Sprintf("failed opening reference file=%s", reportRecord.ReferenceFilePath), err)
-		return
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeFileUnreadable, fmt.Sprintf("failed opening reference file=%s", reportRecord.ReferenceFilePath), err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	headers, err := reader.Read()
+	reader, err := newReferenceFileReader(file, ReferenceFileOptions{
+		FileName:             reportRecord.ReferenceFileName,
+		Sheet:                reportRecord.ReferenceSheet,
+		ColumnMappingProfile: reportRecord.ColumnMappingProfile,
+	})
 	if err != nil {
-		rg.updateReportRecordWithStatusFailed(reportRecord, fmt.Sprintf("failed reading csv headers from reference file=%s", reportRecord.ReferenceFilePath), err)
-		return
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeFileUnreadable, fmt.Sprintf("failed reading reference file=%s", reportRecord.ReferenceFilePath), err)
 	}
+	defer reader.close()
 
-	if !strings.EqualFold(headers[0], "location") || !strings.EqualFold(headers[1], "item") {
-		rg.updateReportRecordWithStatusFailed(reportRecord, fmt.Sprintf("reference file=%s contains wrong headers=%s", reportRecord.ReferenceFilePath, headers), nil)
-		return
+	_, err = reader.readHeaders()
+	if err != nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeInvalidHeaders, fmt.Sprintf("failed reading headers from reference file=%s", reportRecord.ReferenceFilePath), err)
 	}
 
-	for {
-		row, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			rg.updateReportRecordWithStatusFailed(reportRecord, fmt.Sprintf("failed reading row from reference file=%s", reportRecord.ReferenceFilePath), err)
-			return
-		}
+	expectedLocations, err := rg.readExpectedLocations(reader)
+	if err != nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeMalformedFile, fmt.Sprintf("failed reading row from reference file=%s", reportRecord.ReferenceFilePath), err)
+	}
 
-		_, err = rg.createComparisonData(reportRecord.BulkScanRecord.ID, reportRecord.ID, row[0], row[1])
-		if err != nil {
-			rg.updateReportRecordWithStatusFailed(reportRecord, "failed to generate comparison data", err)
-			return
-		}
+	scans, scansByLocation, err := rg.loadScans(reportRecord.BulkScanRecord.ID)
+	if err != nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeDatabase, "failed to load scans for comparison", err)
+	}
+
+	locationParser, err := rg.locationParserFor(reportRecord)
+	if err != nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeInternal, "failed to compile location pattern of report", err)
+	}
+
+	// the comparison data of a report is written in a single transaction, a failed report never leaves partial data
+	err = rg.comparisonDataClient.CreateAllInTransaction(reportRecord.ID, func(createAll func(comparisonDataList []models.ComparisonData) error) error {
+		writer := newComparisonDataWriter(createAll, locationParser, rg.batchSize)
+		return rg.writeComparisonData(writer, reportRecord.ID, expectedLocations, scans, scansByLocation)
+	})
+	if err != nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeDatabase, "failed to create comparison data in database", err)
 	}
 
-	rg.updateReportRecord(reportRecord, models.Completed)
+	// a failed update leaves the record processing, the retried job replaces the comparison data written above
+	err = rg.updateReportRecord(reportRecord, models.Completed)
+	if err != nil {
+		return fmt.Errorf("failed to mark report record id=%d as completed, error: %w", reportRecord.ID, err)
+	}
 
 	log.WithFields(log.Fields{
 		"report_record_id":    reportRecord.ID,
 		"reference_file_name": reportRecord.ReferenceFileName,
 		"reference_file_path": reportRecord.ReferenceFilePath,
 	}).Info("finished process to create comparison data for report record")
+
+	return nil
 }
 
-func (rg *ComparisonDataService) createComparisonData(bulkScanRecordID, reportRecordID uint, location, barcode string) (*models.ComparisonData, error) {
-	scan, err := rg.scanClient.Get(bulkScanRecordID, location)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get scan with bulk scan record id=%d and location=%s, error: %w", bulkScanRecordID, location, err)
+// locationParserFor returns the grammar of the warehouse of the report, the location pattern of its column mapping
+// profile or else of its bulk scan. Reports of warehouses without a pattern of their own use the default grammar.
+func (rg *ComparisonDataService) locationParserFor(reportRecord *models.ReportRecord) (locationParser, error) {
+	pattern := reportRecord.LocationPattern()
+	if pattern == "" {
+		return rg.locationParser, nil
 	}
-	if scan == nil {
-		return nil, fmt.Errorf("scan not found with bulk scan record id=%d and location=%s", bulkScanRecordID, location)
+
+	return location.NewLocationGrammar(pattern)
+}
+
+func (rg *ComparisonDataService) writeComparisonData(writer *comparisonDataWriter, reportRecordID uint, expectedLocations []ExpectedLocation, scans []models.Scan, scansByLocation map[string]*models.Scan) error {
+	expectedLocationSet := map[string]struct{}{}
+	for _, expectedLocation := range expectedLocations {
+		comparisonData, err := rg.buildComparisonData(scansByLocation[expectedLocation.Location], reportRecordID, expectedLocation.Location, expectedLocation.Barcodes)
+		if err != nil {
+			return &internal.ProcessingError{
+				Code: models.ErrorCodeInternal,
+				Err:  fmt.Errorf("failed to generate comparison data for location=%s, error: %w", expectedLocation.Location, err),
+			}
+		}
+
+		err = writer.write(*comparisonData)
+		if err != nil {
+			return err
+		}
+		expectedLocationSet[expectedLocation.Location] = struct{}{}
 	}
 
-	outcome, err := rg.getComparisonResult(scan, barcode)
-	if err != nil {
-		return nil, fmt.Errorf("failed to calculate comparison outcome for location=%s", location)
+	// reconcile the robot data against the reference file, reporting every scanned location the customer did not list
+	for _, scan := range scans {
+		if _, ok := expectedLocationSet[scan.Location]; ok {
+			continue
+		}
+
+		err := writer.write(rg.buildUnexpectedComparisonData(scan, reportRecordID))
+		if err != nil {
+			return err
+		}
+
+		// the robot may report the same location more than once
+		expectedLocationSet[scan.Location] = struct{}{}
 	}
 
-	expectedBarcodes := []string{}
-	if barcode != "" {
-		expectedBarcodes = []string{barcode}
+	return writer.flush()
+}
+
+// readExpectedLocations groups the reference file rows by location, in the order each location first appears.
+// A location listed without an item is expected to be empty.
+func (rg *ComparisonDataService) readExpectedLocations(reader *referenceFileReader) ([]ExpectedLocation, error) {
+	expectedLocations := []ExpectedLocation{}
+	indexByLocation := map[string]int{}
+
+	for {
+		row, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := row.Record
+
+		index, ok := indexByLocation[record.Location]
+		if !ok {
+			index = len(expectedLocations)
+			indexByLocation[record.Location] = index
+			expectedLocations = append(expectedLocations, ExpectedLocation{Location: record.Location, Barcodes: []string{}})
+		}
+
+		if record.Barcode != "" && !containsBarcode(expectedLocations[index].Barcodes, record.Barcode) {
+			expectedLocations[index].Barcodes = append(expectedLocations[index].Barcodes, record.Barcode)
+		}
+	}
+
+	return expectedLocations, nil
+}
+
+// loadScans reads every scan of the bulk scan record in batches, so the comparison is a single in-memory join
+// instead of one scan lookup per reference file location. When the robot reported a location more than once the
+// first scan wins.
+func (rg *ComparisonDataService) loadScans(bulkScanRecordID uint) ([]models.Scan, map[string]*models.Scan, error) {
+	scans := []models.Scan{}
+	afterID := uint(0)
+	for {
+		batch, err := rg.scanClient.GetBatch(bulkScanRecordID, afterID, rg.batchSize)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get scans for bulk scan record id=%d, error: %w", bulkScanRecordID, err)
+		}
+
+		if len(batch) == 0 {
+			break
+		}
+
+		scans = append(scans, batch...)
+		afterID = batch[len(batch)-1].ID // move to the next batch
 	}
 
+	scansByLocation := make(map[string]*models.Scan, len(scans))
+	for i := range scans {
+		if _, ok := scansByLocation[scans[i].Location]; !ok {
+			scansByLocation[scans[i].Location] = &scans[i]
+		}
+	}
+
+	return scans, scansByLocation, nil
+}
+
+func (rg *ComparisonDataService) buildComparisonData(scan *models.Scan, reportRecordID uint, location string, expectedBarcodes []string) (*models.ComparisonData, error) {
 	comparisonData := models.ComparisonData{
 		Location:         location,
-		Scanned:          scan.Scanned,
-		Occupied:         scan.Occupied,
-		ActualBarcodes:   scan.Barcodes,
+		ActualBarcodes:   []string{},
 		ExpectedBarcodes: expectedBarcodes,
-		Result:           outcome,
 		ReportRecordID:   reportRecordID,
 	}
 
-	err = rg.comparisonDataClient.Create(&comparisonData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create comparison data for location=%s, error: %w ", location, err)
+	// a location the robot has no data for is reported instead of failing the whole report
+	if scan == nil {
+		comparisonData.Result = models.LocationMissingFromRobotData
+	} else {
+		outcome, err := rg.getComparisonResult(scan, expectedBarcodes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate comparison outcome for location=%s", location)
+		}
 
+		comparisonData.Scanned = scan.Scanned
+		comparisonData.Occupied = scan.Occupied
+		comparisonData.ActualBarcodes = scan.Barcodes
+		comparisonData.Result = outcome
 	}
 
+	comparisonData.MatchedBarcodes = intersectionOfBarcodes(comparisonData.ExpectedBarcodes, comparisonData.ActualBarcodes)
+	comparisonData.MissingBarcodes = differenceOfBarcodes(comparisonData.ExpectedBarcodes, comparisonData.ActualBarcodes)
+	comparisonData.UnexpectedBarcodes = differenceOfBarcodes(comparisonData.ActualBarcodes, comparisonData.ExpectedBarcodes)
+
 	return &comparisonData, nil
 }
 
-func (rg *ComparisonDataService) getComparisonResult(scan *models.Scan, barcode string) (models.ScanComparisonOutcome, error) {
+func (rg *ComparisonDataService) buildUnexpectedComparisonData(scan models.Scan, reportRecordID uint) models.ComparisonData {
+	return models.ComparisonData{
+		Location:           scan.Location,
+		Scanned:            scan.Scanned,
+		Occupied:           scan.Occupied,
+		ActualBarcodes:     scan.Barcodes,
+		ExpectedBarcodes:   []string{},
+		MatchedBarcodes:    []string{},
+		MissingBarcodes:    []string{},
+		UnexpectedBarcodes: differenceOfBarcodes(scan.Barcodes, []string{}),
+		Result:             models.LocationNotInExpectedInventory,
+		ReportRecordID:     reportRecordID,
+	}
+}
+
+func (rg *ComparisonDataService) getComparisonResult(scan *models.Scan, expectedBarcodes []string) (models.ScanComparisonOutcome, error) {
+	/*
+	 occupancy and barcodes are meaningless when the robot could not scan the location
+	*/
+	if !scan.Scanned {
+		return models.LocationNotScanned, nil
+	}
+
 	/*
 	 compare scanned data when empty
 	*/
-	if !scan.Occupied && barcode == "" {
+	if !scan.Occupied && len(expectedBarcodes) == 0 {
 		return models.LocationEmptyAsExpected, nil
 	}
 
-	if !scan.Occupied && barcode != "" {
+	if !scan.Occupied && len(expectedBarcodes) > 0 {
 		return models.LocationEmptyButNotExpected, nil
 	}
 
@@ -154,31 +327,94 @@ func (rg *ComparisonDataService) getComparisonResult(scan *models.Scan, barcode
 		return models.LocationOccupiedButBarcodeNotIdentified, nil
 	}
 
-	if scan.Occupied && barcode == "" {
+	if scan.Occupied && len(expectedBarcodes) == 0 {
 		return models.LocationOccupiedButExpectedEmpty, nil
 	}
 
-	if len(scan.Barcodes) > 1 { // seems like a special case
-		return models.LocationOccupiedWithWrongItems, nil
-	}
+	/*
+	 compare expected and actual barcodes as sets
+	*/
+	matchedBarcodes := intersectionOfBarcodes(expectedBarcodes, scan.Barcodes)
+	missingBarcodes := differenceOfBarcodes(expectedBarcodes, scan.Barcodes)
+	unexpectedBarcodes := differenceOfBarcodes(scan.Barcodes, expectedBarcodes)
 
-	if len(scan.Barcodes) == 1 && scan.Barcodes[0] == barcode {
+	// exact match
+	if len(missingBarcodes) == 0 && len(unexpectedBarcodes) == 0 {
 		return models.LocationOccupiedWithCorrectItems, nil
 	}
 
-	if len(scan.Barcodes) == 1 && scan.Barcodes[0] != barcode {
+	// actual barcodes are a superset of the expected barcodes
+	if len(missingBarcodes) == 0 {
+		return models.LocationOccupiedWithUnexpectedItems, nil
+	}
+
+	// actual barcodes are a subset of the expected barcodes
+	if len(unexpectedBarcodes) == 0 {
+		return models.LocationMissingExpectedItems, nil
+	}
+
+	// partial overlap
+	if len(matchedBarcodes) > 0 {
+		return models.LocationOccupiedWithSomeExpectedItems, nil
+	}
+
+	// disjoint
+	if len(matchedBarcodes) == 0 {
 		return models.LocationOccupiedWithWrongItems, nil
 	}
 
 	return "", internal.ErrComparisonCaseNotSupported
 }
 
-func (rg *ComparisonDataService) updateReportRecordWithStatusFailed(reportRecord *models.ReportRecord, message string, err error) {
+func (rg *ComparisonDataService) updateReportRecordWithStatusFailed(reportRecord *models.ReportRecord, code models.ErrorCode, message string, err error) error {
 	log.Errorf("%s: %v", message, err)
+
+	failedErr := errors.New(message)
+	if err != nil {
+		failedErr = fmt.Errorf("%s, error: %w", message, err)
+	}
+
+	reportRecord.Failure = internal.NewFailure(code, failedErr)
 	rg.updateReportRecord(reportRecord, models.Failed)
+
+	return internal.FailureError(reportRecord.Failure, failedErr)
 }
 
-func (rg *ComparisonDataService) updateReportRecord(reportRecord *models.ReportRecord, status models.Status) {
+func (rg *ComparisonDataService) updateReportRecord(reportRecord *models.ReportRecord, status models.Status) error {
 	reportRecord.Status = status
-	rg.reportRecordClient.Update(reportRecord)
+	return rg.reportRecordClient.Update(reportRecord)
+}
+
+// intersectionOfBarcodes returns the distinct barcodes in barcodes that are also in otherBarcodes.
+func intersectionOfBarcodes(barcodes, otherBarcodes []string) []string {
+	intersection := []string{}
+	for _, barcode := range barcodes {
+		if containsBarcode(otherBarcodes, barcode) && !containsBarcode(intersection, barcode) {
+			intersection = append(intersection, barcode)
+		}
+	}
+
+	return intersection
+}
+
+// differenceOfBarcodes returns the distinct barcodes in barcodes that are not in otherBarcodes.
+func differenceOfBarcodes(barcodes, otherBarcodes []string) []string {
+	difference := []string{}
+	for _, barcode := range barcodes {
+		if !containsBarcode(otherBarcodes, barcode) && !containsBarcode(difference, barcode) {
+			difference = append(difference, barcode)
+		}
+	}
+
+	return difference
+}
+
+func containsBarcode(barcodes []string, barcode string) bool {
+	for _, b := range barcodes {
+		if b == barcode {
+			return true
+		}
+	}
+
+	return false
 }
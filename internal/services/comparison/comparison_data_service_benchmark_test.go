@@ -0,0 +1,220 @@
+package comparison
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/services/location"
+	log "github.com/sirupsen/logrus"
+)
+
+const benchmarkLocationCount = 2000
+
+const benchmarkBatchSize = 1000
+
+// roundTrips counts the queries the fake clients of the benchmarks receive.
+type roundTrips struct {
+	count int
+}
+
+type benchmarkScanClient struct {
+	roundTrips      *roundTrips
+	scans           []models.Scan
+	scansByLocation map[string]*models.Scan
+}
+
+func (c *benchmarkScanClient) Get(bulkScanRecordID uint, location string) (*models.Scan, error) {
+	c.roundTrips.count++
+	return c.scansByLocation[location], nil
+}
+
+func (c *benchmarkScanClient) GetBatch(bulkScanRecordID uint, afterID uint, limit int) ([]models.Scan, error) {
+	c.roundTrips.count++
+	var batch []models.Scan
+	for _, scan := range c.scans {
+		if scan.ID > afterID && len(batch) < limit {
+			batch = append(batch, scan)
+		}
+	}
+	return batch, nil
+}
+
+type benchmarkComparisonDataClient struct {
+	roundTrips *roundTrips
+}
+
+func (c *benchmarkComparisonDataClient) Create(comparisonData *models.ComparisonData) error {
+	c.roundTrips.count++
+	return nil
+}
+
+// CreateAllInTransaction counts the begin, the delete of an earlier attempt and the commit of the transaction, and
+// one round trip per batch insert.
+func (c *benchmarkComparisonDataClient) CreateAllInTransaction(_ uint, fn func(createAll func(comparisonDataList []models.ComparisonData) error) error) error {
+	c.roundTrips.count += 3
+	return fn(func(comparisonDataList []models.ComparisonData) error {
+		c.roundTrips.count++
+		return nil
+	})
+}
+
+type benchmarkReportRecordClient struct {
+	roundTrips *roundTrips
+}
+
+func (c *benchmarkReportRecordClient) Get(reportRecordID uint) (*models.ReportRecord, error) {
+	c.roundTrips.count++
+	return nil, nil
+}
+
+func (c *benchmarkReportRecordClient) Update(reportRecord *models.ReportRecord) error {
+	c.roundTrips.count++
+	return nil
+}
+
+// perLocationScanClient and perLocationComparisonDataClient look up the scan and insert the comparison data of a
+// single location, the way comparison data was generated before scans were loaded and inserted in batches.
+type perLocationScanClient interface {
+	Get(bulkScanRecordID uint, location string) (*models.Scan, error)
+}
+
+type perLocationComparisonDataClient interface {
+	Create(comparisonData *models.ComparisonData) error
+}
+
+// BenchmarkGenerateComparisonDataPerLocation generates a report for a reference file of 2000 locations by looking up
+// the scan and inserting the comparison data of every location on its own, two round trips per location.
+func BenchmarkGenerateComparisonDataPerLocation(b *testing.B) {
+	trips := &roundTrips{}
+	scanClient, reportRecord := setupComparisonDataBenchmark(b, trips)
+	service := newComparisonDataBenchmarkService(b, scanClient, trips)
+	comparisonDataClient := &benchmarkComparisonDataClient{roundTrips: trips}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := generateComparisonDataPerLocation(service, scanClient, comparisonDataClient, reportRecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(trips.count)/float64(b.N), "round-trips/op")
+}
+
+// BenchmarkGenerateComparisonData generates a report for the same reference file with the scans loaded and the
+// comparison data inserted in batches of 1000: 3 scan batches, the transaction with its 2 batch inserts and the
+// processing and completed status updates.
+func BenchmarkGenerateComparisonData(b *testing.B) {
+	trips := &roundTrips{}
+	scanClient, reportRecord := setupComparisonDataBenchmark(b, trips)
+	service := newComparisonDataBenchmarkService(b, scanClient, trips)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := service.GenerateComparisonDataForReport(reportRecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(trips.count)/float64(b.N), "round-trips/op")
+}
+
+// generateComparisonDataPerLocation is the baseline the batched generation is benchmarked against. It compares every
+// expected location with the same rules, but looks up its scan and inserts its comparison data one location at a
+// time. Scanned locations missing from the reference file are left out, they cost the same in both approaches.
+func generateComparisonDataPerLocation(service *ComparisonDataService, scanClient perLocationScanClient, comparisonDataClient perLocationComparisonDataClient, reportRecord *models.ReportRecord) error {
+	file, err := os.Open(reportRecord.ReferenceFilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := newReferenceFileReader(file, ReferenceFileOptions{FileName: reportRecord.ReferenceFileName})
+	if err != nil {
+		return err
+	}
+	defer reader.close()
+
+	if _, err := reader.readHeaders(); err != nil {
+		return err
+	}
+
+	expectedLocations, err := service.readExpectedLocations(reader)
+	if err != nil {
+		return err
+	}
+
+	for _, expectedLocation := range expectedLocations {
+		scan, err := scanClient.Get(reportRecord.BulkScanRecord.ID, expectedLocation.Location)
+		if err != nil {
+			return err
+		}
+
+		comparisonData, err := service.buildComparisonData(scan, reportRecord.ID, expectedLocation.Location, expectedLocation.Barcodes)
+		if err != nil {
+			return err
+		}
+		hierarchy, parsed := service.locationParser.Parse(comparisonData.Location)
+		comparisonData.Hierarchy = hierarchy
+		comparisonData.LocationParsed = &parsed
+
+		if err := comparisonDataClient.Create(comparisonData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newComparisonDataBenchmarkService(b *testing.B, scanClient scanClient, trips *roundTrips) *ComparisonDataService {
+	locationGrammar, err := location.NewLocationGrammar(location.DefaultLocationPattern)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return NewComparisonDataService(scanClient, &benchmarkComparisonDataClient{roundTrips: trips}, &benchmarkReportRecordClient{roundTrips: trips}, locationGrammar, benchmarkBatchSize)
+}
+
+// setupComparisonDataBenchmark writes a reference file of benchmarkLocationCount locations, each expected to hold the
+// barcode its scan detected, and returns the scans with the report record of the reference file.
+func setupComparisonDataBenchmark(b *testing.B, trips *roundTrips) (*benchmarkScanClient, *models.ReportRecord) {
+	log.SetLevel(log.WarnLevel)
+	b.Cleanup(func() { log.SetLevel(log.InfoLevel) })
+
+	scanClient := &benchmarkScanClient{roundTrips: trips, scansByLocation: map[string]*models.Scan{}}
+	lines := []string{"Location,Item"}
+	for i := 0; i < benchmarkLocationCount; i++ {
+		location := fmt.Sprintf("ZA-%03d-%02d", i/100, i%100)
+		barcode := fmt.Sprintf("DX%08d", i)
+		lines = append(lines, fmt.Sprintf("%s,%s", location, barcode))
+
+		scan := models.Scan{Location: location, Scanned: true, Occupied: true, Barcodes: []string{barcode}, BulkScanRecordID: uint(1)}
+		scan.ID = uint(i + 1)
+		scanClient.scans = append(scanClient.scans, scan)
+	}
+	for i := range scanClient.scans {
+		scanClient.scansByLocation[scanClient.scans[i].Location] = &scanClient.scans[i]
+	}
+
+	file, err := os.CreateTemp("", "benchmark_reference_*.csv")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+	b.Cleanup(func() { os.Remove(file.Name()) })
+
+	if _, err := file.WriteString(strings.Join(lines, "\n")); err != nil {
+		b.Fatal(err)
+	}
+
+	bulkScanRecord := models.BulkScanRecord{}
+	bulkScanRecord.ID = uint(1)
+	reportRecord := &models.ReportRecord{BulkScanRecord: bulkScanRecord, ReferenceFileName: "benchmark_reference.csv", ReferenceFilePath: file.Name()}
+	reportRecord.ID = uint(2)
+
+	return scanClient, reportRecord
+}
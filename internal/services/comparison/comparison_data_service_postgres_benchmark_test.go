@@ -0,0 +1,120 @@
+//go:build postgres
+
+package comparison
+
+import (
+	"os"
+	"testing"
+
+	"github.com/habbas99/dexory/internal/db"
+	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/repositories"
+	"github.com/habbas99/dexory/internal/services/location"
+)
+
+// BenchmarkPostgresGenerateComparisonDataPerLocation generates the report of setupComparisonDataBenchmark against the
+// postgres database of .env, looking up the scan and inserting the comparison data of every location on its own.
+func BenchmarkPostgresGenerateComparisonDataPerLocation(b *testing.B) {
+	database, reportRecord := setupPostgresComparisonDataBenchmark(b)
+	scanRepository := repositories.NewScanRepository(database.DB)
+	comparisonDataRepository := repositories.NewComparisonDataRepository(database.DB)
+	service := newPostgresComparisonDataBenchmarkService(b, database)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := generateComparisonDataPerLocation(service, scanRepository, comparisonDataRepository, reportRecord); err != nil {
+			b.Fatal(err)
+		}
+
+		// the comparison data of every iteration is inserted again, the batched generation replaces it instead
+		b.StopTimer()
+		deleteComparisonData(b, database, reportRecord.ID)
+		b.StartTimer()
+	}
+}
+
+// BenchmarkPostgresGenerateComparisonData generates the same report against the postgres database of .env with the
+// scans loaded and the comparison data inserted in batches.
+func BenchmarkPostgresGenerateComparisonData(b *testing.B) {
+	database, reportRecord := setupPostgresComparisonDataBenchmark(b)
+	service := newPostgresComparisonDataBenchmarkService(b, database)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := service.GenerateComparisonDataForReport(reportRecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func newPostgresComparisonDataBenchmarkService(b *testing.B, database *db.Database) *ComparisonDataService {
+	locationGrammar, err := location.NewLocationGrammar(location.DefaultLocationPattern)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return NewComparisonDataService(
+		repositories.NewScanRepository(database.DB),
+		repositories.NewComparisonDataRepository(database.DB),
+		repositories.NewReportRecordRepository(database.DB),
+		locationGrammar,
+		benchmarkBatchSize,
+	)
+}
+
+// setupPostgresComparisonDataBenchmark copies the scans of setupComparisonDataBenchmark into a new bulk scan of the
+// database and creates the report record of its reference file, both are deleted when the benchmark ends.
+func setupPostgresComparisonDataBenchmark(b *testing.B) (*db.Database, *models.ReportRecord) {
+	scanClient, reportRecord := setupComparisonDataBenchmark(b, &roundTrips{})
+
+	database, err := db.NewDatabase(os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := database.Migrate(); err != nil {
+		b.Fatal(err)
+	}
+
+	bulkScanRecord := models.BulkScanRecord{FileName: "benchmark_scans.json", Status: models.Completed}
+	if err := database.DB.Create(&bulkScanRecord).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	scans := make([]models.Scan, 0, len(scanClient.scans))
+	for _, scan := range scanClient.scans {
+		scan.ID = 0
+		scan.BulkScanRecordID = bulkScanRecord.ID
+		scans = append(scans, scan)
+	}
+	err = repositories.NewScanRepository(database.DB).CopyAllInTransaction(bulkScanRecord.ID, func(copyAll func(scans []models.Scan) (int64, error)) error {
+		_, err := copyAll(scans)
+		return err
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	reportRecord.ID = 0
+	reportRecord.BulkScanRecordID = bulkScanRecord.ID
+	reportRecord.BulkScanRecord = bulkScanRecord
+	reportRecord.Status = models.Pending
+	if err := database.DB.Create(reportRecord).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	b.Cleanup(func() {
+		deleteComparisonData(b, database, reportRecord.ID)
+		database.DB.Unscoped().Delete(&models.ReportRecord{}, reportRecord.ID)
+		database.DB.Unscoped().Where("bulk_scan_record_id = ?", bulkScanRecord.ID).Delete(&models.Scan{})
+		database.DB.Unscoped().Delete(&models.BulkScanRecord{}, bulkScanRecord.ID)
+		database.Close()
+	})
+
+	return database, reportRecord
+}
+
+func deleteComparisonData(b *testing.B, database *db.Database, reportRecordID uint) {
+	if err := database.DB.Unscoped().Where("report_record_id = ?", reportRecordID).Delete(&models.ComparisonData{}).Error; err != nil {
+		b.Fatal(err)
+	}
+}
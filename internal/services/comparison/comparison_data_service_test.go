@@ -4,18 +4,24 @@ import (
 	"fmt"
 	mockcomparisondataservice "github.com/habbas99/dexory/generated/services/report"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/habbas99/dexory/internal"
 	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/services/location"
 	"github.com/stretchr/testify/suite"
+	"github.com/xuri/excelize/v2"
 )
 
 type ComparisonDataServiceTestSuite struct {
 	suite.Suite
-	MockScanClient           *mockcomparisondataservice.MockScanClient
-	MockComparisonDataClient *mockcomparisondataservice.MockComparisonDataClient
-	MockReportRecordClient   *mockcomparisondataservice.MockReportRecordClient
+	MockScanClient           *mockcomparisondataservice.MockscanClient
+	MockComparisonDataClient *mockcomparisondataservice.MockcomparisonDataClient
+	MockReportRecordClient   *mockcomparisondataservice.MockreportRecordClient
+	LocationGrammar          *location.LocationGrammar
 	ComparisonDataService    *ComparisonDataService
 	ctrl                     *gomock.Controller
 }
@@ -27,11 +33,15 @@ func TestComparisonDataServiceTestSuite(t *testing.T) {
 func (suite *ComparisonDataServiceTestSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 
-	suite.MockScanClient = mockcomparisondataservice.NewMockScanClient(suite.ctrl)
-	suite.MockComparisonDataClient = mockcomparisondataservice.NewMockComparisonDataClient(suite.ctrl)
-	suite.MockReportRecordClient = mockcomparisondataservice.NewMockReportRecordClient(suite.ctrl)
+	suite.MockScanClient = mockcomparisondataservice.NewMockscanClient(suite.ctrl)
+	suite.MockComparisonDataClient = mockcomparisondataservice.NewMockcomparisonDataClient(suite.ctrl)
+	suite.MockReportRecordClient = mockcomparisondataservice.NewMockreportRecordClient(suite.ctrl)
 
-	suite.ComparisonDataService = NewComparisonDataService(suite.MockScanClient, suite.MockComparisonDataClient, suite.MockReportRecordClient)
+	var err error
+	suite.LocationGrammar, err = location.NewLocationGrammar(location.DefaultLocationPattern)
+	suite.Require().NoError(err)
+
+	suite.ComparisonDataService = NewComparisonDataService(suite.MockScanClient, suite.MockComparisonDataClient, suite.MockReportRecordClient, suite.LocationGrammar, 50)
 }
 
 func (suite *ComparisonDataServiceTestSuite) TearDownTest() {
@@ -47,36 +57,399 @@ func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReport
 	})
 	defer os.Remove(mockFile.Name())
 
-	bulkScanRecord := models.BulkScanRecord{}
-	bulkScanRecord.ID = uint(1)
+	reportRecord := suite.createReportRecord(mockFile.Name())
 
-	reportRecord := &models.ReportRecord{
-		BulkScanRecord:    bulkScanRecord,
-		ReferenceFilePath: mockFile.Name(),
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"Barcode1"}),
+		suite.createScan(uint(11), "Location2", true, []string{"Barcode2"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(11), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal("completed", string(reportRecord.Status))
+	suite.Require().Len(createdComparisonData, 2)
+	suite.Equal("Location1", createdComparisonData[0].Location)
+	suite.Equal(models.LocationOccupiedWithCorrectItems, createdComparisonData[0].Result)
+	suite.Equal("Location2", createdComparisonData[1].Location)
+	suite.Equal(models.LocationOccupiedWithCorrectItems, createdComparisonData[1].Result)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataReturnsErrorWhenCompletedStatusIsNotSaved() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"Location1,Barcode1",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(1)
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(fmt.Errorf("connection reset")).Times(1)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"Barcode1"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(10), 50).Return([]models.Scan{}, nil)
+
+	// the comparison data of an earlier attempt is replaced inside the transaction of the retry
+	suite.MockComparisonDataClient.EXPECT().CreateAllInTransaction(uint(2), gomock.Any()).DoAndReturn(
+		func(_ uint, fn func(createAll func(comparisonDataList []models.ComparisonData) error) error) error {
+			return fn(func(comparisonDataList []models.ComparisonData) error {
+				return nil
+			})
+		},
+	).Times(1)
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "failed to mark report record id=2 as completed")
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataParsesLocationHierarchy() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"ZA001A,Barcode1",
+		"ZB014C,Barcode2",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "ZA001A", true, []string{"Barcode1"}),
+		suite.createScan(uint(11), "DOCK-1", true, []string{"Barcode3"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(11), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Require().Len(createdComparisonData, 3)
+	suite.Equal(models.LocationHierarchy{Zone: "Z", Aisle: "A", Bay: "001", Level: "A"}, createdComparisonData[0].Hierarchy)
+	suite.Equal(models.LocationHierarchy{Zone: "Z", Aisle: "B", Bay: "014", Level: "C"}, createdComparisonData[1].Hierarchy)
+	suite.Equal(models.LocationHierarchy{}, createdComparisonData[2].Hierarchy)
+}
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataParsesLocationHierarchyWithLocationPatternOfWarehouse() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"Z-01-02,Barcode1",
+		"ZA001A,Barcode2",
+	})
+	defer os.Remove(mockFile.Name())
+
+	// the profile of the warehouse takes precedence over the pattern the bulk scan was uploaded with
+	reportRecord := suite.createReportRecord(mockFile.Name())
+	reportRecord.BulkScanRecord.LocationPattern = `^(?P<zone>[A-Z])(?P<aisle>[A-Z])`
+	reportRecord.ColumnMappingProfile = &models.ColumnMappingProfile{
+		LocationColumn:  "Location",
+		BarcodeColumn:   "Item",
+		Delimiter:       ",",
+		Encoding:        models.UTF8Encoding,
+		LocationPattern: `^(?P<zone>[A-Z]+)-(?P<aisle>[0-9]+)-(?P<level>[0-9]+)$`,
 	}
 
 	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
 
-	suite.MockScanClient.EXPECT().Get(uint(1), "Location1").Return(&models.Scan{
-		Scanned:  true,
-		Occupied: true,
-		Barcodes: []string{"Barcode1"},
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Z-01-02", true, []string{"Barcode1"}),
 	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(10), 50).Return([]models.Scan{}, nil)
 
-	suite.MockScanClient.EXPECT().Get(uint(1), "Location2").Return(&models.Scan{
-		Scanned:  true,
-		Occupied: true,
-		Barcodes: []string{"Barcode2"},
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Require().Len(createdComparisonData, 2)
+	suite.Equal(models.LocationHierarchy{Zone: "Z", Aisle: "01", Level: "02"}, createdComparisonData[0].Hierarchy)
+	suite.Equal(models.LocationHierarchy{}, createdComparisonData[1].Hierarchy)
+	suite.False(*createdComparisonData[1].LocationParsed)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataParsesLocationMatchingPatternWithoutOptionalGroups() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"COLD-12-BIN1,Barcode1",
+		"BIN2,Barcode2",
+		"DOCK-1,Barcode3",
+	})
+	defer os.Remove(mockFile.Name())
+
+	// bins outside of the aisles of a zone have none of the groups of the pattern, they are parsed all the same
+	reportRecord := suite.createReportRecord(mockFile.Name())
+	reportRecord.BulkScanRecord.LocationPattern = `^(?:(?P<zone>[A-Z]+)-)?(?:(?P<aisle>[0-9]+)-)?BIN[0-9]+$`
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Require().Len(createdComparisonData, 3)
+	suite.Equal(models.LocationHierarchy{Zone: "COLD", Aisle: "12"}, createdComparisonData[0].Hierarchy)
+	suite.True(*createdComparisonData[0].LocationParsed)
+	suite.Equal(models.LocationHierarchy{}, createdComparisonData[1].Hierarchy)
+	suite.True(*createdComparisonData[1].LocationParsed)
+	suite.Equal(models.LocationHierarchy{}, createdComparisonData[2].Hierarchy)
+	suite.False(*createdComparisonData[2].LocationParsed)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportWritesInBatches() {
+	// Given
+	service := NewComparisonDataService(suite.MockScanClient, suite.MockComparisonDataClient, suite.MockReportRecordClient, suite.LocationGrammar, 2)
+
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"Location1,Barcode1",
+		"Location2,Barcode2",
+		"Location3,Barcode3",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 2).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"Barcode1"}),
+		suite.createScan(uint(11), "Location2", true, []string{"Barcode2"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(11), 2).Return([]models.Scan{
+		suite.createScan(uint(12), "Location3", true, []string{"Barcode3"}),
 	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(12), 2).Return([]models.Scan{}, nil)
 
-	suite.MockComparisonDataClient.EXPECT().Create(gomock.Any()).Return(nil).Times(2)
+	var batchSizes []int
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		batchSizes = append(batchSizes, len(comparisonDataList))
+		return nil
+	})
 
 	// When
-	suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+	err := service.GenerateComparisonDataForReport(reportRecord)
 
 	// Then
-	suite.NotNil(reportRecord)
-	suite.Equal("completed", string(reportRecord.Status))
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, reportRecord.Status)
+	suite.Equal([]int{2, 1}, batchSizes)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportWithUnexpectedLocations() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"Location1,Barcode1",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"Barcode1"}),
+		suite.createScan(uint(11), "Location2", true, []string{"Barcode2"}),
+		suite.createScan(uint(12), "Location2", true, []string{"Barcode2"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(12), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, reportRecord.Status)
+	suite.Require().Len(createdComparisonData, 2)
+	suite.Equal("Location2", createdComparisonData[1].Location)
+	suite.True(createdComparisonData[1].Occupied)
+	suite.EqualValues([]string{"Barcode2"}, createdComparisonData[1].ActualBarcodes)
+	suite.EqualValues([]string{}, createdComparisonData[1].ExpectedBarcodes)
+	suite.EqualValues([]string{"Barcode2"}, createdComparisonData[1].UnexpectedBarcodes)
+	suite.Equal(uint(2), createdComparisonData[1].ReportRecordID)
+	suite.Equal("The location was not in the expected inventory", string(createdComparisonData[1].Result))
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportWithMissingLocation() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"Location1,Barcode1",
+		"Location2,Barcode2",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"Barcode1"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(10), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, reportRecord.Status)
+	suite.Require().Len(createdComparisonData, 2)
+	suite.Equal(models.LocationMissingFromRobotData, createdComparisonData[1].Result)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportGroupsRowsByLocation() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"Location1,Barcode1",
+		"Location2,",
+		"Location1,Barcode2",
+		"Location1,Barcode1",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"Barcode1", "Barcode2"}),
+		suite.createScan(uint(11), "Location2", false, []string{}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(11), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, reportRecord.Status)
+	suite.Require().Len(createdComparisonData, 2)
+	suite.Equal("Location1", createdComparisonData[0].Location)
+	suite.EqualValues([]string{"Barcode1", "Barcode2"}, createdComparisonData[0].ExpectedBarcodes)
+	suite.Equal(models.LocationOccupiedWithCorrectItems, createdComparisonData[0].Result)
+	suite.Equal("Location2", createdComparisonData[1].Location)
+	suite.EqualValues([]string{}, createdComparisonData[1].ExpectedBarcodes)
+	suite.Equal(models.LocationEmptyAsExpected, createdComparisonData[1].Result)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportFailedToLoadScans() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"Location1,Barcode1",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return(nil, fmt.Errorf("database error"))
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Equal(models.Failed, reportRecord.Status)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportRollsBackWhenCreateFails() {
+	// Given
+	service := NewComparisonDataService(suite.MockScanClient, suite.MockComparisonDataClient, suite.MockReportRecordClient, suite.LocationGrammar, 1)
+
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"Location1,Barcode1",
+		"Location2,Barcode2",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 1).Return([]models.Scan{}, nil)
+
+	createdBatches := 0
+	transactionErr := suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdBatches++
+		if createdBatches == 2 {
+			return fmt.Errorf("database error")
+		}
+		return nil
+	})
+
+	// When
+	err := service.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Equal(models.Failed, reportRecord.Status)
+	suite.Equal(2, createdBatches)
+	// returning the error from the transaction is what makes the repository roll back the first batch
+	suite.Require().Error(*transactionErr)
 }
 
 func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportFileDoesNotExist() {
@@ -118,20 +491,284 @@ func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReport
 	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
 
 	// When
-	suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
 
 	// Then
+	var permanentErr *internal.PermanentError
+	suite.ErrorAs(err, &permanentErr)
 	suite.NotNil(reportRecord)
 	suite.Equal("failed", string(reportRecord.Status))
+	suite.Equal(models.ErrorCodeInvalidHeaders, reportRecord.ErrorCode)
+	suite.Equal(1, reportRecord.FailedRecordNumber)
+	suite.Contains(reportRecord.ErrorMessage, "contains wrong headers")
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportFileHasRowWithTooFewColumns() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"Location1,Barcode1",
+		"Location2",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Equal(models.Failed, reportRecord.Status)
+	suite.Equal(models.ErrorCodeMalformedFile, reportRecord.ErrorCode)
+	suite.Equal(3, reportRecord.FailedRecordNumber)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportWithColumnMappingProfile() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"\xEF\xBB\xBFQty; SKU ;Bin;LPN",
+		"1;Barcode1;Location1;LPN1",
+		"0;;Location2;LPN2",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+	reportRecord.ColumnMappingProfile = &models.ColumnMappingProfile{
+		Name:           "acme",
+		LocationColumn: "bin",
+		BarcodeColumn:  "sku",
+		Delimiter:      ";",
+		Encoding:       models.UTF8Encoding,
+	}
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"Barcode1"}),
+		suite.createScan(uint(11), "Location2", false, []string{}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(11), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, reportRecord.Status)
+	suite.Require().Len(createdComparisonData, 2)
+	suite.Equal("Location1", createdComparisonData[0].Location)
+	suite.Equal(models.LocationOccupiedWithCorrectItems, createdComparisonData[0].Result)
+	suite.Equal("Location2", createdComparisonData[1].Location)
+	suite.Equal(models.LocationEmptyAsExpected, createdComparisonData[1].Result)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportWithWindows1252Encoding() {
+	// Given
+	mockFile := suite.createMockCSVFile([]string{
+		"Location,Item",
+		"All\xE9e1,Barcode1",
+	})
+	defer os.Remove(mockFile.Name())
+
+	reportRecord := suite.createReportRecord(mockFile.Name())
+	reportRecord.ColumnMappingProfile = &models.ColumnMappingProfile{
+		Name:           "legacy",
+		LocationColumn: "Location",
+		BarcodeColumn:  "Item",
+		Delimiter:      ",",
+		Encoding:       models.Windows1252Encoding,
+	}
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Allée1", true, []string{"Barcode1"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(10), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Require().Len(createdComparisonData, 1)
+	suite.Equal("Allée1", createdComparisonData[0].Location)
+	suite.Equal(models.LocationOccupiedWithCorrectItems, createdComparisonData[0].Result)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestValidateReferenceFile() {
+	// Given
+	fileContent := strings.Join([]string{
+		"Location,Item",
+		"Location1,Barcode1",
+		"Location1,Barcode2",
+		"Location2",
+		"Location3,Bar code 3",
+		"Location4,",
+	}, "\n")
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"Barcode1", "Barcode2"}),
+		suite.createScan(uint(11), "Location3", true, []string{"Barcode3"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(11), 50).Return([]models.Scan{}, nil)
+	suite.MockReportRecordClient.EXPECT().Update(gomock.Any()).Times(0)
+
+	// When
+	validation, err := suite.ComparisonDataService.ValidateReferenceFile(uint(1), strings.NewReader(fileContent), ReferenceFileOptions{FileName: "reference.csv"})
+
+	// Then
+	suite.Require().NoError(err)
+	suite.False(validation.Valid)
+	suite.Equal([]string{"Location", "Item"}, validation.Headers)
+	suite.Empty(validation.HeaderError)
+	suite.Equal(5, validation.TotalRows)
+	suite.Equal([]int{4}, validation.RowsWithTooFewColumns)
+	suite.Equal([]DuplicateLocation{{Location: "Location1", LineNumbers: []int{2, 3}}}, validation.DuplicateLocations)
+	suite.Equal([]MalformedBarcode{{LineNumber: 5, Location: "Location3", Barcode: "Bar code 3"}}, validation.MalformedBarcodes)
+	suite.Equal([]string{"Location4"}, validation.UnknownLocations)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestValidateReferenceFileWithInvalidHeaders() {
+	// Given
+	suite.MockScanClient.EXPECT().GetBatch(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	// When
+	validation, err := suite.ComparisonDataService.ValidateReferenceFile(uint(1), strings.NewReader("Bin\nLocation1"), ReferenceFileOptions{FileName: "reference.csv"})
+
+	// Then
+	suite.Require().NoError(err)
+	suite.False(validation.Valid)
+	suite.Equal([]string{"Bin"}, validation.Headers)
+	suite.Contains(validation.HeaderError, "contains wrong headers")
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportFromXLSXFile() {
+	// Given
+	mockFile := suite.createMockXLSXFile("Inventory", [][]interface{}{
+		{"Bin", "Qty", "LPN"},
+		{"Location1", 1, 9850004338},
+		{},
+		{"Location2", 0, ""},
+	})
+	defer os.Remove(mockFile)
+
+	reportRecord := suite.createReportRecord(mockFile)
+	reportRecord.ReferenceFileName = filepath.Base(mockFile)
+	reportRecord.ReferenceSheet = "Inventory"
+	reportRecord.ColumnMappingProfile = &models.ColumnMappingProfile{
+		Name:           "acme",
+		LocationColumn: "Bin",
+		BarcodeColumn:  "LPN",
+	}
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"9850004338"}),
+		suite.createScan(uint(11), "Location2", false, []string{}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(11), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, reportRecord.Status)
+	suite.Require().Len(createdComparisonData, 2)
+	suite.Equal("Location1", createdComparisonData[0].Location)
+	suite.Equal(models.LocationOccupiedWithCorrectItems, createdComparisonData[0].Result)
+	suite.Equal("Location2", createdComparisonData[1].Location)
+	suite.Equal(models.LocationEmptyAsExpected, createdComparisonData[1].Result)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateComparisonDataForReportXLSXSheetNotFound() {
+	// Given
+	mockFile := suite.createMockXLSXFile("Inventory", [][]interface{}{
+		{"Location", "Item"},
+		{"Location1", "Barcode1"},
+	})
+	defer os.Remove(mockFile)
+
+	reportRecord := suite.createReportRecord(mockFile)
+	reportRecord.ReferenceFileName = filepath.Base(mockFile)
+	reportRecord.ReferenceSheet = "Stock"
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+	suite.MockScanClient.EXPECT().GetBatch(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReport(reportRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Equal(models.Failed, reportRecord.Status)
+	suite.Equal(models.ErrorCodeMalformedFile, reportRecord.ErrorCode)
+	suite.Contains(reportRecord.ErrorMessage, "sheet=Stock not found")
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestValidateReferenceFileFromXLSXFile() {
+	// Given
+	mockFile := suite.createMockXLSXFile("Inventory", [][]interface{}{
+		{"Location", "Item"},
+		{"Location1", "Barcode1"},
+		{},
+		{"Location1", "Barcode 2"},
+		{"Location2"},
+	})
+	defer os.Remove(mockFile)
+
+	file, err := os.Open(mockFile)
+	suite.Require().NoError(err)
+	defer file.Close()
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(10), "Location1", true, []string{"Barcode1"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(10), 50).Return([]models.Scan{}, nil)
+
+	// When
+	validation, err := suite.ComparisonDataService.ValidateReferenceFile(uint(1), file, ReferenceFileOptions{FileName: filepath.Base(mockFile), Sheet: "Inventory"})
+
+	// Then
+	suite.Require().NoError(err)
+	suite.True(validation.Valid)
+	suite.Equal([]string{"Location", "Item"}, validation.Headers)
+	suite.Equal(3, validation.TotalRows)
+	suite.Empty(validation.RowsWithTooFewColumns)
+	suite.Equal([]DuplicateLocation{{Location: "Location1", LineNumbers: []int{2, 4}}}, validation.DuplicateLocations)
+	suite.Equal([]MalformedBarcode{{LineNumber: 4, Location: "Location1", Barcode: "Barcode 2"}}, validation.MalformedBarcodes)
+	suite.Equal([]string{"Location2"}, validation.UnknownLocations)
 }
 
 func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedMatched() {
 	// Given
-	bulkScanRecordID := uint(1)
 	reportRecordID := uint(2)
 
 	location := "Location1"
-	barcode := "Barcode1"
+	expectedBarcodes := []string{"Barcode1"}
 
 	scan := &models.Scan{
 		Scanned:  true,
@@ -139,11 +776,8 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedMatched(
 		Barcodes: []string{"Barcode1"},
 	}
 
-	suite.MockScanClient.EXPECT().Get(bulkScanRecordID, location).Return(scan, nil)
-	suite.MockComparisonDataClient.EXPECT().Create(gomock.Any()).Return(nil)
-
 	// When
-	comparisonData, err := suite.ComparisonDataService.createComparisonData(bulkScanRecordID, reportRecordID, location, barcode)
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
 
 	// Then
 	suite.Require().NoError(err)
@@ -159,11 +793,10 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedMatched(
 
 func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedWithBarcodeNotIdentified() {
 	// Given
-	bulkScanRecordID := uint(1)
 	reportRecordID := uint(2)
 
 	location := "Location1"
-	barcode := "Barcode1"
+	expectedBarcodes := []string{"Barcode1"}
 
 	scan := &models.Scan{
 		Scanned:  true,
@@ -171,11 +804,8 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedWithBarc
 		Barcodes: []string{},
 	}
 
-	suite.MockScanClient.EXPECT().Get(bulkScanRecordID, location).Return(scan, nil)
-	suite.MockComparisonDataClient.EXPECT().Create(gomock.Any()).Return(nil)
-
 	// When
-	comparisonData, err := suite.ComparisonDataService.createComparisonData(bulkScanRecordID, reportRecordID, location, barcode)
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
 
 	// Then
 	suite.Require().NoError(err)
@@ -191,11 +821,10 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedWithBarc
 
 func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedMisMatch() {
 	// Given
-	bulkScanRecordID := uint(1)
 	reportRecordID := uint(2)
 
 	location := "Location1"
-	barcode := "Barcode2"
+	expectedBarcodes := []string{"Barcode2"}
 
 	scan := &models.Scan{
 		Scanned:  true,
@@ -203,11 +832,8 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedMisMatch
 		Barcodes: []string{"Barcode1"},
 	}
 
-	suite.MockScanClient.EXPECT().Get(bulkScanRecordID, location).Return(scan, nil)
-	suite.MockComparisonDataClient.EXPECT().Create(gomock.Any()).Return(nil)
-
 	// When
-	comparisonData, err := suite.ComparisonDataService.createComparisonData(bulkScanRecordID, reportRecordID, location, barcode)
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
 
 	// Then
 	suite.Require().NoError(err)
@@ -218,16 +844,43 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedMisMatch
 	suite.True(comparisonData.Occupied)
 	suite.EqualValues([]string{"Barcode1"}, comparisonData.ActualBarcodes)
 	suite.EqualValues([]string{"Barcode2"}, comparisonData.ExpectedBarcodes)
+	suite.EqualValues([]string{}, comparisonData.MatchedBarcodes)
+	suite.EqualValues([]string{"Barcode2"}, comparisonData.MissingBarcodes)
+	suite.EqualValues([]string{"Barcode1"}, comparisonData.UnexpectedBarcodes)
 	suite.Equal("The location was occupied by the wrong items", string(comparisonData.Result))
 }
 
+func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedWithPartialOverlap() {
+	// Given
+	reportRecordID := uint(2)
+
+	location := "Location1"
+	expectedBarcodes := []string{"Barcode1", "Barcode2"}
+
+	scan := &models.Scan{
+		Scanned:  true,
+		Occupied: true,
+		Barcodes: []string{"Barcode2", "Barcode3"},
+	}
+
+	// When
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.NotNil(comparisonData)
+	suite.EqualValues([]string{"Barcode2"}, comparisonData.MatchedBarcodes)
+	suite.EqualValues([]string{"Barcode1"}, comparisonData.MissingBarcodes)
+	suite.EqualValues([]string{"Barcode3"}, comparisonData.UnexpectedBarcodes)
+	suite.Equal("The location was occupied by some of the expected items, along with unexpected items", string(comparisonData.Result))
+}
+
 func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedByMultipleItems() {
 	// Given
-	bulkScanRecordID := uint(1)
 	reportRecordID := uint(2)
 
 	location := "Location1"
-	barcode := "Barcode2"
+	expectedBarcodes := []string{"Barcode2"}
 
 	scan := &models.Scan{
 		Scanned:  true,
@@ -235,11 +888,8 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedByMultip
 		Barcodes: []string{"Barcode1", "Barcode2"},
 	}
 
-	suite.MockScanClient.EXPECT().Get(bulkScanRecordID, location).Return(scan, nil)
-	suite.MockComparisonDataClient.EXPECT().Create(gomock.Any()).Return(nil)
-
 	// When
-	comparisonData, err := suite.ComparisonDataService.createComparisonData(bulkScanRecordID, reportRecordID, location, barcode)
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
 
 	// Then
 	suite.Require().NoError(err)
@@ -250,16 +900,67 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedByMultip
 	suite.True(comparisonData.Occupied)
 	suite.EqualValues([]string{"Barcode1", "Barcode2"}, comparisonData.ActualBarcodes)
 	suite.EqualValues([]string{"Barcode2"}, comparisonData.ExpectedBarcodes)
-	suite.Equal("The location was occupied by the wrong items", string(comparisonData.Result))
+	suite.EqualValues([]string{"Barcode2"}, comparisonData.MatchedBarcodes)
+	suite.EqualValues([]string{}, comparisonData.MissingBarcodes)
+	suite.EqualValues([]string{"Barcode1"}, comparisonData.UnexpectedBarcodes)
+	suite.Equal("The location was occupied by the expected items, along with unexpected items", string(comparisonData.Result))
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedMissingExpectedItems() {
+	// Given
+	reportRecordID := uint(2)
+
+	location := "Location1"
+	expectedBarcodes := []string{"Barcode1", "Barcode2"}
+
+	scan := &models.Scan{
+		Scanned:  true,
+		Occupied: true,
+		Barcodes: []string{"Barcode2"},
+	}
+
+	// When
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.NotNil(comparisonData)
+	suite.EqualValues([]string{"Barcode2"}, comparisonData.ActualBarcodes)
+	suite.EqualValues([]string{"Barcode1", "Barcode2"}, comparisonData.ExpectedBarcodes)
+	suite.EqualValues([]string{"Barcode2"}, comparisonData.MatchedBarcodes)
+	suite.EqualValues([]string{"Barcode1"}, comparisonData.MissingBarcodes)
+	suite.EqualValues([]string{}, comparisonData.UnexpectedBarcodes)
+	suite.Equal("The location was occupied, but some expected items were missing", string(comparisonData.Result))
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedWithAllExpectedItemsInAnyOrder() {
+	// Given
+	reportRecordID := uint(2)
+
+	location := "Location1"
+	expectedBarcodes := []string{"Barcode1", "Barcode2"}
+
+	scan := &models.Scan{
+		Scanned:  true,
+		Occupied: true,
+		Barcodes: []string{"Barcode2", "Barcode1"},
+	}
+
+	// When
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.NotNil(comparisonData)
+	suite.Equal("The location was occupied by the expected items", string(comparisonData.Result))
 }
 
 func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedButExpectedEmpty() {
 	// Given
-	bulkScanRecordID := uint(1)
 	reportRecordID := uint(2)
 
 	location := "Location1"
-	barcode := ""
+	expectedBarcodes := []string{}
 
 	scan := &models.Scan{
 		Scanned:  true,
@@ -267,11 +968,8 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedButExpec
 		Barcodes: []string{"Barcode1"},
 	}
 
-	suite.MockScanClient.EXPECT().Get(bulkScanRecordID, location).Return(scan, nil)
-	suite.MockComparisonDataClient.EXPECT().Create(gomock.Any()).Return(nil)
-
 	// When
-	comparisonData, err := suite.ComparisonDataService.createComparisonData(bulkScanRecordID, reportRecordID, location, barcode)
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
 
 	// Then
 	suite.Require().NoError(err)
@@ -287,11 +985,10 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationOccupiedButExpec
 
 func (suite *ComparisonDataServiceTestSuite) TestScannedLocationEmptyMatch() {
 	// Given
-	bulkScanRecordID := uint(1)
 	reportRecordID := uint(2)
 
 	location := "Location1"
-	barcode := ""
+	expectedBarcodes := []string{}
 
 	scan := &models.Scan{
 		Scanned:  true,
@@ -299,11 +996,8 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationEmptyMatch() {
 		Barcodes: []string{},
 	}
 
-	suite.MockScanClient.EXPECT().Get(bulkScanRecordID, location).Return(scan, nil)
-	suite.MockComparisonDataClient.EXPECT().Create(gomock.Any()).Return(nil)
-
 	// When
-	comparisonData, err := suite.ComparisonDataService.createComparisonData(bulkScanRecordID, reportRecordID, location, barcode)
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
 
 	// Then
 	suite.Require().NoError(err)
@@ -319,11 +1013,10 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationEmptyMatch() {
 
 func (suite *ComparisonDataServiceTestSuite) TestScannedLocationEmptyButNotExpected() {
 	// Given
-	bulkScanRecordID := uint(1)
 	reportRecordID := uint(2)
 
 	location := "Location1"
-	barcode := "Barcode1"
+	expectedBarcodes := []string{"Barcode1"}
 
 	scan := &models.Scan{
 		Scanned:  true,
@@ -331,11 +1024,8 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationEmptyButNotExpec
 		Barcodes: []string{},
 	}
 
-	suite.MockScanClient.EXPECT().Get(bulkScanRecordID, location).Return(scan, nil)
-	suite.MockComparisonDataClient.EXPECT().Create(gomock.Any()).Return(nil)
-
 	// When
-	comparisonData, err := suite.ComparisonDataService.createComparisonData(bulkScanRecordID, reportRecordID, location, barcode)
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
 
 	// Then
 	suite.Require().NoError(err)
@@ -349,42 +1039,49 @@ func (suite *ComparisonDataServiceTestSuite) TestScannedLocationEmptyButNotExpec
 	suite.Equal("The location was empty, but it should have been occupied", string(comparisonData.Result))
 }
 
-func (suite *ComparisonDataServiceTestSuite) TestFailToGetScan() {
+func (suite *ComparisonDataServiceTestSuite) TestNoScanFound() {
 	// Given
-	bulkScanRecordID := uint(1)
 	reportRecordID := uint(2)
 
 	location := "Location1"
-	barcode := "Barcode1"
-
-	suite.MockScanClient.EXPECT().Get(bulkScanRecordID, location).Return(nil, fmt.Errorf("error message"))
+	expectedBarcodes := []string{"Barcode1"}
 
 	// When
-	comparisonData, err := suite.ComparisonDataService.createComparisonData(bulkScanRecordID, reportRecordID, location, barcode)
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(nil, reportRecordID, location, expectedBarcodes)
 
 	// Then
-	suite.Require().Error(err)
-	suite.Nil(comparisonData)
-	suite.Equal("failed to get scan with bulk scan record id=1 and location=Location1, error: error message", err.Error())
+	suite.Require().NoError(err)
+	suite.NotNil(comparisonData)
+	suite.Equal("Location1", comparisonData.Location)
+	suite.False(comparisonData.Scanned)
+	suite.False(comparisonData.Occupied)
+	suite.EqualValues([]string{}, comparisonData.ActualBarcodes)
+	suite.EqualValues([]string{"Barcode1"}, comparisonData.ExpectedBarcodes)
+	suite.Equal("The location was missing from the robot data", string(comparisonData.Result))
 }
 
-func (suite *ComparisonDataServiceTestSuite) TestNoScanFound() {
+func (suite *ComparisonDataServiceTestSuite) TestLocationNotScanned() {
 	// Given
-	bulkScanRecordID := uint(1)
 	reportRecordID := uint(2)
 
 	location := "Location1"
-	barcode := "Barcode1"
+	expectedBarcodes := []string{"Barcode1"}
 
-	suite.MockScanClient.EXPECT().Get(bulkScanRecordID, location).Return(nil, nil)
+	scan := &models.Scan{
+		Scanned:  false,
+		Occupied: false,
+		Barcodes: []string{},
+	}
 
 	// When
-	comparisonData, err := suite.ComparisonDataService.createComparisonData(bulkScanRecordID, reportRecordID, location, barcode)
+	comparisonData, err := suite.ComparisonDataService.buildComparisonData(scan, reportRecordID, location, expectedBarcodes)
 
 	// Then
-	suite.Require().Error(err)
-	suite.Nil(comparisonData)
-	suite.Equal("scan not found with bulk scan record id=1 and location=Location1", err.Error())
+	suite.Require().NoError(err)
+	suite.NotNil(comparisonData)
+	suite.False(comparisonData.Scanned)
+	suite.EqualValues([]string{"Barcode1"}, comparisonData.ExpectedBarcodes)
+	suite.Equal("The location was not scanned", string(comparisonData.Result))
 }
 
 func (suite *ComparisonDataServiceTestSuite) createMockCSVFile(lines []string) *os.File {
@@ -401,3 +1098,71 @@ func (suite *ComparisonDataServiceTestSuite) createMockCSVFile(lines []string) *
 
 	return file
 }
+
+// createMockXLSXFile writes a workbook whose first worksheet is an unrelated summary sheet, so reading the named
+// sheet is not the default.
+func (suite *ComparisonDataServiceTestSuite) createMockXLSXFile(sheet string, rows [][]interface{}) string {
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	suite.Require().NoError(workbook.SetSheetName("Sheet1", "Summary"))
+	suite.Require().NoError(workbook.SetSheetRow("Summary", "A1", &[]interface{}{"Total", 2}))
+
+	_, err := workbook.NewSheet(sheet)
+	suite.Require().NoError(err)
+	for i, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		suite.Require().NoError(err)
+		suite.Require().NoError(workbook.SetSheetRow(sheet, cell, &row))
+	}
+
+	file, err := os.CreateTemp("", "test*.xlsx")
+	suite.Require().NoError(err)
+	suite.Require().NoError(file.Close())
+	suite.Require().NoError(workbook.SaveAs(file.Name()))
+
+	return file.Name()
+}
+
+func (suite *ComparisonDataServiceTestSuite) createReportRecord(referenceFilePath string) *models.ReportRecord {
+	bulkScanRecord := models.BulkScanRecord{}
+	bulkScanRecord.ID = uint(1)
+
+	reportRecord := &models.ReportRecord{
+		BulkScanRecord:    bulkScanRecord,
+		ReferenceFilePath: referenceFilePath,
+	}
+	reportRecord.ID = uint(2)
+
+	return reportRecord
+}
+
+func (suite *ComparisonDataServiceTestSuite) createScan(id uint, location string, occupied bool, barcodes []string) models.Scan {
+	scan := models.Scan{
+		Location:         location,
+		Scanned:          true,
+		Occupied:         occupied,
+		Barcodes:         barcodes,
+		BulkScanRecordID: uint(1),
+	}
+	scan.ID = id
+
+	return scan
+}
+
+// expectCreateAllInTransaction runs the transaction function against createAll and returns a pointer to the error it
+// returned, which the repository commits on nil and rolls back otherwise.
+func (suite *ComparisonDataServiceTestSuite) expectCreateAllInTransaction(createAll func(comparisonDataList []models.ComparisonData) error) *error {
+	var transactionErr error
+	suite.MockComparisonDataClient.EXPECT().CreateAllInTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ uint, fn func(createAll func(comparisonDataList []models.ComparisonData) error) error) error {
+			transactionErr = fn(createAll)
+			return transactionErr
+		},
+	).Times(1)
+
+	return &transactionErr
+}
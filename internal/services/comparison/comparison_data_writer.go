@@ -0,0 +1,52 @@
+package comparison
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// comparisonDataWriter buffers comparison data and writes it with one batched insert per batch. The location of the
+// comparison data is broken into its hierarchy with the grammar of the warehouse of the report.
+type comparisonDataWriter struct {
+	createAll      func(comparisonDataList []models.ComparisonData) error
+	locationParser locationParser
+	batchSize      int
+	batch          []models.ComparisonData
+}
+
+func newComparisonDataWriter(createAll func(comparisonDataList []models.ComparisonData) error, locationParser locationParser, batchSize int) *comparisonDataWriter {
+	return &comparisonDataWriter{
+		createAll:      createAll,
+		locationParser: locationParser,
+		batchSize:      batchSize,
+		batch:          make([]models.ComparisonData, 0, batchSize),
+	}
+}
+
+func (w *comparisonDataWriter) write(comparisonData models.ComparisonData) error {
+	hierarchy, parsed := w.locationParser.Parse(comparisonData.Location)
+	comparisonData.Hierarchy = hierarchy
+	comparisonData.LocationParsed = &parsed
+	w.batch = append(w.batch, comparisonData)
+	if len(w.batch) < w.batchSize {
+		return nil
+	}
+
+	return w.flush()
+}
+
+func (w *comparisonDataWriter) flush() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+
+	log.Printf("creating %d comparison data in database", len(w.batch))
+	err := w.createAll(w.batch)
+	if err != nil {
+		return err
+	}
+
+	w.batch = w.batch[:0] // reset batch
+	return nil
+}
@@ -0,0 +1,76 @@
+package comparison
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+
+	"github.com/habbas99/dexory/internal"
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// csvRowReader reads the rows of a CSV reference file with the delimiter and encoding of the profile.
+type csvRowReader struct {
+	reader *csv.Reader
+}
+
+func newCSVRowReader(file io.Reader, profile *models.ColumnMappingProfile) (*csvRowReader, error) {
+	decoder, err := referenceFileDecoder(profile.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(transform.NewReader(file, decoder))
+	// the column count is checked per row, so a short row is reported with its line instead of failing the read
+	reader.FieldsPerRecord = -1
+	if profile.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(profile.Delimiter)
+	}
+
+	return &csvRowReader{reader: reader}, nil
+}
+
+// referenceFileDecoder returns the decoder turning the file into UTF-8. A byte order mark at the start of the file
+// is dropped, spreadsheet tools add one to UTF-8 exports.
+func referenceFileDecoder(fileEncoding models.FileEncoding) (*encoding.Decoder, error) {
+	switch fileEncoding {
+	case "", models.UTF8Encoding:
+		return unicode.UTF8BOM.NewDecoder(), nil
+	case models.UTF16Encoding:
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder(), nil
+	case models.Windows1252Encoding:
+		return charmap.Windows1252.NewDecoder(), nil
+	case models.ISO88591Encoding:
+		return charmap.ISO8859_1.NewDecoder(), nil
+	default:
+		return nil, fmt.Errorf("reference file encoding=%s is not supported", fileEncoding)
+	}
+}
+
+func (r *csvRowReader) readRow() ([]string, int, error) {
+	row, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, 0, err
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, parseErr.Line, &internal.ProcessingError{Code: models.ErrorCodeMalformedFile, RecordNumber: parseErr.Line, Err: err}
+		}
+		return nil, 0, err
+	}
+
+	lineNumber, _ := r.reader.FieldPos(0)
+	return row, lineNumber, nil
+}
+
+func (r *csvRowReader) close() error {
+	return nil
+}
@@ -0,0 +1,137 @@
+package comparison
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/habbas99/dexory/internal"
+	"github.com/habbas99/dexory/internal/models"
+)
+
+var errTooFewColumns = errors.New("row is missing the location or barcode column")
+
+// defaultColumnMappingProfile is the reference file format used when a report does not name a profile.
+var defaultColumnMappingProfile = models.ColumnMappingProfile{
+	LocationColumn: "location",
+	BarcodeColumn:  "item",
+	Delimiter:      ",",
+	Encoding:       models.UTF8Encoding,
+}
+
+// ReferenceFileOptions describe how a reference file is read. The extension of FileName decides whether the file
+// is read as CSV or XLSX, Sheet selects the worksheet of an XLSX file and defaults to the first one. A nil
+// ColumnMappingProfile reads the file with the default column mapping.
+type ReferenceFileOptions struct {
+	FileName             string
+	Sheet                string
+	ColumnMappingProfile *models.ColumnMappingProfile
+}
+
+// IsXLSXFile reports whether the reference file is read as an XLSX workbook.
+func IsXLSXFile(fileName string) bool {
+	return strings.EqualFold(filepath.Ext(fileName), ".xlsx")
+}
+
+// referenceRow is a row of a reference file together with the line it was read from.
+type referenceRow struct {
+	Record
+	LineNumber int
+}
+
+// referenceRowReader reads the rows of a reference file in one file format.
+type referenceRowReader interface {
+	// readRow returns the cells of the next row and the line or row number it was read from, or io.EOF once the
+	// file is exhausted.
+	readRow() ([]string, int, error)
+	close() error
+}
+
+// referenceFileReader reads the customer reference file. Report generation and reference file validation both
+// read through it, so the two never disagree about a file. The header mapping is the same for every file format.
+type referenceFileReader struct {
+	rows                referenceRowReader
+	profile             models.ColumnMappingProfile
+	locationColumnIndex int
+	barcodeColumnIndex  int
+}
+
+func newReferenceFileReader(file io.Reader, options ReferenceFileOptions) (*referenceFileReader, error) {
+	profile := options.ColumnMappingProfile
+	if profile == nil {
+		profile = &defaultColumnMappingProfile
+	}
+
+	var rows referenceRowReader
+	var err error
+	if IsXLSXFile(options.FileName) {
+		rows, err = newXLSXRowReader(file, options.Sheet)
+	} else {
+		rows, err = newCSVRowReader(file, profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &referenceFileReader{rows: rows, profile: *profile}, nil
+}
+
+// readHeaders reads the header row and finds the location and barcode columns named by the profile. Header names
+// are matched ignoring case and surrounding spaces, other columns are ignored.
+func (r *referenceFileReader) readHeaders() ([]string, error) {
+	headers, lineNumber, err := r.rows.readRow()
+	if err != nil {
+		return nil, &internal.ProcessingError{Code: models.ErrorCodeMalformedFile, RecordNumber: 1, Err: err}
+	}
+
+	r.locationColumnIndex = headerIndex(headers, r.profile.LocationColumn)
+	r.barcodeColumnIndex = headerIndex(headers, r.profile.BarcodeColumn)
+	if r.locationColumnIndex < 0 || r.barcodeColumnIndex < 0 {
+		return headers, &internal.ProcessingError{
+			Code:         models.ErrorCodeInvalidHeaders,
+			RecordNumber: lineNumber,
+			Err: fmt.Errorf("reference file contains wrong headers=%s, expected headers=[%s %s]",
+				headers, r.profile.LocationColumn, r.profile.BarcodeColumn),
+		}
+	}
+
+	return headers, nil
+}
+
+// next reads the next row, returning io.EOF once the file is exhausted. A row with too few columns is returned as
+// a ProcessingError wrapping errTooFewColumns, reading can carry on with the following row.
+func (r *referenceFileReader) next() (referenceRow, error) {
+	row, lineNumber, err := r.rows.readRow()
+	if err != nil {
+		return referenceRow{}, err
+	}
+
+	if len(row) <= r.locationColumnIndex || len(row) <= r.barcodeColumnIndex {
+		return referenceRow{LineNumber: lineNumber}, &internal.ProcessingError{
+			Code:         models.ErrorCodeMalformedFile,
+			RecordNumber: lineNumber,
+			Err:          errTooFewColumns,
+		}
+	}
+
+	return referenceRow{
+		Record:     Record{Location: row[r.locationColumnIndex], Barcode: row[r.barcodeColumnIndex]},
+		LineNumber: lineNumber,
+	}, nil
+}
+
+func (r *referenceFileReader) close() error {
+	return r.rows.close()
+}
+
+func headerIndex(headers []string, name string) int {
+	for i, header := range headers {
+		if strings.EqualFold(strings.TrimSpace(header), strings.TrimSpace(name)) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,137 @@
+package comparison
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/habbas99/dexory/internal"
+)
+
+var barcodePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ReferenceFileValidation is the result of a dry run of a reference file against a bulk scan. Valid is false when
+// generating a report from the file would fail, the remaining findings are warnings that do not stop a report.
+type ReferenceFileValidation struct {
+	Valid                 bool                `json:"valid"`
+	Headers               []string            `json:"headers"`
+	HeaderError           string              `json:"headerError,omitempty"`
+	MalformedFileError    string              `json:"malformedFileError,omitempty"`
+	TotalRows             int                 `json:"totalRows"`
+	RowsWithTooFewColumns []int               `json:"rowsWithTooFewColumns"`
+	DuplicateLocations    []DuplicateLocation `json:"duplicateLocations"`
+	MalformedBarcodes     []MalformedBarcode  `json:"malformedBarcodes"`
+	UnknownLocations      []string            `json:"unknownLocations"`
+}
+
+// DuplicateLocation is a location listed on more than one row of the reference file.
+type DuplicateLocation struct {
+	Location    string `json:"location"`
+	LineNumbers []int  `json:"lineNumbers"`
+}
+
+// MalformedBarcode is a barcode that is not made of letters, digits, '.', '_' and '-'.
+type MalformedBarcode struct {
+	LineNumber int    `json:"lineNumber"`
+	Location   string `json:"location"`
+	Barcode    string `json:"barcode"`
+}
+
+// ValidateReferenceFile reads the reference file the same way report generation does, without writing anything,
+// and reports every problem found in it. Locations that are not in the robot data of the bulk scan are listed as
+// unknown.
+func (rg *ComparisonDataService) ValidateReferenceFile(bulkScanRecordID uint, file io.Reader, options ReferenceFileOptions) (*ReferenceFileValidation, error) {
+	validation := &ReferenceFileValidation{
+		Headers:               []string{},
+		RowsWithTooFewColumns: []int{},
+		DuplicateLocations:    []DuplicateLocation{},
+		MalformedBarcodes:     []MalformedBarcode{},
+		UnknownLocations:      []string{},
+	}
+
+	reader, err := newReferenceFileReader(file, options)
+	if err != nil {
+		var processingErr *internal.ProcessingError
+		if !errors.As(err, &processingErr) {
+			return nil, fmt.Errorf("failed to read reference file for validation, error: %w", err)
+		}
+		validation.MalformedFileError = err.Error()
+		return validation, nil
+	}
+	defer reader.close()
+
+	headers, err := reader.readHeaders()
+	if headers != nil {
+		validation.Headers = headers
+	}
+	if err != nil {
+		validation.HeaderError = err.Error()
+		return validation, nil
+	}
+
+	_, scansByLocation, err := rg.loadScans(bulkScanRecordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scans for reference file validation, error: %w", err)
+	}
+
+	lineNumbersByLocation := map[string][]int{}
+	locations := []string{}
+	for {
+		row, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, errTooFewColumns) {
+			validation.TotalRows++
+			validation.RowsWithTooFewColumns = append(validation.RowsWithTooFewColumns, row.LineNumber)
+			continue
+		}
+		if err != nil {
+			var processingErr *internal.ProcessingError
+			if !errors.As(err, &processingErr) {
+				return nil, fmt.Errorf("failed reading reference file, error: %w", err)
+			}
+			// the rest of the file cannot be read reliably after a parse error
+			validation.MalformedFileError = err.Error()
+			break
+		}
+		validation.TotalRows++
+
+		if _, ok := lineNumbersByLocation[row.Location]; !ok {
+			locations = append(locations, row.Location)
+		}
+		lineNumbersByLocation[row.Location] = append(lineNumbersByLocation[row.Location], row.LineNumber)
+
+		if row.Barcode != "" && !barcodePattern.MatchString(row.Barcode) {
+			validation.MalformedBarcodes = append(validation.MalformedBarcodes, MalformedBarcode{
+				LineNumber: row.LineNumber,
+				Location:   row.Location,
+				Barcode:    row.Barcode,
+			})
+		}
+	}
+
+	for _, location := range locations {
+		lineNumbers := lineNumbersByLocation[location]
+		if len(lineNumbers) > 1 {
+			validation.DuplicateLocations = append(validation.DuplicateLocations, DuplicateLocation{Location: location, LineNumbers: lineNumbers})
+		}
+
+		if _, ok := scansByLocation[location]; !ok {
+			validation.UnknownLocations = append(validation.UnknownLocations, location)
+		}
+	}
+
+	validation.Valid = validation.MalformedFileError == "" && len(validation.RowsWithTooFewColumns) == 0
+
+	log.WithFields(log.Fields{
+		"bulk_scan_record_id": bulkScanRecordID,
+		"total_rows":          validation.TotalRows,
+		"valid":               validation.Valid,
+	}).Info("validated reference file")
+
+	return validation, nil
+}
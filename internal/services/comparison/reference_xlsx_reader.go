@@ -0,0 +1,116 @@
+package comparison
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/habbas99/dexory/internal"
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// xlsxRowReader reads the rows of one worksheet of an XLSX reference file. Rows without any value are skipped the
+// same way the CSV reader skips empty lines, row numbers are the ones shown by spreadsheet tools.
+type xlsxRowReader struct {
+	file        *excelize.File
+	rows        *excelize.Rows
+	rowNumber   int
+	headerWidth int
+}
+
+func newXLSXRowReader(file io.Reader, sheet string) (*xlsxRowReader, error) {
+	workbook, err := excelize.OpenReader(file)
+	if err != nil {
+		return nil, &internal.ProcessingError{
+			Code: models.ErrorCodeMalformedFile,
+			Err:  fmt.Errorf("failed to open xlsx reference file, error: %w", err),
+		}
+	}
+
+	sheets := workbook.GetSheetList()
+	if sheet == "" && len(sheets) > 0 {
+		sheet = sheets[0]
+	}
+
+	if !containsSheet(sheets, sheet) {
+		workbook.Close()
+		return nil, &internal.ProcessingError{
+			Code: models.ErrorCodeMalformedFile,
+			Err:  fmt.Errorf("sheet=%s not found in xlsx reference file, sheets=%s", sheet, sheets),
+		}
+	}
+
+	rows, err := workbook.Rows(sheet)
+	if err != nil {
+		workbook.Close()
+		return nil, &internal.ProcessingError{
+			Code: models.ErrorCodeMalformedFile,
+			Err:  fmt.Errorf("failed to read sheet=%s of xlsx reference file, error: %w", sheet, err),
+		}
+	}
+
+	return &xlsxRowReader{file: workbook, rows: rows}, nil
+}
+
+func (r *xlsxRowReader) readRow() ([]string, int, error) {
+	for r.rows.Next() {
+		r.rowNumber++
+
+		// raw values keep barcodes stored as numbers from being reformatted by the cell number format
+		row, err := r.rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, r.rowNumber, &internal.ProcessingError{Code: models.ErrorCodeMalformedFile, RecordNumber: r.rowNumber, Err: err}
+		}
+
+		if isBlankRow(row) {
+			continue
+		}
+
+		// a worksheet does not store trailing empty cells, a row with an empty barcode is as wide as the header row
+		if r.headerWidth == 0 {
+			r.headerWidth = len(row)
+		}
+		for len(row) < r.headerWidth {
+			row = append(row, "")
+		}
+
+		return row, r.rowNumber, nil
+	}
+
+	if err := r.rows.Error(); err != nil {
+		return nil, r.rowNumber, &internal.ProcessingError{Code: models.ErrorCodeMalformedFile, RecordNumber: r.rowNumber, Err: err}
+	}
+
+	return nil, 0, io.EOF
+}
+
+func (r *xlsxRowReader) close() error {
+	if err := r.rows.Close(); err != nil {
+		r.file.Close()
+		return err
+	}
+
+	return r.file.Close()
+}
+
+func containsSheet(sheets []string, sheet string) bool {
+	for _, name := range sheets {
+		if name == sheet {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
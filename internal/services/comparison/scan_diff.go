@@ -0,0 +1,170 @@
+package comparison
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// GenerateScanDiffForReport compares the bulk scan of a scan diff report against its baseline bulk scan, location by
+// location. The barcodes of the baseline scan are stored as the expected barcodes and the barcodes of the later scan as
+// the actual barcodes, so missing barcodes were removed from the location and unexpected barcodes were added to it.
+func (rg *ComparisonDataService) GenerateScanDiffForReport(reportRecord *models.ReportRecord) error {
+	log.WithFields(log.Fields{
+		"report_record_id":             reportRecord.ID,
+		"bulk_scan_record_id":          reportRecord.BulkScanRecordID,
+		"baseline_bulk_scan_record_id": reportRecord.BaselineBulkScanRecordID,
+	}).Info("starting process to create scan diff for report record")
+
+	// a retried record must not keep the reason of its previous failed attempt
+	reportRecord.Failure = models.Failure{}
+	rg.updateReportRecord(reportRecord, models.Processing)
+
+	if reportRecord.BaselineBulkScanRecord == nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeInternal, "scan diff has no baseline bulk scan", nil)
+	}
+
+	baselineScans, baselineScansByLocation, err := rg.loadScans(reportRecord.BaselineBulkScanRecord.ID)
+	if err != nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeDatabase, "failed to load baseline scans for scan diff", err)
+	}
+
+	scans, scansByLocation, err := rg.loadScans(reportRecord.BulkScanRecord.ID)
+	if err != nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeDatabase, "failed to load scans for scan diff", err)
+	}
+
+	locationParser, err := rg.locationParserFor(reportRecord)
+	if err != nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeInternal, "failed to compile location pattern of report", err)
+	}
+
+	// the scan diff of a report is written in a single transaction, a failed report never leaves partial data
+	err = rg.comparisonDataClient.CreateAllInTransaction(reportRecord.ID, func(createAll func(comparisonDataList []models.ComparisonData) error) error {
+		writer := newComparisonDataWriter(createAll, locationParser, rg.batchSize)
+		return rg.writeScanDiff(writer, reportRecord.ID, baselineScans, baselineScansByLocation, scans, scansByLocation)
+	})
+	if err != nil {
+		return rg.updateReportRecordWithStatusFailed(reportRecord, models.ErrorCodeDatabase, "failed to create scan diff in database", err)
+	}
+
+	err = rg.updateReportRecord(reportRecord, models.Completed)
+	if err != nil {
+		return fmt.Errorf("failed to mark report record id=%d as completed, error: %w", reportRecord.ID, err)
+	}
+
+	log.WithFields(log.Fields{
+		"report_record_id":             reportRecord.ID,
+		"bulk_scan_record_id":          reportRecord.BulkScanRecordID,
+		"baseline_bulk_scan_record_id": reportRecord.BaselineBulkScanRecord.ID,
+	}).Info("finished process to create scan diff for report record")
+
+	return nil
+}
+
+// writeScanDiff writes the change of every location of the baseline scans, in their order, followed by the locations
+// only the later scans have.
+func (rg *ComparisonDataService) writeScanDiff(writer *comparisonDataWriter, reportRecordID uint, baselineScans []models.Scan, baselineScansByLocation map[string]*models.Scan, scans []models.Scan, scansByLocation map[string]*models.Scan) error {
+	written := map[string]struct{}{}
+	for _, scanList := range [][]models.Scan{baselineScans, scans} {
+		for _, scan := range scanList {
+			// the robot may report the same location more than once
+			if _, ok := written[scan.Location]; ok {
+				continue
+			}
+			written[scan.Location] = struct{}{}
+
+			comparisonData := rg.buildScanDiffData(baselineScansByLocation[scan.Location], scansByLocation[scan.Location], reportRecordID, scan.Location)
+			if err := writer.write(comparisonData); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.flush()
+}
+
+// buildScanDiffData compares the scans of a location, either scan is nil when its bulk scan does not have the location.
+func (rg *ComparisonDataService) buildScanDiffData(baselineScan *models.Scan, scan *models.Scan, reportRecordID uint, location string) models.ComparisonData {
+	comparisonData := models.ComparisonData{
+		Location:         location,
+		ActualBarcodes:   []string{},
+		ExpectedBarcodes: []string{},
+		Result:           getScanDiffResult(baselineScan, scan),
+		ReportRecordID:   reportRecordID,
+	}
+
+	if baselineScan != nil {
+		comparisonData.ExpectedBarcodes = baselineScan.Barcodes
+	}
+	if scan != nil {
+		comparisonData.Scanned = scan.Scanned
+		comparisonData.Occupied = scan.Occupied
+		comparisonData.ActualBarcodes = scan.Barcodes
+	}
+
+	comparisonData.MatchedBarcodes = intersectionOfBarcodes(comparisonData.ExpectedBarcodes, comparisonData.ActualBarcodes)
+	comparisonData.MissingBarcodes = differenceOfBarcodes(comparisonData.ExpectedBarcodes, comparisonData.ActualBarcodes)
+	comparisonData.UnexpectedBarcodes = differenceOfBarcodes(comparisonData.ActualBarcodes, comparisonData.ExpectedBarcodes)
+
+	return comparisonData
+}
+
+// getScanDiffResult returns the change of a location. A location missing from one of the bulk scans was added or
+// removed, whether or not the robot could read it in the other. A location is readable when the robot scanned it and,
+// when it was occupied, identified its barcodes. What changed is only known when the location was readable in both
+// scans:
+//
+//	| baseline scan     | later scan           | outcome                 |
+//	|-------------------|----------------------|-------------------------|
+//	| missing           | any                  | LocationAdded           |
+//	| any               | missing              | LocationRemoved         |
+//	| readable          | not readable         | LocationNewlyUnreadable |
+//	| not readable      | readable             | LocationNewlyReadable   |
+//	| not readable      | not readable         | LocationUnchanged       |
+//	| empty             | empty                | LocationUnchanged       |
+//	| empty             | occupied             | LocationNewlyOccupied   |
+//	| occupied          | empty                | LocationNewlyEmptied    |
+//	| occupied by items | occupied by the same | LocationUnchanged       |
+//	| occupied by items | occupied by others   | LocationBarcodeChanged  |
+func getScanDiffResult(baselineScan *models.Scan, scan *models.Scan) models.ScanComparisonOutcome {
+	if baselineScan == nil {
+		return models.LocationAdded
+	}
+	if scan == nil {
+		return models.LocationRemoved
+	}
+
+	baselineReadable := isReadable(baselineScan)
+	readable := isReadable(scan)
+
+	switch {
+	case baselineReadable && !readable:
+		return models.LocationNewlyUnreadable
+	case !baselineReadable && readable:
+		return models.LocationNewlyReadable
+	case !baselineReadable && !readable:
+		return models.LocationUnchanged
+	case !baselineScan.Occupied && !scan.Occupied:
+		return models.LocationUnchanged
+	case !baselineScan.Occupied:
+		return models.LocationNewlyOccupied
+	case !scan.Occupied:
+		return models.LocationNewlyEmptied
+	case sameBarcodes(baselineScan.Barcodes, scan.Barcodes):
+		return models.LocationUnchanged
+	default:
+		return models.LocationBarcodeChanged
+	}
+}
+
+func isReadable(scan *models.Scan) bool {
+	return scan.Scanned && (!scan.Occupied || len(scan.Barcodes) > 0)
+}
+
+// sameBarcodes compares the barcodes as sets, the robot does not report the barcodes of a location in a fixed order.
+func sameBarcodes(barcodes, otherBarcodes []string) bool {
+	return len(differenceOfBarcodes(barcodes, otherBarcodes)) == 0 && len(differenceOfBarcodes(otherBarcodes, barcodes)) == 0
+}
@@ -0,0 +1,160 @@
+package comparison
+
+import (
+	"errors"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateScanDiffForReportRecord() {
+	// Given
+	reportRecord := suite.createScanDiffReportRecord()
+
+	suite.MockReportRecordClient.EXPECT().Get(uint(3)).Return(reportRecord, nil)
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	unscanned := suite.createScan(uint(7), "ZA006A", false, []string{})
+	unscanned.Scanned = false
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(1), "ZA001A", true, []string{"Barcode1", "Barcode2"}),
+		suite.createScan(uint(2), "ZA002A", false, []string{}),
+		suite.createScan(uint(3), "ZA003A", true, []string{"Barcode3"}),
+		suite.createScan(uint(4), "ZA004A", true, []string{"Barcode4"}),
+		suite.createScan(uint(5), "ZA005A", true, []string{"Barcode5"}),
+		unscanned,
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(7), 50).Return([]models.Scan{}, nil)
+
+	suite.MockScanClient.EXPECT().GetBatch(uint(2), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(11), "ZA001A", true, []string{"Barcode2", "Barcode1"}),
+		suite.createScan(uint(12), "ZA002A", true, []string{"Barcode6"}),
+		suite.createScan(uint(13), "ZA003A", false, []string{}),
+		suite.createScan(uint(14), "ZA004A", true, []string{"Barcode7"}),
+		suite.createScan(uint(15), "ZA005A", true, []string{}),
+		suite.createScan(uint(16), "ZA006A", false, []string{}),
+		suite.createScan(uint(17), "ZA007A", true, []string{"Barcode8"}),
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(2), uint(17), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateComparisonDataForReportRecord(uint(3))
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, reportRecord.Status)
+
+	results := map[string]models.ScanComparisonOutcome{}
+	for _, comparisonData := range createdComparisonData {
+		results[comparisonData.Location] = comparisonData.Result
+	}
+	suite.Equal(map[string]models.ScanComparisonOutcome{
+		"ZA001A": models.LocationUnchanged,
+		"ZA002A": models.LocationNewlyOccupied,
+		"ZA003A": models.LocationNewlyEmptied,
+		"ZA004A": models.LocationBarcodeChanged,
+		"ZA005A": models.LocationNewlyUnreadable,
+		"ZA006A": models.LocationNewlyReadable,
+		"ZA007A": models.LocationAdded,
+	}, results)
+
+	barcodeChanged := createdComparisonData[3]
+	suite.Equal("ZA004A", barcodeChanged.Location)
+	suite.Equal([]string{"Barcode4"}, []string(barcodeChanged.ExpectedBarcodes))
+	suite.Equal([]string{"Barcode7"}, []string(barcodeChanged.ActualBarcodes))
+	suite.Equal([]string{"Barcode4"}, []string(barcodeChanged.MissingBarcodes))
+	suite.Equal([]string{"Barcode7"}, []string(barcodeChanged.UnexpectedBarcodes))
+	suite.Equal(models.LocationHierarchy{Zone: "Z", Aisle: "A", Bay: "004", Level: "A"}, barcodeChanged.Hierarchy)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateScanDiffReportsLocationsOfOneScanAsAddedOrRemoved() {
+	// Given
+	reportRecord := suite.createScanDiffReportRecord()
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+
+	// a location the robot could not read is still added or removed, not newly readable or unreadable
+	unscannedBaseline := suite.createScan(uint(2), "ZA002A", false, []string{})
+	unscannedBaseline.Scanned = false
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(1), "ZA001A", true, []string{"Barcode1"}),
+		unscannedBaseline,
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(2), 50).Return([]models.Scan{}, nil)
+
+	unscanned := suite.createScan(uint(12), "ZA004A", false, []string{})
+	unscanned.Scanned = false
+	suite.MockScanClient.EXPECT().GetBatch(uint(2), uint(0), 50).Return([]models.Scan{
+		suite.createScan(uint(11), "ZA003A", true, []string{"Barcode3"}),
+		unscanned,
+	}, nil)
+	suite.MockScanClient.EXPECT().GetBatch(uint(2), uint(12), 50).Return([]models.Scan{}, nil)
+
+	var createdComparisonData []models.ComparisonData
+	suite.expectCreateAllInTransaction(func(comparisonDataList []models.ComparisonData) error {
+		createdComparisonData = append(createdComparisonData, comparisonDataList...)
+		return nil
+	})
+
+	// When
+	err := suite.ComparisonDataService.GenerateScanDiffForReport(reportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+
+	results := map[string]models.ScanComparisonOutcome{}
+	for _, comparisonData := range createdComparisonData {
+		results[comparisonData.Location] = comparisonData.Result
+	}
+	suite.Equal(map[string]models.ScanComparisonOutcome{
+		"ZA001A": models.LocationRemoved,
+		"ZA002A": models.LocationRemoved,
+		"ZA003A": models.LocationAdded,
+		"ZA004A": models.LocationAdded,
+	}, results)
+
+	removed := createdComparisonData[0]
+	suite.Equal([]string{"Barcode1"}, []string(removed.ExpectedBarcodes))
+	suite.Equal([]string{"Barcode1"}, []string(removed.MissingBarcodes))
+	suite.Empty(removed.ActualBarcodes)
+}
+
+func (suite *ComparisonDataServiceTestSuite) TestGenerateScanDiffFailsWhenScansCanNotBeLoaded() {
+	// Given
+	reportRecord := suite.createScanDiffReportRecord()
+
+	suite.MockReportRecordClient.EXPECT().Update(reportRecord).Return(nil).Times(2)
+	suite.MockScanClient.EXPECT().GetBatch(uint(1), uint(0), 50).Return(nil, errors.New("connection refused"))
+
+	// When
+	err := suite.ComparisonDataService.GenerateScanDiffForReport(reportRecord)
+
+	// Then
+	suite.Error(err)
+	suite.Equal(models.Failed, reportRecord.Status)
+	suite.Equal(models.ErrorCodeDatabase, reportRecord.ErrorCode)
+}
+
+func (suite *ComparisonDataServiceTestSuite) createScanDiffReportRecord() *models.ReportRecord {
+	baselineBulkScanRecord := models.BulkScanRecord{}
+	baselineBulkScanRecord.ID = uint(1)
+	bulkScanRecord := models.BulkScanRecord{}
+	bulkScanRecord.ID = uint(2)
+
+	reportRecord := &models.ReportRecord{
+		Mode:                     models.ScanDiffReport,
+		BulkScanRecordID:         bulkScanRecord.ID,
+		BulkScanRecord:           bulkScanRecord,
+		BaselineBulkScanRecordID: &baselineBulkScanRecord.ID,
+		BaselineBulkScanRecord:   &baselineBulkScanRecord,
+		Status:                   models.Pending,
+	}
+	reportRecord.ID = uint(3)
+
+	return reportRecord
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// csvReportWriter writes the report as CSV with one row per location. Every barcode list is a single column with
+// the barcodes joined by the barcode separator.
+type csvReportWriter struct {
+	writer           *csv.Writer
+	columns          []models.ExportColumn
+	barcodeSeparator string
+}
+
+func newCSVReportWriter(file io.Writer, columns []models.ExportColumn, barcodeSeparator string) *csvReportWriter {
+	return &csvReportWriter{writer: csv.NewWriter(file), columns: columns, barcodeSeparator: barcodeSeparator}
+}
+
+func (w *csvReportWriter) writeStart() error {
+	headers := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		headers[i] = string(column)
+	}
+
+	return w.writer.Write(headers)
+}
+
+func (w *csvReportWriter) write(comparisonData models.ComparisonData) error {
+	row := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		row[i] = formatReportColumnValue(column, comparisonData, w.barcodeSeparator)
+	}
+
+	return w.writer.Write(row)
+}
+
+func (w *csvReportWriter) flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvReportWriter) writeEnd() error {
+	return w.flush()
+}
+
+func (w *csvReportWriter) close() error {
+	return nil
+}
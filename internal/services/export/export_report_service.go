@@ -1,160 +1,364 @@
 package export
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/habbas99/dexory/internal"
 	"github.com/habbas99/dexory/internal/models"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"os"
+	"strings"
+	"time"
 )
 
-type jsonExportedComparisonData struct {
-	Location         string   `json:"location"`
-	Scanned          bool     `json:"scanned"`
-	Occupied         bool     `json:"occupied"`
-	ActualBarcodes   []string `json:"actualBarcodes"`
-	ExpectedBarcodes []string `json:"expectedBarcodes"`
-	Result           string   `json:"result"`
-}
-
 type exportReportRecordClient interface {
+	Get(exportReportRecordID uint) (*models.ExportReportRecord, error)
 	Update(exportReportRecord *models.ExportReportRecord) error
 }
 
+type reportRecordClient interface {
+	Get(reportRecordID uint) (*models.ReportRecord, error)
+}
+
+// exportBatchSize is the number of locations read from the database and written to an export at once.
+const exportBatchSize = 1000
+
 type comparisonDataClient interface {
-	GetAllPaginated(reportRecordID uint, limit int, offset int) ([]models.ComparisonData, error)
+	GetPage(reportRecordID uint, pageQuery models.ComparisonDataPageQuery) ([]models.ComparisonData, error)
+	GetResults(reportRecordID uint, locations []string) (map[string]models.ScanComparisonOutcome, error)
+	GetReportDiff(baselineReportRecordID uint, reportRecordID uint, diffQuery models.ReportDiffQuery) ([]models.ReportDiffEntry, error)
+}
+
+// reportWriter writes the comparison data of a report into an export file of one report type. The comparison data
+// is streamed into the file a batch at a time, flush is called after every batch. close releases what the writer
+// holds besides the file and is called whether or not the export succeeded.
+type reportWriter interface {
+	writeStart() error
+	write(comparisonData models.ComparisonData) error
+	flush() error
+	writeEnd() error
+	close() error
 }
 
 type ExportReportService struct {
 	exportReportRecordClient exportReportRecordClient
+	reportRecordClient       reportRecordClient
 	comparisonDataClient     comparisonDataClient
+	config                   ExportReportServiceConfig
 }
 
-func NewExportReportService(exportReportRecordClient exportReportRecordClient, comparisonDataClient comparisonDataClient) *ExportReportService {
+type ExportReportServiceConfig struct {
+	// CSVBarcodeSeparator joins the barcodes of a location into a single column of a CSV export
+	CSVBarcodeSeparator string
+	// PDFIncludeMatchedLocations lists the locations that matched the expected inventory in the table of a PDF export,
+	// which otherwise only lists discrepancies unless the export is filtered by result
+	PDFIncludeMatchedLocations bool
+}
+
+func NewExportReportService(exportReportRecordClient exportReportRecordClient, reportRecordClient reportRecordClient, comparisonDataClient comparisonDataClient, config ExportReportServiceConfig) *ExportReportService {
 	return &ExportReportService{
 		exportReportRecordClient: exportReportRecordClient,
+		reportRecordClient:       reportRecordClient,
 		comparisonDataClient:     comparisonDataClient,
+		config:                   config,
+	}
+}
+
+func (er *ExportReportService) ExportReportRecord(exportReportRecordID uint) error {
+	exportReportRecord, err := er.exportReportRecordClient.Get(exportReportRecordID)
+	if err != nil {
+		return fmt.Errorf("failed to get export report record with id=%d, error: %w", exportReportRecordID, err)
+	}
+
+	// a job can be redelivered after a crash, never export the same report twice
+	if exportReportRecord.Status == models.Completed {
+		return nil
 	}
+
+	return er.ExportReport(exportReportRecord)
 }
 
-func (er *ExportReportService) ExportReport(exportReportRecord *models.ExportReportRecord) {
+func (er *ExportReportService) ExportReport(exportReportRecord *models.ExportReportRecord) error {
 	log.WithFields(log.Fields{
 		"export_report_record_id": exportReportRecord.ReportRecordID,
 		"file_name":               exportReportRecord.FileName,
 		"file_path":               exportReportRecord.FilePath,
 	}).Info("starting process to export report record")
 
+	// a retried record must not keep the reason of its previous failed attempt
+	exportReportRecord.Failure = models.Failure{}
 	er.updateExportReportRecord(exportReportRecord, models.Processing)
 
-	file, err := os.OpenFile(exportReportRecord.FilePath, os.O_APPEND|os.O_WRONLY, 0644)
+	// a retried export rewrites the file from the start
+	file, err := os.OpenFile(exportReportRecord.FilePath, os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
-		er.updateExportReportRecordWithStatusFailed(exportReportRecord, fmt.Sprintf("failed opening export report file=%s", exportReportRecord.FilePath), err)
-		return
+		return er.updateExportReportRecordWithStatusFailed(exportReportRecord, models.ErrorCodeFileUnreadable, fmt.Sprintf("failed opening export report file=%s", exportReportRecord.FilePath), err)
 	}
 	defer file.Close()
 
-	err = er.writeArrayStartingBracket(file)
+	writer, err := er.newReportWriter(exportReportRecord, file)
+	if err != nil {
+		return er.updateExportReportRecordWithStatusFailed(exportReportRecord, models.ErrorCodeInternal, fmt.Sprintf("failed to export report file=%s", file.Name()), err)
+	}
+	defer writer.close()
+
+	// ensure data is flushed to disk after every batch
+	err = er.writeReport(exportReportRecord.ReportRecordID, exportReportRecord.Filter, writer, file.Sync)
+	if err != nil {
+		var writeErr *writeReportError
+		if errors.As(err, &writeErr) {
+			return er.updateExportReportRecordWithStatusFailed(exportReportRecord, writeErr.code, fmt.Sprintf("%s, export report file=%s", writeErr.message, file.Name()), writeErr.err)
+		}
+		return er.updateExportReportRecordWithStatusFailed(exportReportRecord, models.ErrorCodeWriteFailed, fmt.Sprintf("failed to write export report file=%s", file.Name()), err)
+	}
+
+	// a failed update leaves the record processing, the retried job writes the file again
+	err = er.updateExportReportRecord(exportReportRecord, models.Completed)
+	if err != nil {
+		return fmt.Errorf("failed to mark export report record id=%d as completed, error: %w", exportReportRecord.ID, err)
+	}
+
+	log.WithFields(log.Fields{
+		"export_report_record_id": exportReportRecord.ReportRecordID,
+		"file_name":               exportReportRecord.FileName,
+		"file_path":               exportReportRecord.FilePath,
+	}).Info("finished process to export report record")
+
+	return nil
+}
+
+// StreamReport writes the comparison data of a report passing the filter straight to w, without an export record or
+// file. w is flushed after every batch when it can be, so a large report is sent with constant memory.
+func (er *ExportReportService) StreamReport(reportRecordID uint, reportType models.ExportReportType, filter models.ExportFilter, w io.Writer) error {
+	var writer reportWriter
+	switch reportType {
+	case models.ExportReportJson:
+		writer = newJSONReportWriter(w, reportColumns(filter))
+	case models.ExportReportNdjson:
+		writer = newNDJSONReportWriter(w, reportColumns(filter))
+	case models.ExportReportCsv:
+		writer = newCSVReportWriter(w, reportColumns(filter), er.config.CSVBarcodeSeparator)
+	default:
+		return fmt.Errorf("report type=%s can not be streamed", reportType)
+	}
+	defer writer.close()
+
+	sync := func() error {
+		if flusher, ok := w.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	err := er.writeReport(reportRecordID, filter, writer, sync)
+	if err != nil {
+		var writeErr *writeReportError
+		if errors.As(err, &writeErr) {
+			return fmt.Errorf("%s, report record id=%d, error: %w", writeErr.message, reportRecordID, writeErr.err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// writeReportError is a failure of writeReport with the error code and message it is recorded with.
+type writeReportError struct {
+	code    models.ErrorCode
+	message string
+	err     error
+}
+
+func (e *writeReportError) Error() string {
+	return fmt.Sprintf("%s, error: %v", e.message, e.err)
+}
+
+func (e *writeReportError) Unwrap() error {
+	return e.err
+}
+
+// writeReport writes the comparison data of a report passing the filter through the report writer a batch at a
+// time, sync is called after every batch once the writer is flushed. Batches are read in location order by keyset, so
+// reading the last batch of a large report costs the same as reading the first.
+func (er *ExportReportService) writeReport(reportRecordID uint, filter models.ExportFilter, writer reportWriter, sync func() error) error {
+	err := writer.writeStart()
 	if err != nil {
-		er.updateExportReportRecordWithStatusFailed(exportReportRecord, fmt.Sprintf("failed to write starting array bracket to export report file=%s", file.Name()), err)
-		return
+		return &writeReportError{code: models.ErrorCodeWriteFailed, message: "failed to write start of report", err: err}
 	}
 
-	limit := 50
-	offset := 0
-	firstObject := true
+	pageQuery := models.ComparisonDataPageQuery{Filter: filter, Sort: models.SortByLocation, Limit: exportBatchSize}
+	recordNumber := 0
 	for {
-		comparisonDataList, err := er.comparisonDataClient.GetAllPaginated(exportReportRecord.ID, limit, offset)
+		comparisonDataList, err := er.comparisonDataClient.GetPage(reportRecordID, pageQuery)
 		if err != nil {
-			er.updateExportReportRecordWithStatusFailed(exportReportRecord, fmt.Sprintf("failed to get comparison data for report record id=%d", exportReportRecord.ReportRecordID), err)
-			return
+			return &writeReportError{code: models.ErrorCodeDatabase, message: fmt.Sprintf("failed to get comparison data for report record id=%d", reportRecordID), err: err}
 		}
 
 		if len(comparisonDataList) == 0 {
 			break
 		}
 
-		for _, comparisonData := range comparisonDataList {
-			// write a comma before each object, except the first one
-			if !firstObject {
-				err = er.writeStringToReportFile(",\n", file)
-				if err != nil {
-					er.updateExportReportRecordWithStatusFailed(exportReportRecord, fmt.Sprintf("failed to write comma to export report file=%s", file.Name()), err)
-					return
-				}
-			} else {
-				firstObject = false
+		if filter.BaselineReportRecordID != nil {
+			err = er.addPreviousResults(*filter.BaselineReportRecordID, comparisonDataList)
+			if err != nil {
+				return &writeReportError{code: models.ErrorCodeDatabase, message: fmt.Sprintf("failed to get comparison data for baseline report record id=%d", *filter.BaselineReportRecordID), err: err}
 			}
+		}
 
-			data := jsonExportedComparisonData{
-				Location:         comparisonData.Location,
-				Scanned:          comparisonData.Scanned,
-				Occupied:         comparisonData.Occupied,
-				ActualBarcodes:   comparisonData.ActualBarcodes,
-				ExpectedBarcodes: comparisonData.ExpectedBarcodes,
-				Result:           string(comparisonData.Result),
-			}
+		for _, comparisonData := range comparisonDataList {
+			recordNumber++
 
-			jsonData, err := json.MarshalIndent(data, "  ", "  ")
+			err = writer.write(comparisonData)
 			if err != nil {
-				er.updateExportReportRecordWithStatusFailed(exportReportRecord, fmt.Sprintf("failed to write comparison data to export report file=%s", file.Name()), err)
-				return
+				return &writeReportError{code: models.ErrorCodeWriteFailed, message: "failed to write comparison data", err: &internal.ProcessingError{RecordNumber: recordNumber, Err: err}}
 			}
+		}
 
-			err = er.writeBytesToReportFile(jsonData, file)
-			if err != nil {
-				er.updateExportReportRecordWithStatusFailed(exportReportRecord, fmt.Sprintf("failed to write comparison data json to export report file=%s", file.Name()), err)
-				return
-			}
+		err = writer.flush()
+		if err == nil {
+			err = sync()
 		}
+		if err != nil {
+			return &writeReportError{code: models.ErrorCodeWriteFailed, message: "failed to sync data", err: err}
+		}
+
+		// move to the next batch
+		last := comparisonDataList[len(comparisonDataList)-1]
+		pageQuery.After = &models.ComparisonDataCursor{Value: last.Location, ID: last.ID}
+	}
 
-		// ensure data is flushed to disk
-		err = file.Sync()
+	if filter.BaselineReportRecordID != nil {
+		err = er.writeBaselineOnlyLocations(reportRecordID, filter, writer, sync, recordNumber)
 		if err != nil {
-			er.updateExportReportRecordWithStatusFailed(exportReportRecord, fmt.Sprintf("failed to sync data to disk for export report file=%s", file.Name()), err)
-			return
+			return err
 		}
+	}
 
-		offset += len(comparisonDataList) // move to the next batch
+	err = writer.writeEnd()
+	if err != nil {
+		return &writeReportError{code: models.ErrorCodeWriteFailed, message: "failed to write end of report", err: err}
 	}
 
-	err = er.writeArrayClosingBracket(file)
+	return nil
+}
+
+// addPreviousResults sets the outcome of every location of the batch in the baseline report, locations the baseline
+// report does not have are left without one.
+func (er *ExportReportService) addPreviousResults(baselineReportRecordID uint, comparisonDataList []models.ComparisonData) error {
+	locations := make([]string, len(comparisonDataList))
+	for i, comparisonData := range comparisonDataList {
+		locations[i] = comparisonData.Location
+	}
+
+	previousResults, err := er.comparisonDataClient.GetResults(baselineReportRecordID, locations)
 	if err != nil {
-		er.updateExportReportRecordWithStatusFailed(exportReportRecord, fmt.Sprintf("failed to write ending array bracket to export report file=%s", file.Name()), err)
-		return
+		return err
 	}
 
-	er.updateExportReportRecord(exportReportRecord, models.Completed)
+	for i := range comparisonDataList {
+		comparisonDataList[i].PreviousResult = previousResults[comparisonDataList[i].Location]
+	}
 
-	log.WithFields(log.Fields{
-		"export_report_record_id": exportReportRecord.ReportRecordID,
-		"file_name":               exportReportRecord.FileName,
-		"file_path":               exportReportRecord.FilePath,
-	}).Info("finished process to export report record")
+	return nil
 }
 
-func (er *ExportReportService) writeArrayStartingBracket(file *os.File) error {
-	return er.writeStringToReportFile("[\n", file)
-}
+// writeBaselineOnlyLocations writes the discrepancies of the baseline report at locations the report does not have
+// after the comparison data of the report. They are read from the same diff of the two reports the diff endpoint
+// counts, so an export lists every resolved discrepancy it does. The locations have no outcome in the report and are
+// left out when the filter selects by outcome, scanned or occupied.
+func (er *ExportReportService) writeBaselineOnlyLocations(reportRecordID uint, filter models.ExportFilter, writer reportWriter, sync func() error, recordNumber int) error {
+	if len(filter.Results) > 0 || filter.DiscrepanciesOnly || filter.Scanned != nil || filter.Occupied != nil {
+		return nil
+	}
 
-func (er *ExportReportService) writeArrayClosingBracket(file *os.File) error {
-	return er.writeStringToReportFile("\n]", file)
-}
+	baselineReportRecordID := *filter.BaselineReportRecordID
+	diffQuery := models.ReportDiffQuery{Changes: []models.DiscrepancyChange{models.DiscrepancyResolved}, Limit: exportBatchSize}
+	for {
+		entries, err := er.comparisonDataClient.GetReportDiff(baselineReportRecordID, reportRecordID, diffQuery)
+		if err != nil {
+			return &writeReportError{code: models.ErrorCodeDatabase, message: fmt.Sprintf("failed to get diff against baseline report record id=%d", baselineReportRecordID), err: err}
+		}
+
+		if len(entries) == 0 {
+			return nil
+		}
+
+		for _, entry := range entries {
+			if entry.Result != "" || !strings.HasPrefix(entry.Location, filter.LocationPrefix) {
+				continue
+			}
+			recordNumber++
+
+			err = writer.write(models.ComparisonData{ReportRecordID: reportRecordID, Location: entry.Location, PreviousResult: entry.PreviousResult})
+			if err != nil {
+				return &writeReportError{code: models.ErrorCodeWriteFailed, message: "failed to write comparison data", err: &internal.ProcessingError{RecordNumber: recordNumber, Err: err}}
+			}
+		}
+
+		err = writer.flush()
+		if err == nil {
+			err = sync()
+		}
+		if err != nil {
+			return &writeReportError{code: models.ErrorCodeWriteFailed, message: "failed to sync data", err: err}
+		}
 
-func (er *ExportReportService) writeStringToReportFile(str string, file *os.File) error {
-	return er.writeBytesToReportFile([]byte(str), file)
+		diffQuery.After = entries[len(entries)-1].Location // move to the next page
+	}
 }
 
-func (er *ExportReportService) writeBytesToReportFile(bytes []byte, file *os.File) error {
-	_, err := file.Write(bytes)
-	return err
+func (er *ExportReportService) newReportWriter(exportReportRecord *models.ExportReportRecord, file io.Writer) (reportWriter, error) {
+	filter := exportReportRecord.Filter
+
+	switch exportReportRecord.ReportType {
+	case models.ExportReportJson:
+		return newJSONReportWriter(file, reportColumns(filter)), nil
+	case models.ExportReportCsv:
+		return newCSVReportWriter(file, reportColumns(filter), er.config.CSVBarcodeSeparator), nil
+	case models.ExportReportXlsx:
+		// the sheets of an XLSX export are the outcomes of the report mode
+		reportRecord, err := er.reportRecordClient.Get(exportReportRecord.ReportRecordID)
+		if err != nil {
+			return nil, &internal.ProcessingError{Code: models.ErrorCodeDatabase, Err: err}
+		}
+
+		return newXLSXReportWriter(file, reportColumns(filter), filter.Results, reportRecord.Mode, filter.BaselineReportRecordID), nil
+	case models.ExportReportPdf:
+		// the cover page of a PDF export describes the report
+		reportRecord, err := er.reportRecordClient.Get(exportReportRecord.ReportRecordID)
+		if err != nil {
+			return nil, &internal.ProcessingError{Code: models.ErrorCodeDatabase, Err: err}
+		}
+
+		// the table of a PDF export has its own default columns, and lists the results an export is filtered by
+		var columns []models.ExportColumn
+		if len(filter.Columns) > 0 {
+			columns = reportColumns(filter)
+		}
+		includeMatched := er.config.PDFIncludeMatchedLocations || len(filter.Results) > 0
+
+		return newPDFReportWriter(file, reportRecord, filter.BaselineReportRecordID, columns, includeMatched, time.Now()), nil
+	default:
+		return nil, fmt.Errorf("report type=%s not supported", exportReportRecord.ReportType)
+	}
 }
 
-func (er *ExportReportService) updateExportReportRecordWithStatusFailed(exportReportRecord *models.ExportReportRecord, message string, err error) {
+func (er *ExportReportService) updateExportReportRecordWithStatusFailed(exportReportRecord *models.ExportReportRecord, code models.ErrorCode, message string, err error) error {
 	log.Errorf("%s: %v", message, err)
+
+	failedErr := errors.New(message)
+	if err != nil {
+		failedErr = fmt.Errorf("%s, error: %w", message, err)
+	}
+
+	exportReportRecord.Failure = internal.NewFailure(code, failedErr)
 	er.updateExportReportRecord(exportReportRecord, models.Failed)
+
+	return internal.FailureError(exportReportRecord.Failure, failedErr)
 }
 
-func (er *ExportReportService) updateExportReportRecord(exportReportRecord *models.ExportReportRecord, status models.Status) {
+func (er *ExportReportService) updateExportReportRecord(exportReportRecord *models.ExportReportRecord, status models.Status) error {
 	exportReportRecord.Status = status
 	err := er.exportReportRecordClient.Update(exportReportRecord)
 	if err != nil {
@@ -163,4 +367,6 @@ func (er *ExportReportService) updateExportReportRecord(exportReportRecord *mode
 			"status":                  exportReportRecord.Status,
 		}).Errorf("failed to update export report record status, error: %v", err)
 	}
+
+	return err
 }
@@ -1,17 +1,26 @@
 package export
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/golang/mock/gomock"
 	mockexportreportservice "github.com/habbas99/dexory/generated/services/export"
+	"github.com/habbas99/dexory/internal"
 	"github.com/habbas99/dexory/internal/models"
 	"github.com/stretchr/testify/suite"
+	"github.com/xuri/excelize/v2"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 type ExportReportServiceTestSuite struct {
 	suite.Suite
 	MockExportReportRecordClient *mockexportreportservice.MockexportReportRecordClient
+	MockReportRecordClient       *mockexportreportservice.MockreportRecordClient
 	MockComparisonDataClient     *mockexportreportservice.MockcomparisonDataClient
 	ExportReportService          *ExportReportService
 	tempFilePath                 string
@@ -26,9 +35,12 @@ func (suite *ExportReportServiceTestSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 
 	suite.MockExportReportRecordClient = mockexportreportservice.NewMockexportReportRecordClient(suite.ctrl)
+	suite.MockReportRecordClient = mockexportreportservice.NewMockreportRecordClient(suite.ctrl)
 	suite.MockComparisonDataClient = mockexportreportservice.NewMockcomparisonDataClient(suite.ctrl)
 
-	suite.ExportReportService = NewExportReportService(suite.MockExportReportRecordClient, suite.MockComparisonDataClient)
+	suite.ExportReportService = NewExportReportService(suite.MockExportReportRecordClient, suite.MockReportRecordClient, suite.MockComparisonDataClient, ExportReportServiceConfig{
+		CSVBarcodeSeparator: "|",
+	})
 
 	// create a temporary file to simulate the export report file
 	file, err := os.CreateTemp("", "export_report_*.json")
@@ -55,27 +67,32 @@ func (suite *ExportReportServiceTestSuite) TestExportReport() {
 
 	comparisonData := []models.ComparisonData{
 		{
-			ReportRecordID:   reportRecordID,
-			Location:         "Location1",
-			Scanned:          true,
-			Occupied:         true,
-			ActualBarcodes:   []string{"Barcode1"},
-			ExpectedBarcodes: []string{"Barcode1"},
-			Result:           models.LocationOccupiedWithCorrectItems,
+			ReportRecordID:     reportRecordID,
+			Location:           "Location1",
+			Scanned:            true,
+			Occupied:           true,
+			ActualBarcodes:     []string{"Barcode1"},
+			ExpectedBarcodes:   []string{"Barcode1"},
+			MatchedBarcodes:    []string{"Barcode1"},
+			MissingBarcodes:    []string{},
+			UnexpectedBarcodes: []string{},
+			Result:             models.LocationOccupiedWithCorrectItems,
 		},
 		{
-			ReportRecordID:   reportRecordID,
-			Location:         "Location2",
-			Scanned:          true,
-			Occupied:         true,
-			ActualBarcodes:   []string{"Barcode2"},
-			ExpectedBarcodes: []string{"Barcode2"},
-			Result:           models.LocationOccupiedWithCorrectItems,
+			ReportRecordID:     reportRecordID,
+			Location:           "Location2",
+			Scanned:            true,
+			Occupied:           true,
+			ActualBarcodes:     []string{"Barcode2"},
+			ExpectedBarcodes:   []string{"Barcode2"},
+			MatchedBarcodes:    []string{"Barcode2"},
+			MissingBarcodes:    []string{},
+			UnexpectedBarcodes: []string{},
+			Result:             models.LocationOccupiedWithCorrectItems,
 		},
 	}
 
-	suite.MockComparisonDataClient.EXPECT().GetAllPaginated(reportRecordID, 50, 0).Return(comparisonData, nil).Times(1)
-	suite.MockComparisonDataClient.EXPECT().GetAllPaginated(reportRecordID, 50, 2).Return([]models.ComparisonData{}, nil).Times(1)
+	suite.expectPages(reportRecordID, models.ExportFilter{}, comparisonData)
 
 	suite.MockExportReportRecordClient.EXPECT().Update(gomock.Any()).Times(2)
 
@@ -93,6 +110,9 @@ func (suite *ExportReportServiceTestSuite) TestExportReport() {
 		"occupied":true,
 		"actualBarcodes":["Barcode1"],
 		"expectedBarcodes":["Barcode1"],
+		"matchedBarcodes":["Barcode1"],
+		"missingBarcodes":[],
+		"unexpectedBarcodes":[],
 		"result":"The location was occupied by the expected items"
 	},{
 		"location":"Location2",
@@ -100,6 +120,632 @@ func (suite *ExportReportServiceTestSuite) TestExportReport() {
 		"occupied":true,
 		"actualBarcodes":["Barcode2"],
 		"expectedBarcodes":["Barcode2"],
+		"matchedBarcodes":["Barcode2"],
+		"missingBarcodes":[],
+		"unexpectedBarcodes":[],
 		"result":"The location was occupied by the expected items"
 	}]`, string(fileContents))
 }
+
+func (suite *ExportReportServiceTestSuite) TestExportReportAsCSV() {
+	// Given
+	reportRecordID := uint(3)
+	exportReportRecord := &models.ExportReportRecord{
+		ReportType:     models.ExportReportCsv,
+		FilePath:       suite.tempFilePath,
+		ReportRecordID: reportRecordID,
+		Status:         models.Pending,
+	}
+	exportReportRecord.ID = uint(7)
+
+	comparisonData := []models.ComparisonData{
+		{
+			ReportRecordID:     reportRecordID,
+			Location:           "Location1",
+			Scanned:            true,
+			Occupied:           true,
+			ActualBarcodes:     []string{"Barcode1", "Barcode3"},
+			ExpectedBarcodes:   []string{"Barcode1", "Barcode2"},
+			MatchedBarcodes:    []string{"Barcode1"},
+			MissingBarcodes:    []string{"Barcode2"},
+			UnexpectedBarcodes: []string{"Barcode3"},
+			Result:             models.LocationOccupiedWithSomeExpectedItems,
+		},
+		{
+			ReportRecordID:     reportRecordID,
+			Location:           "Location2",
+			Scanned:            false,
+			Occupied:           false,
+			ActualBarcodes:     []string{},
+			ExpectedBarcodes:   []string{},
+			MatchedBarcodes:    []string{},
+			MissingBarcodes:    []string{},
+			UnexpectedBarcodes: []string{},
+			Result:             models.LocationNotScanned,
+		},
+	}
+
+	// comparison data belongs to the report record, not to the export report record
+	suite.expectPages(reportRecordID, models.ExportFilter{}, comparisonData)
+
+	suite.MockExportReportRecordClient.EXPECT().Update(gomock.Any()).Times(2)
+
+	// When
+	err := suite.ExportReportService.ExportReport(exportReportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, exportReportRecord.Status)
+
+	fileContents, err := os.ReadFile(suite.tempFilePath)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"location,scanned,occupied,actualBarcodes,expectedBarcodes,matchedBarcodes,missingBarcodes,unexpectedBarcodes,result\n"+
+			"Location1,true,true,Barcode1|Barcode3,Barcode1|Barcode2,Barcode1,Barcode2,Barcode3,"+
+			"\"The location was occupied by some of the expected items, along with unexpected items\"\n"+
+			"Location2,false,false,,,,,,The location was not scanned\n",
+		string(fileContents),
+	)
+}
+
+func (suite *ExportReportServiceTestSuite) TestExportFilteredReportAsCSV() {
+	// Given
+	reportRecordID := uint(3)
+	filter := models.ExportFilter{
+		DiscrepanciesOnly: true,
+		LocationPrefix:    "A-",
+		Columns:           []string{"location", "missingBarcodes", "result"},
+	}
+	exportReportRecord := &models.ExportReportRecord{
+		ReportType:     models.ExportReportCsv,
+		FilePath:       suite.tempFilePath,
+		ReportRecordID: reportRecordID,
+		Status:         models.Pending,
+		Filter:         filter,
+	}
+	exportReportRecord.ID = uint(10)
+
+	comparisonData := []models.ComparisonData{
+		{
+			ReportRecordID:   reportRecordID,
+			Location:         "A-1",
+			Scanned:          true,
+			ExpectedBarcodes: []string{"Barcode1", "Barcode2"},
+			MissingBarcodes:  []string{"Barcode1", "Barcode2"},
+			Result:           models.LocationEmptyButNotExpected,
+		},
+	}
+
+	// filtering is left to the database
+	suite.expectPages(reportRecordID, filter, comparisonData)
+
+	suite.MockExportReportRecordClient.EXPECT().Update(gomock.Any()).Times(2)
+
+	// When
+	err := suite.ExportReportService.ExportReport(exportReportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+
+	fileContents, err := os.ReadFile(suite.tempFilePath)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"location,missingBarcodes,result\n"+
+			"A-1,Barcode1|Barcode2,\"The location was empty, but it should have been occupied\"\n",
+		string(fileContents),
+	)
+}
+
+func (suite *ExportReportServiceTestSuite) TestExportReportAsXLSX() {
+	// Given
+	reportRecordID := uint(4)
+	exportReportRecord := &models.ExportReportRecord{
+		ReportType:     models.ExportReportXlsx,
+		FilePath:       suite.tempFilePath,
+		ReportRecordID: reportRecordID,
+		Status:         models.Pending,
+	}
+	exportReportRecord.ID = uint(8)
+
+	comparisonData := []models.ComparisonData{
+		{
+			ReportRecordID:     reportRecordID,
+			Location:           "Location1",
+			Scanned:            true,
+			Occupied:           true,
+			ActualBarcodes:     []string{"Barcode1", "Barcode3"},
+			ExpectedBarcodes:   []string{"Barcode1", "Barcode2"},
+			MatchedBarcodes:    []string{"Barcode1"},
+			MissingBarcodes:    []string{"Barcode2"},
+			UnexpectedBarcodes: []string{"Barcode3"},
+			Result:             models.LocationOccupiedWithSomeExpectedItems,
+		},
+		{
+			ReportRecordID:   reportRecordID,
+			Location:         "Location2",
+			Scanned:          true,
+			Occupied:         true,
+			ActualBarcodes:   []string{"Barcode4"},
+			ExpectedBarcodes: []string{"Barcode4"},
+			MatchedBarcodes:  []string{"Barcode4"},
+			Result:           models.LocationOccupiedWithCorrectItems,
+		},
+		{
+			ReportRecordID: reportRecordID,
+			Location:       "Location3",
+			Scanned:        true,
+			Result:         models.LocationEmptyAsExpected,
+		},
+		{
+			ReportRecordID: reportRecordID,
+			Location:       "Location4",
+			Result:         models.LocationNotScanned,
+		},
+	}
+
+	reportRecord := &models.ReportRecord{Mode: models.ComparisonReport, Status: models.Completed}
+	reportRecord.ID = reportRecordID
+
+	suite.MockReportRecordClient.EXPECT().Get(reportRecordID).Return(reportRecord, nil).Times(1)
+	suite.expectPages(reportRecordID, models.ExportFilter{}, comparisonData)
+
+	suite.MockExportReportRecordClient.EXPECT().Update(gomock.Any()).Times(2)
+
+	// When
+	err := suite.ExportReportService.ExportReport(exportReportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, exportReportRecord.Status)
+
+	workbook, err := excelize.OpenFile(suite.tempFilePath)
+	suite.Require().NoError(err)
+	defer workbook.Close()
+
+	sheets := workbook.GetSheetList()
+	suite.Len(sheets, 12)
+	suite.Equal([]string{"Summary", "Details"}, sheets[:2])
+
+	summary, err := workbook.GetRows("Summary")
+	suite.Require().NoError(err)
+	suite.Equal([]string{"result", "locations", "share (%)"}, summary[0])
+	suite.Equal([]string{string(models.LocationEmptyAsExpected), "1", "25"}, summary[1])
+	suite.Equal([]string{string(models.LocationNotScanned), "1", "25"}, summary[7])
+	suite.Equal([]string{string(models.LocationEmptyButNotExpected), "0", "0"}, summary[3])
+	suite.Equal([]string{"total locations", "4"}, summary[14])
+	suite.Equal([]string{"accuracy (%)", "50"}, summary[17])
+
+	details, err := workbook.GetRows("Details")
+	suite.Require().NoError(err)
+	suite.Len(details, 5)
+	suite.Equal([]string{
+		"Location1", "TRUE", "TRUE", "Barcode1, Barcode3", "Barcode1, Barcode2", "Barcode1", "Barcode2", "Barcode3",
+		string(models.LocationOccupiedWithSomeExpectedItems),
+	}, details[1])
+
+	notScanned, err := workbook.GetRows("Not scanned")
+	suite.Require().NoError(err)
+	suite.Len(notScanned, 2)
+	suite.Equal("Location4", notScanned[1][0])
+
+	wrongItems, err := workbook.GetRows("Wrong items")
+	suite.Require().NoError(err)
+	suite.Len(wrongItems, 1)
+
+	panes, err := workbook.GetPanes("Details")
+	suite.Require().NoError(err)
+	suite.True(panes.Freeze)
+	suite.Equal(1, panes.YSplit)
+
+	tables, err := workbook.GetTables("Details")
+	suite.Require().NoError(err)
+	suite.Require().Len(tables, 1)
+	suite.Equal("A1:I5", tables[0].Range)
+}
+
+func (suite *ExportReportServiceTestSuite) TestExportScanDiffAsXLSX() {
+	// Given
+	reportRecordID := uint(4)
+	exportReportRecord := &models.ExportReportRecord{
+		ReportType:     models.ExportReportXlsx,
+		FilePath:       suite.tempFilePath,
+		ReportRecordID: reportRecordID,
+		Status:         models.Pending,
+	}
+	exportReportRecord.ID = uint(8)
+
+	comparisonData := []models.ComparisonData{
+		{
+			ReportRecordID:     reportRecordID,
+			Location:           "ZA001A",
+			Scanned:            true,
+			Occupied:           true,
+			ActualBarcodes:     []string{"Barcode2"},
+			ExpectedBarcodes:   []string{"Barcode1"},
+			MissingBarcodes:    []string{"Barcode1"},
+			UnexpectedBarcodes: []string{"Barcode2"},
+			Result:             models.LocationBarcodeChanged,
+		},
+		{
+			ReportRecordID: reportRecordID,
+			Location:       "ZA002A",
+			Scanned:        true,
+			Result:         models.LocationUnchanged,
+		},
+	}
+
+	baselineBulkScanRecord := models.BulkScanRecord{FileName: "monday.json"}
+	reportRecord := &models.ReportRecord{
+		Mode:                   models.ScanDiffReport,
+		BaselineBulkScanRecord: &baselineBulkScanRecord,
+		Status:                 models.Completed,
+	}
+	reportRecord.ID = reportRecordID
+
+	suite.MockReportRecordClient.EXPECT().Get(reportRecordID).Return(reportRecord, nil).Times(1)
+	suite.expectPages(reportRecordID, models.ExportFilter{}, comparisonData)
+
+	suite.MockExportReportRecordClient.EXPECT().Update(gomock.Any()).Times(2)
+
+	// When
+	err := suite.ExportReportService.ExportReport(exportReportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, exportReportRecord.Status)
+
+	workbook, err := excelize.OpenFile(suite.tempFilePath)
+	suite.Require().NoError(err)
+	defer workbook.Close()
+
+	suite.Equal([]string{
+		"Summary", "Details", "Newly occupied", "Newly emptied", "Barcode changed", "Newly unreadable", "Newly readable",
+		"Added location", "Removed location",
+	}, workbook.GetSheetList())
+
+	summary, err := workbook.GetRows("Summary")
+	suite.Require().NoError(err)
+	suite.Equal([]string{string(models.LocationUnchanged), "1", "50"}, summary[1])
+	suite.Equal([]string{"unchanged locations", "1"}, summary[len(summary)-3])
+	suite.Equal([]string{"changes", "1"}, summary[len(summary)-2])
+	suite.Equal([]string{"unchanged (%)", "50"}, summary[len(summary)-1])
+
+	barcodeChanged, err := workbook.GetRows("Barcode changed")
+	suite.Require().NoError(err)
+	suite.Len(barcodeChanged, 2)
+	suite.Equal("ZA001A", barcodeChanged[1][0])
+}
+
+func (suite *ExportReportServiceTestSuite) TestExportReportAsPDF() {
+	// Given
+	reportRecordID := uint(5)
+	exportReportRecord := &models.ExportReportRecord{
+		ReportType:     models.ExportReportPdf,
+		FilePath:       suite.tempFilePath,
+		ReportRecordID: reportRecordID,
+		Status:         models.Pending,
+	}
+	exportReportRecord.ID = uint(9)
+
+	reportRecord := &models.ReportRecord{
+		BulkScanRecord:    models.BulkScanRecord{FileName: "scan.json"},
+		ReferenceFileName: "reference.csv",
+		Status:            models.Completed,
+	}
+	reportRecord.ID = reportRecordID
+
+	// enough discrepancies for the table to span several pages
+	var comparisonData []models.ComparisonData
+	for i := 0; i < 50; i++ {
+		comparisonData = append(comparisonData, models.ComparisonData{
+			ReportRecordID:   reportRecordID,
+			Location:         fmt.Sprintf("Location%d", i),
+			Scanned:          true,
+			ExpectedBarcodes: []string{"Barcode1"},
+			MissingBarcodes:  []string{"Barcode1"},
+			Result:           models.LocationEmptyButNotExpected,
+		})
+	}
+
+	suite.MockReportRecordClient.EXPECT().Get(reportRecordID).Return(reportRecord, nil).Times(1)
+	suite.expectPages(reportRecordID, models.ExportFilter{}, comparisonData)
+
+	suite.MockExportReportRecordClient.EXPECT().Update(gomock.Any()).Times(2)
+
+	// When
+	err := suite.ExportReportService.ExportReport(exportReportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, exportReportRecord.Status)
+
+	fileContents, err := os.ReadFile(suite.tempFilePath)
+	suite.Require().NoError(err)
+	suite.True(bytes.HasPrefix(fileContents, []byte("%PDF-")))
+	// cover, summary and two pages of discrepancies
+	suite.Len(regexp.MustCompile(`/Type /Page\b`).FindAll(fileContents, -1), 4)
+}
+
+func (suite *ExportReportServiceTestSuite) TestExportReportAsPDFFailsWithoutReportRecord() {
+	// Given
+	reportRecordID := uint(5)
+	exportReportRecord := &models.ExportReportRecord{
+		ReportType:     models.ExportReportPdf,
+		FilePath:       suite.tempFilePath,
+		ReportRecordID: reportRecordID,
+		Status:         models.Pending,
+	}
+
+	suite.MockReportRecordClient.EXPECT().Get(reportRecordID).Return(nil, errors.New("record not found")).Times(1)
+	suite.MockExportReportRecordClient.EXPECT().Update(gomock.Any()).Times(2)
+
+	// When
+	err := suite.ExportReportService.ExportReport(exportReportRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Equal(models.Failed, exportReportRecord.Status)
+	suite.Equal(models.ErrorCodeDatabase, exportReportRecord.ErrorCode)
+}
+
+func (suite *ExportReportServiceTestSuite) TestPDFReportWriterLeavesOutMatchedLocations() {
+	// Given
+	reportRecord := &models.ReportRecord{
+		BulkScanRecord:    models.BulkScanRecord{FileName: "scan.json"},
+		ReferenceFileName: "reference.xlsx",
+		ReferenceSheet:    "Inventory",
+	}
+	reportRecord.ID = uint(6)
+
+	var buffer bytes.Buffer
+	writer := newPDFReportWriter(&buffer, reportRecord, nil, nil, false, time.Date(2024, 8, 1, 6, 0, 0, 0, time.UTC))
+	// page contents are only readable without compression
+	writer.pdf.SetCompression(false)
+
+	// When
+	suite.Require().NoError(writer.writeStart())
+	suite.Require().NoError(writer.write(models.ComparisonData{
+		Location:         "MatchedLocation",
+		ActualBarcodes:   []string{"Barcode1"},
+		ExpectedBarcodes: []string{"Barcode1"},
+		MatchedBarcodes:  []string{"Barcode1"},
+		Result:           models.LocationOccupiedWithCorrectItems,
+	}))
+	suite.Require().NoError(writer.write(models.ComparisonData{
+		Location:         "WrongLocation",
+		ActualBarcodes:   []string{"Barcode2"},
+		ExpectedBarcodes: []string{"Barcode3"},
+		Result:           models.LocationOccupiedWithWrongItems,
+	}))
+	suite.Require().NoError(writer.writeEnd())
+
+	// Then
+	contents := buffer.String()
+	suite.Contains(contents, "(scan.json)")
+	suite.Contains(contents, "(reference.xlsx)")
+	suite.Contains(contents, "(Inventory)")
+	suite.Contains(contents, "(2024-08-01 06:00 UTC)")
+	suite.Contains(contents, "(Accuracy: 50.00% \\(1 of 2 locations matched, 1 discrepancies\\))")
+	suite.Contains(contents, "(WrongLocation)")
+	suite.NotContains(contents, "(MatchedLocation)")
+}
+
+func (suite *ExportReportServiceTestSuite) TestExportReportComparedAgainstBaselineAsCSV() {
+	// Given
+	reportRecordID := uint(3)
+	baselineReportRecordID := uint(2)
+	filter := models.ExportFilter{
+		Columns:                []string{"location", "result", "previousResult", "discrepancyChange"},
+		BaselineReportRecordID: &baselineReportRecordID,
+	}
+	exportReportRecord := &models.ExportReportRecord{
+		ReportType:     models.ExportReportCsv,
+		FilePath:       suite.tempFilePath,
+		ReportRecordID: reportRecordID,
+		Status:         models.Pending,
+		Filter:         filter,
+	}
+	exportReportRecord.ID = uint(11)
+
+	comparisonData := []models.ComparisonData{
+		{ReportRecordID: reportRecordID, Location: "A-1", Result: models.LocationEmptyAsExpected},
+		{ReportRecordID: reportRecordID, Location: "A-2", Result: models.LocationNotScanned},
+		{ReportRecordID: reportRecordID, Location: "A-3", Result: models.LocationOccupiedWithWrongItems},
+		{ReportRecordID: reportRecordID, Location: "A-4", Result: models.LocationEmptyAsExpected},
+	}
+
+	suite.expectPages(reportRecordID, filter, comparisonData)
+	suite.MockComparisonDataClient.EXPECT().GetResults(baselineReportRecordID, []string{"A-1", "A-2", "A-3", "A-4"}).Return(map[string]models.ScanComparisonOutcome{
+		"A-1": models.LocationEmptyButNotExpected,
+		"A-2": models.LocationNotScanned,
+		"A-4": models.LocationEmptyAsExpected,
+	}, nil).Times(1)
+
+	// B-1 only has a discrepancy in the baseline report, the report does not have the location anymore
+	resolvedQuery := models.ReportDiffQuery{Changes: []models.DiscrepancyChange{models.DiscrepancyResolved}, Limit: exportBatchSize}
+	suite.MockComparisonDataClient.EXPECT().GetReportDiff(baselineReportRecordID, reportRecordID, resolvedQuery).Return([]models.ReportDiffEntry{
+		{Location: "A-1", Change: models.DiscrepancyResolved, PreviousResult: models.LocationEmptyButNotExpected, Result: models.LocationEmptyAsExpected},
+		{Location: "B-1", Change: models.DiscrepancyResolved, PreviousResult: models.LocationMissingExpectedItems},
+	}, nil).Times(1)
+	resolvedQuery.After = "B-1"
+	suite.MockComparisonDataClient.EXPECT().GetReportDiff(baselineReportRecordID, reportRecordID, resolvedQuery).Return([]models.ReportDiffEntry{}, nil).Times(1)
+
+	suite.MockExportReportRecordClient.EXPECT().Update(gomock.Any()).Times(2)
+
+	// When
+	err := suite.ExportReportService.ExportReport(exportReportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+
+	fileContents, err := os.ReadFile(suite.tempFilePath)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"location,result,previousResult,discrepancyChange\n"+
+			"A-1,\"The location was empty, as expected\",\"The location was empty, but it should have been occupied\",resolved\n"+
+			"A-2,The location was not scanned,The location was not scanned,persistent\n"+
+			"A-3,The location was occupied by the wrong items,,new\n"+
+			"A-4,\"The location was empty, as expected\",\"The location was empty, as expected\",\n"+
+			"B-1,,\"The location was occupied, but some expected items were missing\",resolved\n",
+		string(fileContents),
+	)
+}
+
+func (suite *ExportReportServiceTestSuite) TestPDFReportWriterListsResolvedDiscrepancies() {
+	// Given
+	reportRecord := &models.ReportRecord{
+		BulkScanRecord:    models.BulkScanRecord{FileName: "scan.json"},
+		ReferenceFileName: "reference.csv",
+	}
+	reportRecord.ID = uint(7)
+	baselineReportRecordID := uint(6)
+
+	var buffer bytes.Buffer
+	writer := newPDFReportWriter(&buffer, reportRecord, &baselineReportRecordID, nil, false, time.Date(2024, 8, 1, 6, 0, 0, 0, time.UTC))
+	// page contents are only readable without compression
+	writer.pdf.SetCompression(false)
+
+	// When
+	suite.Require().NoError(writer.writeStart())
+	suite.Require().NoError(writer.write(models.ComparisonData{
+		Location:       "ResolvedLocation",
+		PreviousResult: models.LocationOccupiedWithWrongItems,
+		Result:         models.LocationOccupiedWithCorrectItems,
+	}))
+	suite.Require().NoError(writer.write(models.ComparisonData{
+		Location:       "MatchedLocation",
+		PreviousResult: models.LocationOccupiedWithCorrectItems,
+		Result:         models.LocationOccupiedWithCorrectItems,
+	}))
+	suite.Require().NoError(writer.write(models.ComparisonData{
+		Location: "NewLocation",
+		Result:   models.LocationNotScanned,
+	}))
+	// the report does not have a location of the baseline report anymore
+	suite.Require().NoError(writer.write(models.ComparisonData{
+		Location:       "RemovedLocation",
+		PreviousResult: models.LocationOccupiedWithWrongItems,
+	}))
+	suite.Require().NoError(writer.writeEnd())
+
+	// Then
+	contents := buffer.String()
+	suite.Contains(contents, "(Report 6)")
+	suite.Contains(contents, "(Accuracy: 66.67% \\(2 of 3 locations matched, 1 discrepancies\\))")
+	suite.Contains(contents, "(Compared against report 6: 2 resolved, 0 persistent and 1 new discrepancies)")
+	suite.Contains(contents, "(ResolvedLocation)")
+	suite.Contains(contents, "(NewLocation)")
+	suite.Contains(contents, "(RemovedLocation)")
+	suite.NotContains(contents, "(MatchedLocation)")
+}
+
+func (suite *ExportReportServiceTestSuite) TestExportReportRewritesFileOfFailedAttempt() {
+	// Given
+	reportRecordID := uint(1)
+	exportReportRecord := &models.ExportReportRecord{
+		ReportType:     models.ExportReportCsv,
+		FilePath:       suite.tempFilePath,
+		ReportRecordID: reportRecordID,
+		Status:         models.Failed,
+	}
+	exportReportRecord.ID = uint(1)
+
+	err := os.WriteFile(suite.tempFilePath, []byte("partial content of a failed attempt\n"), 0644)
+	suite.Require().NoError(err)
+
+	suite.expectPages(reportRecordID, models.ExportFilter{})
+	suite.MockExportReportRecordClient.EXPECT().Update(gomock.Any()).Times(2)
+
+	// When
+	err = suite.ExportReportService.ExportReport(exportReportRecord)
+
+	// Then
+	suite.Require().NoError(err)
+
+	fileContents, err := os.ReadFile(suite.tempFilePath)
+	suite.Require().NoError(err)
+	suite.Equal("location,scanned,occupied,actualBarcodes,expectedBarcodes,matchedBarcodes,missingBarcodes,unexpectedBarcodes,result\n", string(fileContents))
+}
+
+func (suite *ExportReportServiceTestSuite) TestExportReportReturnsErrorWhenCompletedStatusIsNotSaved() {
+	// Given
+	reportRecordID := uint(1)
+	exportReportRecord := &models.ExportReportRecord{
+		ReportType:     models.ExportReportCsv,
+		FilePath:       suite.tempFilePath,
+		ReportRecordID: reportRecordID,
+		Status:         models.Pending,
+	}
+	exportReportRecord.ID = uint(7)
+
+	suite.expectPages(reportRecordID, models.ExportFilter{})
+	suite.MockExportReportRecordClient.EXPECT().Update(exportReportRecord).Return(nil).Times(1)
+	suite.MockExportReportRecordClient.EXPECT().Update(exportReportRecord).Return(fmt.Errorf("connection reset")).Times(1)
+
+	// When
+	err := suite.ExportReportService.ExportReport(exportReportRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "failed to mark export report record id=7 as completed")
+	var permanentErr *internal.PermanentError
+	suite.False(errors.As(err, &permanentErr))
+}
+
+func (suite *ExportReportServiceTestSuite) TestStreamReportAsNDJSON() {
+	// Given
+	reportRecordID := uint(2)
+	filter := models.ExportFilter{Columns: []string{"location", "missingBarcodes"}}
+
+	firstBatch := make([]models.ComparisonData, 50)
+	for i := range firstBatch {
+		firstBatch[i] = models.ComparisonData{ID: uint(i + 1), ReportRecordID: reportRecordID, Location: fmt.Sprintf("A-%02d", i)}
+	}
+	secondBatch := []models.ComparisonData{
+		{ID: uint(51), ReportRecordID: reportRecordID, Location: "B-01", MissingBarcodes: []string{"Barcode1"}},
+	}
+
+	suite.expectPages(reportRecordID, filter, firstBatch, secondBatch)
+
+	var buffer bytes.Buffer
+
+	// When
+	err := suite.ExportReportService.StreamReport(reportRecordID, models.ExportReportNdjson, filter, &buffer)
+
+	// Then
+	suite.Require().NoError(err)
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	suite.Len(lines, 51)
+	suite.Equal(`{"location":"A-00","missingBarcodes":null}`, lines[0])
+	suite.Equal(`{"location":"B-01","missingBarcodes":["Barcode1"]}`, lines[50])
+}
+
+func (suite *ExportReportServiceTestSuite) TestStreamReportFailsOnDatabaseError() {
+	// Given
+	reportRecordID := uint(2)
+
+	suite.MockComparisonDataClient.EXPECT().GetPage(reportRecordID, models.ComparisonDataPageQuery{Sort: models.SortByLocation, Limit: exportBatchSize}).Return(nil, errors.New("connection lost")).Times(1)
+
+	var buffer bytes.Buffer
+
+	// When
+	err := suite.ExportReportService.StreamReport(reportRecordID, models.ExportReportCsv, models.ExportFilter{}, &buffer)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "failed to get comparison data for report record id=2")
+}
+
+// expectPages expects the comparison data of the report to be read a page at a time, each page after the location and
+// id of the last location of the page before, until an empty page.
+func (suite *ExportReportServiceTestSuite) expectPages(reportRecordID uint, filter models.ExportFilter, pages ...[]models.ComparisonData) {
+	pageQuery := models.ComparisonDataPageQuery{Filter: filter, Sort: models.SortByLocation, Limit: exportBatchSize}
+	for _, page := range pages {
+		suite.MockComparisonDataClient.EXPECT().GetPage(reportRecordID, pageQuery).Return(page, nil).Times(1)
+
+		last := page[len(page)-1]
+		pageQuery.After = &models.ComparisonDataCursor{Value: last.Location, ID: last.ID}
+	}
+	suite.MockComparisonDataClient.EXPECT().GetPage(reportRecordID, pageQuery).Return([]models.ComparisonData{}, nil).Times(1)
+}
@@ -0,0 +1,124 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// jsonExportedComparisonData is the JSON object of a location, its keys are the selected columns in column order.
+type jsonExportedComparisonData struct {
+	columns        []models.ExportColumn
+	comparisonData models.ComparisonData
+}
+
+func (d jsonExportedComparisonData) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, column := range d.columns {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		key, err := json.Marshal(string(column))
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(reportColumnValue(column, d.comparisonData))
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+
+	return buffer.Bytes(), nil
+}
+
+// jsonReportWriter writes the report as a JSON array with one object per location.
+type jsonReportWriter struct {
+	file        io.Writer
+	columns     []models.ExportColumn
+	firstObject bool
+}
+
+func newJSONReportWriter(file io.Writer, columns []models.ExportColumn) *jsonReportWriter {
+	return &jsonReportWriter{file: file, columns: columns, firstObject: true}
+}
+
+func (w *jsonReportWriter) writeStart() error {
+	return w.writeString("[\n")
+}
+
+func (w *jsonReportWriter) write(comparisonData models.ComparisonData) error {
+	// write a comma before each object, except the first one
+	if !w.firstObject {
+		if err := w.writeString(",\n"); err != nil {
+			return err
+		}
+	}
+	w.firstObject = false
+
+	data := jsonExportedComparisonData{columns: w.columns, comparisonData: comparisonData}
+
+	jsonData, err := json.MarshalIndent(data, "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.file.Write(jsonData)
+	return err
+}
+
+func (w *jsonReportWriter) flush() error {
+	return nil
+}
+
+func (w *jsonReportWriter) writeEnd() error {
+	return w.writeString("\n]")
+}
+
+func (w *jsonReportWriter) close() error {
+	return nil
+}
+
+func (w *jsonReportWriter) writeString(str string) error {
+	_, err := w.file.Write([]byte(str))
+	return err
+}
+
+// ndjsonReportWriter writes the report as newline delimited JSON with one object per line and location, a client
+// can read it a line at a time while it is streamed.
+type ndjsonReportWriter struct {
+	encoder *json.Encoder
+	columns []models.ExportColumn
+}
+
+func newNDJSONReportWriter(file io.Writer, columns []models.ExportColumn) *ndjsonReportWriter {
+	return &ndjsonReportWriter{encoder: json.NewEncoder(file), columns: columns}
+}
+
+func (w *ndjsonReportWriter) writeStart() error {
+	return nil
+}
+
+func (w *ndjsonReportWriter) write(comparisonData models.ComparisonData) error {
+	return w.encoder.Encode(jsonExportedComparisonData{columns: w.columns, comparisonData: comparisonData})
+}
+
+func (w *ndjsonReportWriter) flush() error {
+	return nil
+}
+
+func (w *ndjsonReportWriter) writeEnd() error {
+	return nil
+}
+
+func (w *ndjsonReportWriter) close() error {
+	return nil
+}
@@ -0,0 +1,403 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+const (
+	pdfPageWidth    = 297.0
+	pdfMargin       = 15.0
+	pdfLineHeight   = 4.0
+	pdfMaxCellLines = 10
+	pdfSummaryPage  = 2
+	pdfTimeFormat   = "2006-01-02 15:04 MST"
+)
+
+// pdfColumn is a column of the discrepancy table, widths are in millimetres.
+type pdfColumn struct {
+	column models.ExportColumn
+	header string
+	width  float64
+}
+
+// pdfColumns are the columns of the discrepancy table in table order, the widths of the default columns add up to the
+// width of a landscape A4 page inside its margins.
+var pdfColumns = []pdfColumn{
+	{column: models.ExportColumnLocation, header: "Location", width: 35},
+	{column: models.ExportColumnDiscrepancyChange, header: "Change", width: 22},
+	{column: models.ExportColumnPreviousResult, header: "Previous result", width: 45},
+	{column: models.ExportColumnResult, header: "Result", width: 45},
+	{column: models.ExportColumnExpectedBarcodes, header: "Expected", width: 47},
+	{column: models.ExportColumnActualBarcodes, header: "Actual", width: 47},
+	{column: models.ExportColumnMatchedBarcodes, header: "Matched", width: 47},
+	{column: models.ExportColumnMissingBarcodes, header: "Missing", width: 47},
+	{column: models.ExportColumnUnexpectedBarcodes, header: "Unexpected", width: 46},
+	{column: models.ExportColumnScanned, header: "Scanned", width: 18},
+	{column: models.ExportColumnOccupied, header: "Occupied", width: 18},
+}
+
+// pdfDefaultColumns are the columns of the table when the export does not select columns.
+var pdfDefaultColumns = []models.ExportColumn{
+	models.ExportColumnLocation,
+	models.ExportColumnResult,
+	models.ExportColumnExpectedBarcodes,
+	models.ExportColumnActualBarcodes,
+	models.ExportColumnMissingBarcodes,
+	models.ExportColumnUnexpectedBarcodes,
+}
+
+// newPDFColumns returns the table columns of the selected columns, widened or narrowed to fill the page width.
+func newPDFColumns(selected []models.ExportColumn) []pdfColumn {
+	if len(selected) == 0 {
+		selected = pdfDefaultColumns
+	}
+
+	var columns []pdfColumn
+	totalWidth := 0.0
+	for _, column := range pdfColumns {
+		for _, selectedColumn := range selected {
+			if column.column == selectedColumn {
+				columns = append(columns, column)
+				totalWidth += column.width
+				break
+			}
+		}
+	}
+
+	for i := range columns {
+		columns[i].width = columns[i].width * (pdfPageWidth - 2*pdfMargin) / totalWidth
+	}
+
+	return columns
+}
+
+func pdfColumnValue(column models.ExportColumn, comparisonData models.ComparisonData) string {
+	switch value := reportColumnValue(column, comparisonData).(type) {
+	case bool:
+		if value {
+			return "yes"
+		}
+		return "no"
+	case []string:
+		return strings.Join(value, ", ")
+	default:
+		if column == models.ExportColumnResult {
+			return outcomeLabels[comparisonData.Result]
+		}
+		if column == models.ExportColumnPreviousResult {
+			return outcomeLabels[comparisonData.PreviousResult]
+		}
+		return formatReportColumnValue(column, comparisonData, "")
+	}
+}
+
+// pdfReportWriter writes the report as a PDF for site managers. The cover page lists the metadata of the report,
+// the second page has the outcome summary table and chart and the discrepancy table follows over as many pages as
+// it needs. Locations that matched the expected inventory are left out of the table unless includeMatched is set or
+// their discrepancy was resolved since the baseline report, the table lists the selected columns or the default ones,
+// with the change of the discrepancy and the previous result when there is a baseline report.
+// The summary is only known once every location is written, its page is laid out at the start and drawn at the end.
+// The document is kept in memory until it is written to the file at the end.
+type pdfReportWriter struct {
+	file                   io.Writer
+	pdf                    *fpdf.Fpdf
+	translate              func(string) string
+	reportRecord           *models.ReportRecord
+	baselineReportRecordID *uint
+	columns                []pdfColumn
+	exportedAt             time.Time
+	includeMatched         bool
+	summary                models.ReportSummary
+	changes                map[models.DiscrepancyChange]int64
+	rowCount               int
+}
+
+func newPDFReportWriter(file io.Writer, reportRecord *models.ReportRecord, baselineReportRecordID *uint, columns []models.ExportColumn, includeMatched bool, exportedAt time.Time) *pdfReportWriter {
+	if len(columns) == 0 && baselineReportRecordID != nil {
+		columns = append(append([]models.ExportColumn{}, pdfDefaultColumns...), models.ReportDiffColumns...)
+	}
+
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	// rows are never split, the table breaks its pages itself
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.SetCreationDate(exportedAt)
+	pdf.AliasNbPages("")
+
+	return &pdfReportWriter{
+		file: file,
+		pdf:  pdf,
+		// core fonts are encoded as cp1252, locations and barcodes are UTF-8
+		translate:              pdf.UnicodeTranslatorFromDescriptor(""),
+		reportRecord:           reportRecord,
+		baselineReportRecordID: baselineReportRecordID,
+		columns:                newPDFColumns(columns),
+		exportedAt:             exportedAt,
+		includeMatched:         includeMatched,
+		changes:                make(map[models.DiscrepancyChange]int64),
+	}
+}
+
+func (w *pdfReportWriter) writeStart() error {
+	labels := summaryLabelsOf(w.reportRecord.Mode)
+	w.pdf.SetTitle(fmt.Sprintf("%s %d", labels.title, w.reportRecord.ID), true)
+	w.pdf.SetFooterFunc(func() {
+		w.pdf.SetY(-pdfMargin + 5)
+		w.pdf.SetFont("Helvetica", "", 8)
+		w.pdf.SetTextColor(100, 100, 100)
+		w.pdf.CellFormat(0, pdfLineHeight, fmt.Sprintf("%s %d - page %d of {nb}", labels.title, w.reportRecord.ID, w.pdf.PageNo()), "", 0, "C", false, 0, "")
+		w.pdf.SetTextColor(0, 0, 0)
+	})
+
+	w.writeCover()
+
+	// left blank until the summary is known
+	w.pdf.AddPage()
+
+	w.pdf.AddPage()
+	w.writeHeading(w.tableTitle())
+	w.writeTableHeader()
+
+	return w.pdf.Error()
+}
+
+func (w *pdfReportWriter) writeCover() {
+	w.pdf.AddPage()
+	w.pdf.SetY(50)
+	w.pdf.SetFont("Helvetica", "B", 24)
+	w.pdf.CellFormat(0, 12, summaryLabelsOf(w.reportRecord.Mode).title, "", 1, "L", false, 0, "")
+	w.pdf.SetFont("Helvetica", "", 14)
+	w.pdf.CellFormat(0, 10, fmt.Sprintf("Report %d", w.reportRecord.ID), "", 1, "L", false, 0, "")
+	w.pdf.Ln(10)
+
+	metadata := [][2]string{{"Bulk scan file", w.reportRecord.BulkScanRecord.FileName}}
+	if w.reportRecord.BaselineBulkScanRecord != nil {
+		metadata = append(metadata, [2]string{"Baseline bulk scan file", w.reportRecord.BaselineBulkScanRecord.FileName})
+	}
+	if w.reportRecord.Mode != models.ScanDiffReport {
+		metadata = append(metadata, [2]string{"Reference file", w.reportRecord.ReferenceFileName})
+	}
+	if w.reportRecord.ReferenceSheet != "" {
+		metadata = append(metadata, [2]string{"Reference sheet", w.reportRecord.ReferenceSheet})
+	}
+	if w.reportRecord.ColumnMappingProfile != nil {
+		metadata = append(metadata, [2]string{"Column mapping profile", w.reportRecord.ColumnMappingProfile.Name})
+	}
+	if w.baselineReportRecordID != nil {
+		metadata = append(metadata, [2]string{"Compared against", fmt.Sprintf("Report %d", *w.baselineReportRecordID)})
+	}
+	metadata = append(metadata,
+		[2]string{"Bulk scan uploaded", formatPDFTime(w.reportRecord.BulkScanRecord.CreatedAt)},
+		[2]string{"Report created", formatPDFTime(w.reportRecord.CreatedAt)},
+		[2]string{"Report completed", formatPDFTime(w.reportRecord.UpdatedAt)},
+		[2]string{"Exported", formatPDFTime(w.exportedAt)},
+	)
+
+	for _, field := range metadata {
+		w.pdf.SetFont("Helvetica", "B", 11)
+		w.pdf.CellFormat(55, 8, field[0], "", 0, "L", false, 0, "")
+		w.pdf.SetFont("Helvetica", "", 11)
+		w.pdf.CellFormat(0, 8, w.translate(field[1]), "", 1, "L", false, 0, "")
+	}
+}
+
+func (w *pdfReportWriter) write(comparisonData models.ComparisonData) error {
+	addToSummary(&w.summary, w.changes, comparisonData)
+	if comparisonData.Result.IsMatch() && !w.includeMatched && comparisonData.DiscrepancyChange() != models.DiscrepancyResolved {
+		return nil
+	}
+
+	w.pdf.SetFont("Helvetica", "", 8)
+	cells := make([][]string, len(w.columns))
+	lineCount := 1
+	for i, column := range w.columns {
+		cells[i] = w.splitCell(pdfColumnValue(column.column, comparisonData), column.width)
+		if len(cells[i]) > lineCount {
+			lineCount = len(cells[i])
+		}
+	}
+	height := float64(lineCount)*pdfLineHeight + 2
+
+	_, pageHeight := w.pdf.GetPageSize()
+	if w.pdf.GetY()+height > pageHeight-pdfMargin {
+		w.pdf.AddPage()
+		w.writeTableHeader()
+		w.pdf.SetFont("Helvetica", "", 8)
+	}
+
+	x, y := w.pdf.GetXY()
+	for i, column := range w.columns {
+		w.pdf.Rect(x, y, column.width, height, "D")
+		for j, line := range cells[i] {
+			w.pdf.SetXY(x, y+1+float64(j)*pdfLineHeight)
+			w.pdf.CellFormat(column.width, pdfLineHeight, line, "", 0, "L", false, 0, "")
+		}
+		x += column.width
+	}
+	w.pdf.SetXY(pdfMargin, y+height)
+	w.rowCount++
+
+	return w.pdf.Error()
+}
+
+// splitCell wraps the text of a cell to the column width. Text beyond the line limit of a cell is cut off, so a
+// single location never outgrows a page.
+func (w *pdfReportWriter) splitCell(text string, width float64) []string {
+	if text == "" {
+		return nil
+	}
+
+	lines := w.pdf.SplitText(w.translate(text), width)
+	if len(lines) > pdfMaxCellLines {
+		lines = append(lines[:pdfMaxCellLines-1], fmt.Sprintf("... %d more lines", len(lines)-pdfMaxCellLines+1))
+	}
+
+	return lines
+}
+
+func (w *pdfReportWriter) flush() error {
+	return nil
+}
+
+func (w *pdfReportWriter) writeEnd() error {
+	if w.rowCount == 0 {
+		w.pdf.SetFont("Helvetica", "I", 10)
+		w.pdf.CellFormat(0, 10, "No locations to list.", "", 1, "L", false, 0, "")
+	}
+
+	// the footer of the last page is drawn when the document is closed, so the last page is made current again
+	lastPage := w.pdf.PageNo()
+	w.pdf.SetPage(pdfSummaryPage)
+	w.writeSummary()
+	w.pdf.SetPage(lastPage)
+
+	if err := w.pdf.Error(); err != nil {
+		return err
+	}
+
+	return w.pdf.Output(w.file)
+}
+
+func (w *pdfReportWriter) close() error {
+	return nil
+}
+
+// writeSummary writes the table with the number of locations of every outcome, the accuracy of the report and a bar
+// chart of the outcomes.
+func (w *pdfReportWriter) writeSummary() {
+	w.pdf.SetXY(pdfMargin, pdfMargin)
+	w.writeHeading("Summary")
+
+	w.pdf.SetFont("Helvetica", "B", 9)
+	w.pdf.SetFillColor(230, 230, 230)
+	w.pdf.CellFormat(170, 6, "Result", "1", 0, "L", true, 0, "")
+	w.pdf.CellFormat(30, 6, "Locations", "1", 0, "R", true, 0, "")
+	w.pdf.CellFormat(30, 6, "Share (%)", "1", 1, "R", true, 0, "")
+
+	w.pdf.SetFont("Helvetica", "", 9)
+	for _, outcome := range w.reportRecord.Mode.Outcomes() {
+		w.pdf.CellFormat(170, 6, string(outcome), "1", 0, "L", false, 0, "")
+		w.pdf.CellFormat(30, 6, fmt.Sprintf("%d", w.summary.Counts[outcome]), "1", 0, "R", false, 0, "")
+		w.pdf.CellFormat(30, 6, fmt.Sprintf("%.2f", w.summary.Share(outcome)), "1", 1, "R", false, 0, "")
+	}
+
+	w.pdf.SetFont("Helvetica", "B", 9)
+	w.pdf.CellFormat(170, 6, "Total locations", "1", 0, "L", false, 0, "")
+	w.pdf.CellFormat(30, 6, fmt.Sprintf("%d", w.summary.Total), "1", 0, "R", false, 0, "")
+	w.pdf.CellFormat(30, 6, "", "1", 1, "R", false, 0, "")
+	w.pdf.Ln(3)
+
+	w.pdf.SetFont("Helvetica", "B", 11)
+	labels := summaryLabelsOf(w.reportRecord.Mode)
+	w.pdf.CellFormat(0, 7, fmt.Sprintf("%s: %.2f%% (%d of %d locations %s, %d %s)", labels.accuracy,
+		w.summary.Accuracy(), w.summary.Matched, w.summary.Total, labels.matched, w.summary.Discrepancies(), labels.discrepancies), "", 1, "L", false, 0, "")
+	if w.baselineReportRecordID != nil {
+		w.pdf.CellFormat(0, 7, fmt.Sprintf("Compared against report %d: %d resolved, %d persistent and %d new discrepancies",
+			*w.baselineReportRecordID, w.changes[models.DiscrepancyResolved], w.changes[models.DiscrepancyPersistent],
+			w.changes[models.DiscrepancyNew]), "", 1, "L", false, 0, "")
+	}
+	w.pdf.Ln(4)
+
+	w.writeSummaryChart()
+}
+
+// writeSummaryChart draws a horizontal bar per outcome, bars are scaled to the outcome with the most locations.
+func (w *pdfReportWriter) writeSummaryChart() {
+	const labelWidth = 55.0
+	const barAreaWidth = 180.0
+	const barHeight = 4.0
+	const barSpacing = 1.5
+
+	maxCount := int64(0)
+	for _, count := range w.summary.Counts {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	w.pdf.SetFont("Helvetica", "", 8)
+	y := w.pdf.GetY()
+	for _, outcome := range w.reportRecord.Mode.Outcomes() {
+		count := w.summary.Counts[outcome]
+
+		w.pdf.SetXY(pdfMargin, y)
+		w.pdf.CellFormat(labelWidth, barHeight, outcomeLabels[outcome], "", 0, "R", false, 0, "")
+
+		barWidth := 0.0
+		if maxCount > 0 {
+			barWidth = barAreaWidth * float64(count) / float64(maxCount)
+		}
+		if outcome.IsMatch() {
+			w.pdf.SetFillColor(46, 139, 87)
+		} else {
+			w.pdf.SetFillColor(204, 85, 0)
+		}
+		if barWidth > 0 {
+			w.pdf.Rect(pdfMargin+labelWidth+2, y, barWidth, barHeight, "F")
+		}
+
+		w.pdf.SetXY(pdfMargin+labelWidth+2+barWidth, y)
+		w.pdf.CellFormat(20, barHeight, fmt.Sprintf("%d", count), "", 0, "L", false, 0, "")
+
+		y += barHeight + barSpacing
+	}
+}
+
+func (w *pdfReportWriter) writeHeading(heading string) {
+	w.pdf.SetFont("Helvetica", "B", 16)
+	w.pdf.CellFormat(0, 10, heading, "", 1, "L", false, 0, "")
+	w.pdf.Ln(2)
+}
+
+func (w *pdfReportWriter) tableTitle() string {
+	if w.includeMatched {
+		return "Locations"
+	}
+
+	return "Discrepancies"
+}
+
+// writeTableHeader starts the discrepancy table, it is repeated on every page of the table.
+func (w *pdfReportWriter) writeTableHeader() {
+	w.pdf.SetFont("Helvetica", "B", 9)
+	w.pdf.SetFillColor(230, 230, 230)
+	for _, column := range w.columns {
+		w.pdf.CellFormat(column.width, 7, column.header, "1", 0, "L", true, 0, "")
+	}
+	w.pdf.Ln(-1)
+}
+
+func formatPDFTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.UTC().Format(pdfTimeFormat)
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// reportColumns returns the columns selected by the filter in the order of its available columns, every available
+// column when the filter selects none.
+func reportColumns(filter models.ExportFilter) []models.ExportColumn {
+	if len(filter.Columns) == 0 {
+		return filter.AvailableColumns()
+	}
+
+	var columns []models.ExportColumn
+	for _, column := range filter.AvailableColumns() {
+		for _, selected := range filter.Columns {
+			if string(column) == selected {
+				columns = append(columns, column)
+				break
+			}
+		}
+	}
+
+	return columns
+}
+
+// reportColumnValue returns the value of a column of the comparison data, flags are booleans and barcode lists are
+// string slices.
+func reportColumnValue(column models.ExportColumn, comparisonData models.ComparisonData) interface{} {
+	switch column {
+	case models.ExportColumnLocation:
+		return comparisonData.Location
+	case models.ExportColumnScanned:
+		return comparisonData.Scanned
+	case models.ExportColumnOccupied:
+		return comparisonData.Occupied
+	case models.ExportColumnActualBarcodes:
+		return []string(comparisonData.ActualBarcodes)
+	case models.ExportColumnExpectedBarcodes:
+		return []string(comparisonData.ExpectedBarcodes)
+	case models.ExportColumnMatchedBarcodes:
+		return []string(comparisonData.MatchedBarcodes)
+	case models.ExportColumnMissingBarcodes:
+		return []string(comparisonData.MissingBarcodes)
+	case models.ExportColumnUnexpectedBarcodes:
+		return []string(comparisonData.UnexpectedBarcodes)
+	case models.ExportColumnResult:
+		return string(comparisonData.Result)
+	case models.ExportColumnPreviousResult:
+		return string(comparisonData.PreviousResult)
+	case models.ExportColumnDiscrepancyChange:
+		return string(comparisonData.DiscrepancyChange())
+	default:
+		return nil
+	}
+}
+
+// formatReportColumnValue returns the value of a column as text, barcodes are joined by the barcode separator.
+func formatReportColumnValue(column models.ExportColumn, comparisonData models.ComparisonData, barcodeSeparator string) string {
+	switch value := reportColumnValue(column, comparisonData).(type) {
+	case bool:
+		return strconv.FormatBool(value)
+	case []string:
+		return strings.Join(value, barcodeSeparator)
+	case string:
+		return value
+	default:
+		return ""
+	}
+}
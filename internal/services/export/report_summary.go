@@ -0,0 +1,61 @@
+package export
+
+import (
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// outcomeLabels are short names of the outcomes for sheet names, tables and charts, XLSX sheet names are limited
+// to 31 characters.
+var outcomeLabels = map[models.ScanComparisonOutcome]string{
+	models.LocationEmptyAsExpected:                 "Empty as expected",
+	models.LocationOccupiedWithCorrectItems:        "Expected items",
+	models.LocationEmptyButNotExpected:             "Empty but expected occupied",
+	models.LocationOccupiedWithWrongItems:          "Wrong items",
+	models.LocationOccupiedButExpectedEmpty:        "Occupied but expected empty",
+	models.LocationOccupiedButBarcodeNotIdentified: "Barcode not identified",
+	models.LocationNotScanned:                      "Not scanned",
+	models.LocationMissingFromRobotData:            "Missing from robot data",
+	models.LocationNotInExpectedInventory:          "Not in expected inventory",
+	models.LocationMissingExpectedItems:            "Missing expected items",
+	models.LocationOccupiedWithUnexpectedItems:     "Unexpected items",
+	models.LocationOccupiedWithSomeExpectedItems:   "Some expected items",
+	models.LocationUnchanged:                       "Unchanged",
+	models.LocationNewlyOccupied:                   "Newly occupied",
+	models.LocationNewlyEmptied:                    "Newly emptied",
+	models.LocationBarcodeChanged:                  "Barcode changed",
+	models.LocationNewlyUnreadable:                 "Newly unreadable",
+	models.LocationNewlyReadable:                   "Newly readable",
+	models.LocationAdded:                           "Added location",
+	models.LocationRemoved:                         "Removed location",
+}
+
+// summaryLabels name the matched locations, the discrepancies and the accuracy in the summary of a report. The
+// locations of a scan diff match when they did not change.
+type summaryLabels struct {
+	title         string
+	matched       string
+	discrepancies string
+	accuracy      string
+}
+
+func summaryLabelsOf(mode models.ReportMode) summaryLabels {
+	if mode == models.ScanDiffReport {
+		return summaryLabels{title: "Scan diff report", matched: "unchanged", discrepancies: "changes", accuracy: "Unchanged"}
+	}
+
+	return summaryLabels{title: "Inventory comparison report", matched: "matched", discrepancies: "discrepancies", accuracy: "Accuracy"}
+}
+
+// addToSummary counts an exported location in the summary of the report, and in the count of its discrepancy change
+// when the report is compared against a baseline report. A location only the baseline report has counts towards its
+// change alone.
+func addToSummary(summary *models.ReportSummary, changes map[models.DiscrepancyChange]int64, comparisonData models.ComparisonData) {
+	if change := comparisonData.DiscrepancyChange(); change != "" {
+		changes[change]++
+	}
+	if comparisonData.Result == "" {
+		return
+	}
+
+	summary.Add(comparisonData.Result, comparisonData.Scanned, comparisonData.Occupied, 1)
+}
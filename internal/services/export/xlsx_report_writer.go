@@ -0,0 +1,301 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+const (
+	xlsxSummarySheet = "Summary"
+	xlsxDetailSheet  = "Details"
+	// xlsxBarcodeSeparator joins the barcodes of a location into one cell, unlike CSV a cell is never re-parsed
+	xlsxBarcodeSeparator = ", "
+)
+
+var xlsxColumnWidths = map[models.ExportColumn]float64{
+	models.ExportColumnLocation:           20,
+	models.ExportColumnScanned:            10,
+	models.ExportColumnOccupied:           10,
+	models.ExportColumnActualBarcodes:     30,
+	models.ExportColumnExpectedBarcodes:   30,
+	models.ExportColumnMatchedBarcodes:    30,
+	models.ExportColumnMissingBarcodes:    30,
+	models.ExportColumnUnexpectedBarcodes: 30,
+	models.ExportColumnResult:             60,
+	models.ExportColumnPreviousResult:     60,
+	models.ExportColumnDiscrepancyChange:  15,
+}
+
+// xlsxDataSheet is a sheet listing comparison data, rows are streamed into it as they are written.
+type xlsxDataSheet struct {
+	name        string
+	tableName   string
+	writer      *excelize.StreamWriter
+	rowNumber   int
+	columnCount int
+}
+
+// xlsxReportWriter writes the report as an XLSX workbook. The summary sheet counts the locations of every outcome,
+// the details sheet lists every location and each discrepancy outcome has a sheet with only its locations, only the
+// outcomes of the results filter when the export is filtered by result. The summary of a report compared against a
+// baseline report also counts the resolved, persistent and new discrepancies. Rows are streamed, the workbook keeps
+// them in temporary files once they outgrow its memory buffer, and the workbook is written to the file at the end.
+type xlsxReportWriter struct {
+	file                   io.Writer
+	workbook               *excelize.File
+	columns                []models.ExportColumn
+	results                []string
+	mode                   models.ReportMode
+	baselineReportRecordID *uint
+	detailSheet            *xlsxDataSheet
+	outcomeSheets          map[models.ScanComparisonOutcome]*xlsxDataSheet
+	summary                models.ReportSummary
+	changes                map[models.DiscrepancyChange]int64
+	headerStyle            int
+}
+
+func newXLSXReportWriter(file io.Writer, columns []models.ExportColumn, results []string, mode models.ReportMode, baselineReportRecordID *uint) *xlsxReportWriter {
+	return &xlsxReportWriter{
+		file:                   file,
+		workbook:               excelize.NewFile(),
+		columns:                columns,
+		results:                results,
+		mode:                   mode,
+		baselineReportRecordID: baselineReportRecordID,
+		outcomeSheets:          make(map[models.ScanComparisonOutcome]*xlsxDataSheet),
+		changes:                make(map[models.DiscrepancyChange]int64),
+	}
+}
+
+func (w *xlsxReportWriter) writeStart() error {
+	// a new workbook has a single sheet, it becomes the summary so the summary is the sheet shown on opening
+	defaultSheet := w.workbook.GetSheetName(0)
+	if err := w.workbook.SetSheetName(defaultSheet, xlsxSummarySheet); err != nil {
+		return err
+	}
+
+	var err error
+	w.headerStyle, err = w.workbook.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	w.detailSheet, err = w.newDataSheet(xlsxDetailSheet, "Details")
+	if err != nil {
+		return err
+	}
+
+	// every discrepancy outcome gets its sheet, even without locations, so the workbook layout never changes
+	for i, outcome := range w.mode.Outcomes() {
+		if outcome.IsMatch() || !w.isSelectedResult(outcome) {
+			continue
+		}
+
+		w.outcomeSheets[outcome], err = w.newDataSheet(outcomeLabels[outcome], fmt.Sprintf("Outcome%d", i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newDataSheet adds a sheet with a frozen header row.
+func (w *xlsxReportWriter) newDataSheet(name string, tableName string) (*xlsxDataSheet, error) {
+	if _, err := w.workbook.NewSheet(name); err != nil {
+		return nil, err
+	}
+
+	writer, err := w.workbook.NewStreamWriter(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, column := range w.columns {
+		if err := writer.SetColWidth(i+1, i+1, xlsxColumnWidths[column]); err != nil {
+			return nil, err
+		}
+	}
+
+	err = writer.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]interface{}, len(w.columns))
+	for i, column := range w.columns {
+		headers[i] = string(column)
+	}
+
+	sheet := &xlsxDataSheet{name: name, tableName: tableName, writer: writer, columnCount: len(w.columns)}
+	if err := sheet.writeRow(headers, excelize.RowOpts{StyleID: w.headerStyle}); err != nil {
+		return nil, err
+	}
+
+	return sheet, nil
+}
+
+func (w *xlsxReportWriter) write(comparisonData models.ComparisonData) error {
+	row := make([]interface{}, len(w.columns))
+	for i, column := range w.columns {
+		// flags stay booleans so spreadsheet tools filter them as TRUE and FALSE
+		if value, ok := reportColumnValue(column, comparisonData).(bool); ok {
+			row[i] = value
+		} else {
+			row[i] = formatReportColumnValue(column, comparisonData, xlsxBarcodeSeparator)
+		}
+	}
+
+	addToSummary(&w.summary, w.changes, comparisonData)
+
+	if err := w.detailSheet.writeRow(row); err != nil {
+		return err
+	}
+
+	if outcomeSheet, ok := w.outcomeSheets[comparisonData.Result]; ok {
+		return outcomeSheet.writeRow(row)
+	}
+
+	return nil
+}
+
+func (w *xlsxReportWriter) isSelectedResult(outcome models.ScanComparisonOutcome) bool {
+	if len(w.results) == 0 {
+		return true
+	}
+
+	for _, result := range w.results {
+		if result == string(outcome) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *xlsxReportWriter) flush() error {
+	return nil
+}
+
+func (w *xlsxReportWriter) writeEnd() error {
+	dataSheets := []*xlsxDataSheet{w.detailSheet}
+	for _, outcome := range w.mode.Outcomes() {
+		if outcomeSheet, ok := w.outcomeSheets[outcome]; ok {
+			dataSheets = append(dataSheets, outcomeSheet)
+		}
+	}
+
+	for _, sheet := range dataSheets {
+		if err := sheet.end(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.writeSummary(); err != nil {
+		return err
+	}
+
+	return w.workbook.Write(w.file)
+}
+
+func (w *xlsxReportWriter) close() error {
+	// removes the temporary files of the streamed rows
+	return w.workbook.Close()
+}
+
+// writeSummary writes the number of locations of every outcome and the accuracy of the report, the share of
+// locations that matched the expected inventory.
+func (w *xlsxReportWriter) writeSummary() error {
+	writer, err := w.workbook.NewStreamWriter(xlsxSummarySheet)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.SetColWidth(1, 1, 90); err != nil {
+		return err
+	}
+	if err := writer.SetColWidth(2, 3, 15); err != nil {
+		return err
+	}
+
+	rows := [][]interface{}{{"result", "locations", "share (%)"}}
+	for _, outcome := range w.mode.Outcomes() {
+		rows = append(rows, []interface{}{string(outcome), w.summary.Counts[outcome], w.summary.Share(outcome)})
+	}
+	labels := summaryLabelsOf(w.mode)
+	rows = append(rows,
+		nil,
+		[]interface{}{"total locations", w.summary.Total},
+		[]interface{}{labels.matched + " locations", w.summary.Matched},
+		[]interface{}{labels.discrepancies, w.summary.Discrepancies()},
+		[]interface{}{strings.ToLower(labels.accuracy) + " (%)", w.summary.Accuracy()},
+	)
+	if w.baselineReportRecordID != nil {
+		rows = append(rows, nil, []interface{}{fmt.Sprintf("compared against report %d", *w.baselineReportRecordID)})
+		for _, change := range models.DiscrepancyChanges {
+			rows = append(rows, []interface{}{fmt.Sprintf("%s discrepancies", change), w.changes[change]})
+		}
+	}
+
+	for i, row := range rows {
+		var opts []excelize.RowOpts
+		if i == 0 {
+			opts = append(opts, excelize.RowOpts{StyleID: w.headerStyle})
+		}
+
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := writer.SetRow(cell, row, opts...); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (s *xlsxDataSheet) writeRow(row []interface{}, opts ...excelize.RowOpts) error {
+	s.rowNumber++
+
+	cell, err := excelize.CoordinatesToCellName(1, s.rowNumber)
+	if err != nil {
+		return err
+	}
+
+	return s.writer.SetRow(cell, row, opts...)
+}
+
+// end adds an autofilter over the rows of the sheet, through a table spanning them, and flushes the sheet.
+func (s *xlsxDataSheet) end() error {
+	lastCell, err := excelize.CoordinatesToCellName(s.columnCount, s.rowNumber)
+	if err != nil {
+		return err
+	}
+
+	err = s.writer.AddTable(&excelize.Table{
+		Range:          "A1:" + lastCell,
+		Name:           s.tableName,
+		StyleName:      "TableStyleLight1",
+		ShowRowStripes: boolPtr(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.writer.Flush()
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
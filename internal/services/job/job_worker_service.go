@@ -0,0 +1,209 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/habbas99/dexory/internal"
+	"github.com/habbas99/dexory/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// JobHandler processes the record referenced by a job. Returning an error schedules a retry until the
+// job runs out of attempts, an internal.PermanentError fails the job straight away.
+type JobHandler func(recordID uint) error
+
+type JobWorkerConfig struct {
+	WorkerID     string
+	Concurrency  int
+	PollInterval time.Duration
+	LeaseTimeout time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+type jobClient interface {
+	Claim(workerID string, leaseTimeout time.Duration, maxAttempts int) (*models.Job, error)
+	Heartbeat(job *models.Job) error
+	Complete(job *models.Job) error
+	Retry(job *models.Job, runAt time.Time, lastError string) error
+	Fail(job *models.Job, lastError string) error
+}
+
+type JobWorkerService struct {
+	jobClient jobClient
+	config    JobWorkerConfig
+	handlers  map[models.JobType]JobHandler
+}
+
+func NewJobWorkerService(jobClient jobClient, config JobWorkerConfig) *JobWorkerService {
+	return &JobWorkerService{
+		jobClient: jobClient,
+		config:    config,
+		handlers:  map[models.JobType]JobHandler{},
+	}
+}
+
+func (jw *JobWorkerService) RegisterHandler(jobType models.JobType, handler JobHandler) {
+	jw.handlers[jobType] = handler
+}
+
+// Run starts the configured number of workers and blocks until the context is cancelled and every
+// in-flight job has finished.
+func (jw *JobWorkerService) Run(ctx context.Context) {
+	log.WithFields(log.Fields{
+		"worker_id":   jw.config.WorkerID,
+		"concurrency": jw.config.Concurrency,
+	}).Info("starting job workers")
+
+	var wg sync.WaitGroup
+	for i := 0; i < jw.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jw.poll(ctx)
+		}()
+	}
+	wg.Wait()
+
+	log.WithFields(log.Fields{
+		"worker_id": jw.config.WorkerID,
+	}).Info("stopped job workers")
+}
+
+func (jw *JobWorkerService) poll(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		processed := jw.processNextJob()
+		if processed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(jw.config.PollInterval):
+		}
+	}
+}
+
+// processNextJob claims and runs a single job, returning false when there was nothing to claim.
+func (jw *JobWorkerService) processNextJob() bool {
+	job, err := jw.jobClient.Claim(jw.config.WorkerID, jw.config.LeaseTimeout, jw.config.MaxAttempts)
+	if err != nil {
+		log.Errorf("failed to claim job: %v", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	log.WithFields(log.Fields{
+		"job_id":    job.ID,
+		"job_type":  job.Type,
+		"record_id": job.RecordID,
+		"attempt":   job.Attempts,
+	}).Info("claimed job")
+
+	stopHeartbeat := jw.startHeartbeat(job)
+	err = jw.runHandler(job)
+	stopHeartbeat()
+
+	if err == nil {
+		if err := jw.jobClient.Complete(job); err != nil {
+			log.Errorf("failed to mark job id=%d as completed: %v", job.ID, err)
+		}
+		return true
+	}
+
+	var permanentErr *internal.PermanentError
+	if job.Attempts >= jw.config.MaxAttempts || errors.As(err, &permanentErr) {
+		log.WithFields(log.Fields{
+			"job_id":   job.ID,
+			"job_type": job.Type,
+			"attempt":  job.Attempts,
+		}).Errorf("job failed permanently: %v", err)
+
+		if err := jw.jobClient.Fail(job, err.Error()); err != nil {
+			log.Errorf("failed to mark job id=%d as failed: %v", job.ID, err)
+		}
+		return true
+	}
+
+	runAt := time.Now().Add(jw.backoff(job.Attempts))
+	log.WithFields(log.Fields{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+		"attempt":  job.Attempts,
+		"run_at":   runAt,
+	}).Warnf("job failed, scheduling retry: %v", err)
+
+	if err := jw.jobClient.Retry(job, runAt, err.Error()); err != nil {
+		log.Errorf("failed to schedule retry for job id=%d: %v", job.ID, err)
+	}
+
+	return true
+}
+
+func (jw *JobWorkerService) runHandler(job *models.Job) (err error) {
+	handler, ok := jw.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("no handler registered for job type=%s", job.Type)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+
+	return handler(job.RecordID)
+}
+
+// startHeartbeat keeps the job lease fresh while the handler runs so other replicas do not reclaim it.
+func (jw *JobWorkerService) startHeartbeat(job *models.Job) func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(jw.config.LeaseTimeout / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := jw.jobClient.Heartbeat(job); err != nil {
+					log.Errorf("failed to refresh lease for job id=%d: %v", job.ID, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func (jw *JobWorkerService) backoff(attempts int) time.Duration {
+	delay := jw.config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= jw.config.MaxBackoff {
+			return jw.config.MaxBackoff
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,173 @@
+package job
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockjobworkerservice "github.com/habbas99/dexory/generated/services/job"
+	"github.com/habbas99/dexory/internal"
+	"github.com/habbas99/dexory/internal/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type JobWorkerServiceTestSuite struct {
+	suite.Suite
+	MockJobClient    *mockjobworkerservice.MockjobClient
+	JobWorkerService *JobWorkerService
+	ctrl             *gomock.Controller
+}
+
+func TestJobWorkerServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(JobWorkerServiceTestSuite))
+}
+
+func (suite *JobWorkerServiceTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+
+	suite.MockJobClient = mockjobworkerservice.NewMockjobClient(suite.ctrl)
+
+	suite.JobWorkerService = NewJobWorkerService(suite.MockJobClient, JobWorkerConfig{
+		WorkerID:     "worker-1",
+		Concurrency:  1,
+		PollInterval: time.Millisecond,
+		LeaseTimeout: time.Minute,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   5 * time.Second,
+	})
+}
+
+func (suite *JobWorkerServiceTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func (suite *JobWorkerServiceTestSuite) TestProcessNextJobNothingToClaim() {
+	// Given
+	suite.MockJobClient.EXPECT().Claim("worker-1", time.Minute, 3).Return(nil, nil).Times(1)
+
+	// When
+	processed := suite.JobWorkerService.processNextJob()
+
+	// Then
+	suite.False(processed)
+}
+
+func (suite *JobWorkerServiceTestSuite) TestProcessNextJobSuccess() {
+	// Given
+	job := &models.Job{Type: models.ScanIngestionJob, RecordID: uint(7), Attempts: 1}
+
+	var handledRecordID uint
+	suite.JobWorkerService.RegisterHandler(models.ScanIngestionJob, func(recordID uint) error {
+		handledRecordID = recordID
+		return nil
+	})
+
+	suite.MockJobClient.EXPECT().Claim("worker-1", time.Minute, 3).Return(job, nil).Times(1)
+	suite.MockJobClient.EXPECT().Complete(job).Return(nil).Times(1)
+
+	// When
+	processed := suite.JobWorkerService.processNextJob()
+
+	// Then
+	suite.True(processed)
+	suite.Equal(uint(7), handledRecordID)
+}
+
+func (suite *JobWorkerServiceTestSuite) TestProcessNextJobRetriesWithBackoff() {
+	// Given
+	job := &models.Job{Type: models.ComparisonDataJob, RecordID: uint(7), Attempts: 2}
+
+	suite.JobWorkerService.RegisterHandler(models.ComparisonDataJob, func(recordID uint) error {
+		return fmt.Errorf("database error")
+	})
+
+	suite.MockJobClient.EXPECT().Claim("worker-1", time.Minute, 3).Return(job, nil).Times(1)
+	suite.MockJobClient.EXPECT().Retry(job, gomock.Any(), "database error").DoAndReturn(func(_ *models.Job, runAt time.Time, _ string) error {
+		suite.WithinDuration(time.Now().Add(2*time.Second), runAt, time.Second)
+		return nil
+	}).Times(1)
+
+	// When
+	processed := suite.JobWorkerService.processNextJob()
+
+	// Then
+	suite.True(processed)
+}
+
+func (suite *JobWorkerServiceTestSuite) TestProcessNextJobFailsAfterMaxAttempts() {
+	// Given
+	job := &models.Job{Type: models.ExportReportJob, RecordID: uint(7), Attempts: 3}
+
+	suite.JobWorkerService.RegisterHandler(models.ExportReportJob, func(recordID uint) error {
+		return fmt.Errorf("database error")
+	})
+
+	suite.MockJobClient.EXPECT().Claim("worker-1", time.Minute, 3).Return(job, nil).Times(1)
+	suite.MockJobClient.EXPECT().Fail(job, "database error").Return(nil).Times(1)
+
+	// When
+	processed := suite.JobWorkerService.processNextJob()
+
+	// Then
+	suite.True(processed)
+}
+
+func (suite *JobWorkerServiceTestSuite) TestProcessNextJobFailsPermanentErrorOnFirstAttempt() {
+	// Given
+	job := &models.Job{Type: models.ScanIngestionJob, RecordID: uint(7), Attempts: 1}
+
+	suite.JobWorkerService.RegisterHandler(models.ScanIngestionJob, func(recordID uint) error {
+		return &internal.PermanentError{Err: fmt.Errorf("failed to read starting array bracket")}
+	})
+
+	suite.MockJobClient.EXPECT().Claim("worker-1", time.Minute, 3).Return(job, nil).Times(1)
+	suite.MockJobClient.EXPECT().Retry(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	suite.MockJobClient.EXPECT().Fail(job, "failed to read starting array bracket").Return(nil).Times(1)
+
+	// When
+	processed := suite.JobWorkerService.processNextJob()
+
+	// Then
+	suite.True(processed)
+}
+
+func (suite *JobWorkerServiceTestSuite) TestProcessNextJobRecoversFromPanic() {
+	// Given
+	job := &models.Job{Type: models.ExportReportJob, RecordID: uint(7), Attempts: 3}
+
+	suite.JobWorkerService.RegisterHandler(models.ExportReportJob, func(recordID uint) error {
+		panic("boom")
+	})
+
+	suite.MockJobClient.EXPECT().Claim("worker-1", time.Minute, 3).Return(job, nil).Times(1)
+	suite.MockJobClient.EXPECT().Fail(job, "job handler panicked: boom").Return(nil).Times(1)
+
+	// When
+	processed := suite.JobWorkerService.processNextJob()
+
+	// Then
+	suite.True(processed)
+}
+
+func (suite *JobWorkerServiceTestSuite) TestProcessNextJobWithoutHandler() {
+	// Given
+	job := &models.Job{Type: models.JobType("unknown"), RecordID: uint(7), Attempts: 3}
+
+	suite.MockJobClient.EXPECT().Claim("worker-1", time.Minute, 3).Return(job, nil).Times(1)
+	suite.MockJobClient.EXPECT().Fail(job, "no handler registered for job type=unknown").Return(nil).Times(1)
+
+	// When
+	processed := suite.JobWorkerService.processNextJob()
+
+	// Then
+	suite.True(processed)
+}
+
+func (suite *JobWorkerServiceTestSuite) TestBackoffIsCapped() {
+	suite.Equal(time.Second, suite.JobWorkerService.backoff(1))
+	suite.Equal(2*time.Second, suite.JobWorkerService.backoff(2))
+	suite.Equal(4*time.Second, suite.JobWorkerService.backoff(3))
+	suite.Equal(5*time.Second, suite.JobWorkerService.backoff(4))
+}
@@ -0,0 +1,62 @@
+package location
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// DefaultLocationPattern matches location codes like ZA001A, zone Z, aisle A, bay 001 and level A.
+const DefaultLocationPattern = `^(?P<zone>[A-Z])(?P<aisle>[A-Z])(?P<bay>[0-9]{3})(?P<level>[A-Z])$`
+
+// LocationGrammar breaks the location codes of a warehouse into their place in the warehouse hierarchy with a
+// regular expression. The named groups zone, aisle, bay and level of the expression are the fields of the hierarchy,
+// a warehouse without bays simply leaves out the bay group.
+type LocationGrammar struct {
+	pattern *regexp.Regexp
+	fields  map[int]models.LocationField
+}
+
+func NewLocationGrammar(pattern string) (*LocationGrammar, error) {
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid location pattern=%s, error: %w", pattern, err)
+	}
+
+	fields := map[int]models.LocationField{}
+	for index, name := range expression.SubexpNames() {
+		if name == "" {
+			continue
+		}
+
+		field := models.LocationField(name)
+		if !field.IsValid() {
+			return nil, fmt.Errorf("location pattern=%s has unknown group=%s, groups are %v", pattern, name, models.LocationFields)
+		}
+		fields[index] = field
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("location pattern=%s has no named group, groups are %v", pattern, models.LocationFields)
+	}
+
+	return &LocationGrammar{pattern: expression, fields: fields}, nil
+}
+
+// Parse returns the hierarchy of the location and whether the grammar matched it. A location the grammar does not
+// match has an empty hierarchy, so does a location that matched without any of the optional groups of the grammar.
+func (g *LocationGrammar) Parse(location string) (models.LocationHierarchy, bool) {
+	var hierarchy models.LocationHierarchy
+
+	matches := g.pattern.FindStringSubmatch(location)
+	if matches == nil {
+		return hierarchy, false
+	}
+
+	for index, field := range g.fields {
+		hierarchy.Set(field, matches[index])
+	}
+
+	return hierarchy, true
+}
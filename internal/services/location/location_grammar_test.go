@@ -0,0 +1,86 @@
+package location
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+type LocationGrammarTestSuite struct {
+	suite.Suite
+}
+
+func TestLocationGrammarTestSuite(t *testing.T) {
+	suite.Run(t, new(LocationGrammarTestSuite))
+}
+
+func (suite *LocationGrammarTestSuite) TestParseWithDefaultPattern() {
+	// Given
+	grammar, err := NewLocationGrammar(DefaultLocationPattern)
+	suite.NoError(err)
+
+	// When
+	hierarchy, parsed := grammar.Parse("ZA001A")
+
+	// Then
+	suite.True(parsed)
+	suite.Equal(models.LocationHierarchy{Zone: "Z", Aisle: "A", Bay: "001", Level: "A"}, hierarchy)
+}
+
+func (suite *LocationGrammarTestSuite) TestParseWithPatternWithoutBay() {
+	// Given
+	grammar, err := NewLocationGrammar(`^(?P<zone>[A-Z]+)-(?P<aisle>\d+)-(?:\d+)-(?P<level>\d+)$`)
+	suite.NoError(err)
+
+	// When
+	hierarchy, parsed := grammar.Parse("COLD-12-004-3")
+
+	// Then
+	suite.True(parsed)
+	suite.Equal(models.LocationHierarchy{Zone: "COLD", Aisle: "12", Level: "3"}, hierarchy)
+}
+
+func (suite *LocationGrammarTestSuite) TestParseLocationMatchingPatternWithoutOptionalGroups() {
+	// Given
+	grammar, err := NewLocationGrammar(`^(?:(?P<zone>[A-Z]+)-)?(?:(?P<aisle>\d+)-)?BIN\d+$`)
+	suite.NoError(err)
+
+	// When
+	hierarchy, parsed := grammar.Parse("BIN7")
+
+	// Then
+	suite.True(parsed)
+	suite.Equal(models.LocationHierarchy{}, hierarchy)
+}
+
+func (suite *LocationGrammarTestSuite) TestParseLocationNotMatchingPattern() {
+	// Given
+	grammar, err := NewLocationGrammar(DefaultLocationPattern)
+	suite.NoError(err)
+
+	// When
+	hierarchy, parsed := grammar.Parse("DOCK-1")
+
+	// Then
+	suite.False(parsed)
+	suite.Equal(models.LocationHierarchy{}, hierarchy)
+}
+
+func (suite *LocationGrammarTestSuite) TestNewLocationGrammarWithInvalidPattern() {
+	patterns := map[string]string{
+		`^(?P<zone>[A-Z]`:              "invalid location pattern",
+		`^(?P<row>[A-Z])(?P<bay>\d+)$`: "unknown group=row",
+		`^[A-Z]\d+$`:                   "has no named group",
+	}
+
+	for pattern, message := range patterns {
+		// When
+		grammar, err := NewLocationGrammar(pattern)
+
+		// Then
+		suite.Nil(grammar)
+		suite.ErrorContains(err, message, pattern)
+	}
+}
@@ -2,17 +2,32 @@ package scan
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"time"
 
+	"github.com/habbas99/dexory/internal"
 	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/services/location"
 )
 
 type ScanService struct {
 	bulkScanRecordClient bulkScanRecordClient
 	scanClient           scanClient
-	batchSize            int
+	fileStorageClient    fileStorageClient
+	locationParser       locationParser
+	config               ScanServiceConfig
+}
+
+type ScanServiceConfig struct {
+	// BatchSize is the number of scans copied into the database at once
+	BatchSize int
+	// ValidationPolicy is applied to bulk scan records uploaded without a policy of their own
+	ValidationPolicy models.ValidationPolicy
+	// ValidationReportDirPath is where the validation reports of files with invalid records are written
+	ValidationReportDirPath string
 }
 
 type fileScanData struct {
@@ -23,35 +38,71 @@ type fileScanData struct {
 }
 
 type scanClient interface {
-	CreateAll(scans []models.Scan) error
+	CopyAllInTransaction(bulkScanRecordID uint, fn func(copyAll func(scans []models.Scan) (int64, error)) error) error
+}
+
+type fileStorageClient interface {
+	CreateFile(dirPath, fileName string) (*os.File, error)
+}
+
+type locationParser interface {
+	Parse(location string) (models.LocationHierarchy, bool)
 }
 
 type bulkScanRecordClient interface {
+	Get(bulkScanRecordID uint) (*models.BulkScanRecord, error)
 	Update(bulkScanRecord *models.BulkScanRecord) error
 }
 
-func NewScanService(bulkScanRecordClient bulkScanRecordClient, scanClient scanClient, batchSize int) *ScanService {
+func NewScanService(bulkScanRecordClient bulkScanRecordClient, scanClient scanClient, fileStorageClient fileStorageClient, locationParser locationParser, config ScanServiceConfig) *ScanService {
 	return &ScanService{
 		bulkScanRecordClient: bulkScanRecordClient,
 		scanClient:           scanClient,
-		batchSize:            batchSize,
+		fileStorageClient:    fileStorageClient,
+		locationParser:       locationParser,
+		config:               config,
+	}
+}
+
+func (s *ScanService) ProcessBulkScanRecord(bulkScanRecordID uint) error {
+	bulkScanRecord, err := s.bulkScanRecordClient.Get(bulkScanRecordID)
+	if err != nil {
+		return fmt.Errorf("failed to get bulk scan record with id=%d, error: %w", bulkScanRecordID, err)
 	}
+
+	// a job can be redelivered after a crash, never ingest the same file twice
+	if bulkScanRecord.Status == models.Completed {
+		return nil
+	}
+
+	return s.ProcessFile(bulkScanRecord)
 }
 
-func (s *ScanService) ProcessFile(bulkScanRecord *models.BulkScanRecord) {
+func (s *ScanService) ProcessFile(bulkScanRecord *models.BulkScanRecord) error {
 	log.WithFields(log.Fields{
 		"bulk_scan_record_id": bulkScanRecord.ID,
 		"file_name":           bulkScanRecord.FileName,
 		"file_path":           bulkScanRecord.FilePath,
 	}).Info("starting to process bulk scan file")
 
+	// a retried record must not keep the reason or validation report of its previous failed attempt
+	bulkScanRecord.Failure = models.Failure{}
+	bulkScanRecord.InvalidRecordCount = 0
+	bulkScanRecord.ValidationReportPath = ""
+	if bulkScanRecord.ValidationPolicy == "" {
+		bulkScanRecord.ValidationPolicy = s.config.ValidationPolicy
+	}
 	s.updateBulkScanRecord(bulkScanRecord, models.Processing)
 
+	locationParser, err := s.locationParserFor(bulkScanRecord)
+	if err != nil {
+		return s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, models.ErrorCodeInternal, "failed to compile location pattern of bulk scan", err)
+	}
+
 	filePath := bulkScanRecord.FilePath
 	file, err := os.Open(filePath)
 	if err != nil {
-		s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, fmt.Sprintf("failed to open file=%s", filePath), err)
-		return
+		return s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, models.ErrorCodeFileUnreadable, fmt.Sprintf("failed to open file=%s", filePath), err)
 	}
 	defer file.Close()
 
@@ -60,26 +111,98 @@ func (s *ScanService) ProcessFile(bulkScanRecord *models.BulkScanRecord) {
 	// read the opening bracket of the array
 	_, err = decoder.Token()
 	if err != nil {
-		s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, fmt.Sprintf("failed to read starting array bracket in json file=%s", filePath), err)
-		return
+		return s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, models.ErrorCodeMalformedFile, fmt.Sprintf("failed to read starting array bracket in json file=%s", filePath), err)
 	}
 
 	log.Printf("starting batch process for bulk scan record id=%d and scan file=%s", bulkScanRecord.ID, filePath)
 
+	startedAt := time.Now()
+	var createdScans int64
+
+	report := newValidationReport(bulkScanRecord)
+
+	// the scans of a file are copied in a single transaction, a failed file never leaves scans behind
+	err = s.scanClient.CopyAllInTransaction(bulkScanRecord.ID, func(copyAll func(scans []models.Scan) (int64, error)) error {
+		var copyErr error
+		createdScans, copyErr = s.copyScans(bulkScanRecord, locationParser, decoder, report, copyAll)
+		if copyErr != nil {
+			return copyErr
+		}
+
+		return s.applyValidationPolicy(bulkScanRecord, report)
+	})
+	if err != nil {
+		return s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, models.ErrorCodeDatabase, fmt.Sprintf("failed to ingest scans from file=%s", filePath), err)
+	}
+
+	// a failed update leaves the record processing, the retried job replaces the scans copied above
+	err = s.updateBulkScanRecord(bulkScanRecord, models.Completed)
+	if err != nil {
+		return fmt.Errorf("failed to mark bulk scan record id=%d as completed, error: %w", bulkScanRecord.ID, err)
+	}
+
+	duration := time.Since(startedAt)
+	log.WithFields(log.Fields{
+		"bulk_scan_record_id": bulkScanRecord.ID,
+		"file_name":           bulkScanRecord.FileName,
+		"file_path":           bulkScanRecord.FilePath,
+		"rows":                createdScans,
+		"invalid_records":     report.InvalidRecords,
+		"duration":            duration,
+		"rows_per_second":     rowsPerSecond(createdScans, duration),
+	}).Info("finished processing of bulk scan file")
+
+	return nil
+}
+
+// locationParserFor returns the grammar of the location pattern the file was uploaded with, or the default grammar
+// when it was uploaded without one.
+func (s *ScanService) locationParserFor(bulkScanRecord *models.BulkScanRecord) (locationParser, error) {
+	if bulkScanRecord.LocationPattern == "" {
+		return s.locationParser, nil
+	}
+
+	return location.NewLocationGrammar(bulkScanRecord.LocationPattern)
+}
+
+// copyScans decodes the remaining scans of the file and copies the valid ones in batches, returning the number of
+// scans copied. Invalid records are added to the validation report instead.
+func (s *ScanService) copyScans(bulkScanRecord *models.BulkScanRecord, locationParser locationParser, decoder *json.Decoder, report *validationReport, copyAll func(scans []models.Scan) (int64, error)) (int64, error) {
+	var createdScans int64
 	var batch []models.Scan
+	recordNumber := 0
 
 	// parse the JSON file in batches
 	for decoder.More() {
-		var fileScanData fileScanData
+		var record json.RawMessage
+		recordNumber++
+
+		// a record that is not valid json leaves the rest of the file unreadable
+		if err := decoder.Decode(&record); err != nil {
+			return createdScans, &internal.ProcessingError{
+				Code:         models.ErrorCodeMalformedFile,
+				RecordNumber: recordNumber,
+				Err:          fmt.Errorf("failed to decode json data, error: %w", err),
+			}
+		}
 
-		// decode each object in the array
-		if err := decoder.Decode(&fileScanData); err != nil {
-			s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, fmt.Sprintf("failed to decode json data from file=%s", filePath), err)
-			return
+		fileScanData, violations := decodeFileScanData(record)
+		if len(violations) > 0 {
+			report.addInvalidRecord(recordNumber, record, violations)
+			continue
 		}
+		report.addValidRecord()
 
+		// the file is going to be rejected, keep validating the remaining records for the report without copying them
+		if bulkScanRecord.ValidationPolicy == models.RejectInvalidScans && report.InvalidRecords > 0 {
+			continue
+		}
+
+		hierarchy, parsed := locationParser.Parse(fileScanData.Name)
 		scan := models.Scan{
 			Location:         fileScanData.Name,
+			Hierarchy:        hierarchy,
+			LocationParsed:   &parsed,
 			Scanned:          fileScanData.Scanned,
 			Occupied:         fileScanData.Occupied,
 			Barcodes:         fileScanData.Barcodes,
@@ -87,11 +210,11 @@ func (s *ScanService) ProcessFile(bulkScanRecord *models.BulkScanRecord) {
 		}
 
 		batch = append(batch, scan)
-		if len(batch) == s.batchSize {
-			err := s.createScans(batch)
+		if len(batch) == s.config.BatchSize {
+			created, err := s.createScans(copyAll, batch)
+			createdScans += created
 			if err != nil {
-				s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, "failed to create scans in database", err)
-				return
+				return createdScans, fmt.Errorf("failed to create scans in database, error: %w", err)
 			}
 			batch = batch[:0] // reset batch
 		}
@@ -99,43 +222,107 @@ func (s *ScanService) ProcessFile(bulkScanRecord *models.BulkScanRecord) {
 
 	// save any remaining scans in the last batch
 	if len(batch) > 0 {
-		err := s.createScans(batch)
+		created, err := s.createScans(copyAll, batch)
+		createdScans += created
 		if err != nil {
-			s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, "failed to create remaining scans in database", err)
-			return
+			return createdScans, fmt.Errorf("failed to create remaining scans in database, error: %w", err)
 		}
 	}
 
 	// read the closing bracket of the array
-	_, err = decoder.Token()
+	_, err := decoder.Token()
 	if err != nil {
-		s.updateBulkScanRecordWithStatusFailed(bulkScanRecord, fmt.Sprintf("failed reading closing array bracket in json file=%s", filePath), err)
-		return
+		return createdScans, &internal.ProcessingError{
+			Code: models.ErrorCodeMalformedFile,
+			Err:  fmt.Errorf("failed reading closing array bracket, error: %w", err),
+		}
 	}
 
-	s.updateBulkScanRecord(bulkScanRecord, models.Completed)
+	return createdScans, nil
+}
 
-	log.WithFields(log.Fields{
-		"bulk_scan_record_id": bulkScanRecord.ID,
-		"file_name":           bulkScanRecord.FileName,
-		"file_path":           bulkScanRecord.FilePath,
-	}).Info("finished processing of bulk scan file")
+// applyValidationPolicy writes the validation report of a file with invalid records and fails the file when the
+// policy rejects invalid records.
+func (s *ScanService) applyValidationPolicy(bulkScanRecord *models.BulkScanRecord, report *validationReport) error {
+	if report.InvalidRecords == 0 {
+		return nil
+	}
+
+	reportPath, err := s.writeValidationReport(report)
+	if err != nil {
+		return &internal.ProcessingError{
+			Code: models.ErrorCodeWriteFailed,
+			Err:  fmt.Errorf("failed to write validation report, error: %w", err),
+		}
+	}
+
+	bulkScanRecord.InvalidRecordCount = report.InvalidRecords
+	bulkScanRecord.ValidationReportPath = reportPath
+
+	if bulkScanRecord.ValidationPolicy == models.QuarantineInvalidScans {
+		log.WithFields(log.Fields{
+			"bulk_scan_record_id": bulkScanRecord.ID,
+			"invalid_records":     report.InvalidRecords,
+		}).Warn("quarantined invalid records of bulk scan file")
+		return nil
+	}
+
+	return &internal.ProcessingError{
+		Code:         models.ErrorCodeInvalidRecords,
+		RecordNumber: report.Violations[0].RecordNumber,
+		Err:          fmt.Errorf("file contains %d invalid records, see the validation report", report.InvalidRecords),
+	}
+}
+
+func (s *ScanService) writeValidationReport(report *validationReport) (string, error) {
+	file, err := s.fileStorageClient.CreateFile(s.config.ValidationReportDirPath, fmt.Sprintf("validation_report_%d.json", report.BulkScanRecordID))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to write validation report file=%s, error: %w", file.Name(), err)
+	}
+
+	return file.Name(), nil
 }
 
-func (s *ScanService) updateBulkScanRecordWithStatusFailed(bulkScanRecord *models.BulkScanRecord, message string, err error) {
+func (s *ScanService) updateBulkScanRecordWithStatusFailed(bulkScanRecord *models.BulkScanRecord, code models.ErrorCode, message string, err error) error {
 	log.Printf("Error: %s: %v", message, err)
+
+	failedErr := errors.New(message)
+	if err != nil {
+		failedErr = fmt.Errorf("%s, error: %w", message, err)
+	}
+
+	bulkScanRecord.Failure = internal.NewFailure(code, failedErr)
 	s.updateBulkScanRecord(bulkScanRecord, models.Failed)
+
+	return internal.FailureError(bulkScanRecord.Failure, failedErr)
 }
 
-func (s *ScanService) updateBulkScanRecord(bulkScanRecord *models.BulkScanRecord, status models.Status) {
+func (s *ScanService) updateBulkScanRecord(bulkScanRecord *models.BulkScanRecord, status models.Status) error {
 	bulkScanRecord.Status = status
 	err := s.bulkScanRecordClient.Update(bulkScanRecord)
 	if err != nil {
 		log.Printf("Error: failed to update bulk scan record: %d to status: %s: %v", bulkScanRecord.ID, bulkScanRecord.Status, err)
 	}
+
+	return err
 }
 
-func (s *ScanService) createScans(batch []models.Scan) error {
-	log.Printf("creating %d scans in database", len(batch))
-	return s.scanClient.CreateAll(batch)
+func (s *ScanService) createScans(copyAll func(scans []models.Scan) (int64, error), batch []models.Scan) (int64, error) {
+	log.Printf("copying %d scans into database", len(batch))
+	return copyAll(batch)
+}
+
+func rowsPerSecond(rows int64, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(rows) / duration.Seconds()
 }
@@ -1,10 +1,14 @@
 package scan
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/habbas99/dexory/generated/services/scan"
+	"github.com/habbas99/dexory/internal"
 	"github.com/habbas99/dexory/internal/models"
+	"github.com/habbas99/dexory/internal/services/location"
 	"github.com/stretchr/testify/suite"
 	"os"
 	"testing"
@@ -14,6 +18,7 @@ type ScanServiceTestSuite struct {
 	suite.Suite
 	MockScanClient           *mockscanservice.MockscanClient
 	MockBulkScanRecordClient *mockscanservice.MockbulkScanRecordClient
+	MockFileStorageClient    *mockscanservice.MockfileStorageClient
 	ScanService              *ScanService
 	ctrl                     *gomock.Controller
 }
@@ -27,8 +32,9 @@ func (suite *ScanServiceTestSuite) SetupTest() {
 
 	suite.MockScanClient = mockscanservice.NewMockscanClient(suite.ctrl)
 	suite.MockBulkScanRecordClient = mockscanservice.NewMockbulkScanRecordClient(suite.ctrl)
+	suite.MockFileStorageClient = mockscanservice.NewMockfileStorageClient(suite.ctrl)
 
-	suite.ScanService = NewScanService(suite.MockBulkScanRecordClient, suite.MockScanClient, 10)
+	suite.ScanService = suite.createScanService(10, models.RejectInvalidScans)
 }
 
 func (suite *ScanServiceTestSuite) TearDownTest() {
@@ -37,7 +43,7 @@ func (suite *ScanServiceTestSuite) TearDownTest() {
 
 func (suite *ScanServiceTestSuite) TestProcessFileInBatchesSuccess() {
 	// Given
-	service := NewScanService(suite.MockBulkScanRecordClient, suite.MockScanClient, 1)
+	service := suite.createScanService(1, models.RejectInvalidScans)
 
 	mockFileContent := `[
 		{"name": "Location1", "scanned": true, "occupied": true, "detected_barcodes": ["Barcode1", "Barcode2"]},
@@ -54,13 +60,119 @@ func (suite *ScanServiceTestSuite) TestProcessFileInBatchesSuccess() {
 	bulkScanRecord.ID = uint(1)
 
 	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(2)
-	suite.MockScanClient.EXPECT().CreateAll(gomock.Any()).Return(nil).Times(3)
+	var batchSizes []int
+	suite.expectCopyAllInTransaction(func(scans []models.Scan) (int64, error) {
+		batchSizes = append(batchSizes, len(scans))
+		return int64(len(scans)), nil
+	})
 
 	// When
 	service.ProcessFile(bulkScanRecord)
 
 	// Then
 	suite.Equal("completed", string(bulkScanRecord.Status))
+	suite.Equal([]int{1, 1, 1}, batchSizes)
+}
+
+func (suite *ScanServiceTestSuite) TestProcessFileReturnsErrorWhenCompletedStatusIsNotSaved() {
+	// Given
+	mockFileContent := `[
+		{"name": "Location1", "scanned": true, "occupied": true, "detected_barcodes": ["Barcode1"]}
+	]`
+	mockFile := suite.createMockJSONFile(mockFileContent)
+	defer os.Remove(mockFile.Name())
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FilePath: mockFile.Name(),
+		Status:   models.Pending,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(1)
+	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(fmt.Errorf("connection reset")).Times(1)
+
+	// the scans of an earlier attempt are replaced inside the transaction of the retry
+	suite.MockScanClient.EXPECT().CopyAllInTransaction(uint(1), gomock.Any()).DoAndReturn(
+		func(_ uint, fn func(copyAll func(scans []models.Scan) (int64, error)) error) error {
+			return fn(func(scans []models.Scan) (int64, error) {
+				return int64(len(scans)), nil
+			})
+		},
+	).Times(1)
+
+	// When
+	err := suite.ScanService.ProcessFile(bulkScanRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "failed to mark bulk scan record id=1 as completed")
+	var permanentErr *internal.PermanentError
+	suite.False(errors.As(err, &permanentErr))
+}
+
+func (suite *ScanServiceTestSuite) TestProcessFileParsesLocationHierarchy() {
+	// Given
+	mockFileContent := `[
+		{"name": "ZA001A", "scanned": true, "occupied": true, "detected_barcodes": ["Barcode1"]},
+		{"name": "DOCK-1", "scanned": true, "occupied": false, "detected_barcodes": []}
+	]`
+	mockFile := suite.createMockJSONFile(mockFileContent)
+	defer os.Remove(mockFile.Name())
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FilePath: mockFile.Name(),
+		Status:   models.Pending,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(2)
+	var hierarchies []models.LocationHierarchy
+	suite.expectCopyAllInTransaction(func(scans []models.Scan) (int64, error) {
+		for _, scan := range scans {
+			hierarchies = append(hierarchies, scan.Hierarchy)
+		}
+		return int64(len(scans)), nil
+	})
+
+	// When
+	suite.ScanService.ProcessFile(bulkScanRecord)
+
+	// Then
+	suite.Equal("completed", string(bulkScanRecord.Status))
+	suite.Equal([]models.LocationHierarchy{{Zone: "Z", Aisle: "A", Bay: "001", Level: "A"}, {}}, hierarchies)
+}
+
+func (suite *ScanServiceTestSuite) TestProcessFileParsesLocationHierarchyWithLocationPatternOfUpload() {
+	// Given
+	mockFileContent := `[
+		{"name": "ZA001A", "scanned": true, "occupied": true, "detected_barcodes": ["Barcode1"]},
+		{"name": "Z-01-02", "scanned": true, "occupied": false, "detected_barcodes": []}
+	]`
+	mockFile := suite.createMockJSONFile(mockFileContent)
+	defer os.Remove(mockFile.Name())
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FilePath:        mockFile.Name(),
+		Status:          models.Pending,
+		LocationPattern: `^(?P<zone>[A-Z]+)-(?P<aisle>[0-9]+)-(?P<level>[0-9]+)$`,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(2)
+	var hierarchies []models.LocationHierarchy
+	suite.expectCopyAllInTransaction(func(scans []models.Scan) (int64, error) {
+		for _, scan := range scans {
+			hierarchies = append(hierarchies, scan.Hierarchy)
+		}
+		return int64(len(scans)), nil
+	})
+
+	// When
+	suite.ScanService.ProcessFile(bulkScanRecord)
+
+	// Then
+	suite.Equal("completed", string(bulkScanRecord.Status))
+	suite.Equal([]models.LocationHierarchy{{}, {Zone: "Z", Aisle: "01", Level: "02"}}, hierarchies)
 }
 
 func (suite *ScanServiceTestSuite) TestProcessFileInOneBatchSuccess() {
@@ -79,7 +191,9 @@ func (suite *ScanServiceTestSuite) TestProcessFileInOneBatchSuccess() {
 	bulkScanRecord.ID = uint(1)
 
 	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(2)
-	suite.MockScanClient.EXPECT().CreateAll(gomock.Any()).Return(nil).Times(1)
+	suite.expectCopyAllInTransaction(func(scans []models.Scan) (int64, error) {
+		return int64(len(scans)), nil
+	})
 
 	// When
 	suite.ScanService.ProcessFile(bulkScanRecord)
@@ -141,13 +255,216 @@ func (suite *ScanServiceTestSuite) TestProcessFileCreateScansFailed() {
 	bulkScanRecord.ID = uint(1)
 
 	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(2)
-	suite.MockScanClient.EXPECT().CreateAll(gomock.Any()).Return(fmt.Errorf("database error")).Times(1)
+	transactionErr := suite.expectCopyAllInTransaction(func(scans []models.Scan) (int64, error) {
+		return 0, fmt.Errorf("database error")
+	})
 
 	// When
 	suite.ScanService.ProcessFile(bulkScanRecord)
 
 	// Then
 	suite.Equal("failed", string(bulkScanRecord.Status))
+	suite.Equal(models.ErrorCodeDatabase, bulkScanRecord.ErrorCode)
+	suite.Equal(0, bulkScanRecord.FailedRecordNumber)
+	suite.Require().Error(*transactionErr)
+}
+
+func (suite *ScanServiceTestSuite) TestProcessFileRollsBackScansWhenDecodeFailsAfterFirstBatch() {
+	// Given
+	service := suite.createScanService(1, models.RejectInvalidScans)
+
+	mockFileContent := `[
+		{"name": "Location1", "scanned": true, "occupied": true, "detected_barcodes": ["Barcode1"]},
+		{"name": "Location2", "scanned": tru}
+	]`
+	mockFile := suite.createMockJSONFile(mockFileContent)
+	defer os.Remove(mockFile.Name())
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FilePath: mockFile.Name(),
+		Status:   models.Pending,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(2)
+
+	copiedScans := 0
+	transactionErr := suite.expectCopyAllInTransaction(func(scans []models.Scan) (int64, error) {
+		copiedScans += len(scans)
+		return int64(len(scans)), nil
+	})
+
+	// When
+	err := service.ProcessFile(bulkScanRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Equal(models.Failed, bulkScanRecord.Status)
+	suite.Equal(models.ErrorCodeMalformedFile, bulkScanRecord.ErrorCode)
+	suite.Equal(2, bulkScanRecord.FailedRecordNumber)
+	suite.Contains(bulkScanRecord.ErrorMessage, "failed to decode json data")
+	suite.Equal(1, copiedScans)
+	// returning the error from the transaction is what makes the repository roll back the first batch
+	suite.Require().Error(*transactionErr)
+}
+
+func (suite *ScanServiceTestSuite) TestProcessFileQuarantinesInvalidRecords() {
+	// Given
+	service := suite.createScanService(10, models.QuarantineInvalidScans)
+
+	mockFileContent := `[
+		{"name": "Location1", "scanned": true, "occupied": true, "detected_barcodes": ["Barcode1"]},
+		{"name": "", "scanned": true, "occupied": false, "detected_barcodes": []},
+		{"name": "Location3", "scanned": true, "occupied": false, "detected_barcodes": []}
+	]`
+	mockFile := suite.createMockJSONFile(mockFileContent)
+	defer os.Remove(mockFile.Name())
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FilePath: mockFile.Name(),
+		Status:   models.Pending,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	reportFile := suite.expectValidationReport()
+	defer os.Remove(reportFile.Name())
+
+	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(2)
+
+	var copiedLocations []string
+	suite.expectCopyAllInTransaction(func(scans []models.Scan) (int64, error) {
+		for _, scan := range scans {
+			copiedLocations = append(copiedLocations, scan.Location)
+		}
+		return int64(len(scans)), nil
+	})
+
+	// When
+	err := service.ProcessFile(bulkScanRecord)
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, bulkScanRecord.Status)
+	suite.Equal(models.QuarantineInvalidScans, bulkScanRecord.ValidationPolicy)
+	suite.Equal([]string{"Location1", "Location3"}, copiedLocations)
+	suite.Equal(1, bulkScanRecord.InvalidRecordCount)
+	suite.Equal(reportFile.Name(), bulkScanRecord.ValidationReportPath)
+
+	report := suite.readValidationReport(reportFile.Name())
+	suite.Equal(3, report.TotalRecords)
+	suite.Equal(2, report.ValidRecords)
+	suite.Equal(1, report.InvalidRecords)
+	suite.Require().Len(report.Violations, 1)
+	suite.Equal(2, report.Violations[0].RecordNumber)
+	suite.Equal([]string{"name must not be empty"}, report.Violations[0].Violations)
+}
+
+func (suite *ScanServiceTestSuite) TestProcessFileRejectsInvalidRecords() {
+	// Given
+	mockFileContent := `[
+		{"name": "Location1", "scanned": true, "occupied": true, "detected_barcodes": ["Barcode1"]},
+		{"name": "Location2", "scanned": false, "occupied": true, "detected_barcodes": []},
+		{"name": "Location3", "scanned": true, "occupied": false, "detected_barcodes": []}
+	]`
+	mockFile := suite.createMockJSONFile(mockFileContent)
+	defer os.Remove(mockFile.Name())
+
+	bulkScanRecord := &models.BulkScanRecord{
+		FilePath: mockFile.Name(),
+		Status:   models.Pending,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	reportFile := suite.expectValidationReport()
+	defer os.Remove(reportFile.Name())
+
+	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(2)
+	transactionErr := suite.expectCopyAllInTransaction(func(scans []models.Scan) (int64, error) {
+		return int64(len(scans)), nil
+	})
+
+	// When
+	err := suite.ScanService.ProcessFile(bulkScanRecord)
+
+	// Then
+	suite.Require().Error(err)
+	suite.Require().Error(*transactionErr)
+	var permanentErr *internal.PermanentError
+	suite.ErrorAs(err, &permanentErr)
+	suite.Equal(models.Failed, bulkScanRecord.Status)
+	suite.Equal(models.ErrorCodeInvalidRecords, bulkScanRecord.ErrorCode)
+	suite.Equal(2, bulkScanRecord.FailedRecordNumber)
+	suite.Equal(1, bulkScanRecord.InvalidRecordCount)
+	suite.Equal(reportFile.Name(), bulkScanRecord.ValidationReportPath)
+
+	report := suite.readValidationReport(reportFile.Name())
+	suite.Equal(models.RejectInvalidScans, report.ValidationPolicy)
+	suite.Require().Len(report.Violations, 1)
+	suite.Equal([]string{"occupied must be false when scanned is false"}, report.Violations[0].Violations)
+}
+
+func (suite *ScanServiceTestSuite) TestDecodeFileScanDataViolations() {
+	// Given
+	records := map[string][]string{
+		`{"name": "Location1", "scanned": true, "occupied": true, "detected_barcodes": ["Barcode1"]}`:  {},
+		`{"name": " ", "scanned": true, "occupied": false, "detected_barcodes": []}`:                   {"name must not be empty"},
+		`{"name": "Location1", "scanned": true, "occupied": false, "detected_barcodes": ["Barcode1"]}`: {"detected_barcodes must be empty when occupied is false"},
+		`{"name": "Location1", "scanned": false, "occupied": true, "detected_barcodes": []}`:           {"occupied must be false when scanned is false"},
+	}
+
+	for record, expectedViolations := range records {
+		// When
+		_, violations := decodeFileScanData([]byte(record))
+
+		// Then
+		suite.Equal(expectedViolations, violations, record)
+	}
+}
+
+func (suite *ScanServiceTestSuite) TestDecodeFileScanDataWithWrongTypes() {
+	// When
+	_, violations := decodeFileScanData([]byte(`{"name": "Location1", "scanned": "yes"}`))
+
+	// Then
+	suite.Require().Len(violations, 1)
+	suite.Contains(violations[0], "record could not be decoded")
+}
+
+func (suite *ScanServiceTestSuite) TestProcessBulkScanRecordAlreadyCompleted() {
+	// Given
+	bulkScanRecord := &models.BulkScanRecord{
+		FilePath: "scans.json",
+		Status:   models.Completed,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	suite.MockBulkScanRecordClient.EXPECT().Get(uint(1)).Return(bulkScanRecord, nil).Times(1)
+
+	// When
+	err := suite.ScanService.ProcessBulkScanRecord(uint(1))
+
+	// Then
+	suite.Require().NoError(err)
+	suite.Equal(models.Completed, bulkScanRecord.Status)
+}
+
+func (suite *ScanServiceTestSuite) TestProcessBulkScanRecordReturnsProcessingError() {
+	// Given
+	bulkScanRecord := &models.BulkScanRecord{
+		FilePath: "nonexistent.json",
+		Status:   models.Pending,
+	}
+	bulkScanRecord.ID = uint(1)
+
+	suite.MockBulkScanRecordClient.EXPECT().Get(uint(1)).Return(bulkScanRecord, nil).Times(1)
+	suite.MockBulkScanRecordClient.EXPECT().Update(bulkScanRecord).Return(nil).Times(2)
+
+	// When
+	err := suite.ScanService.ProcessBulkScanRecord(uint(1))
+
+	// Then
+	suite.Require().Error(err)
+	suite.Equal(models.Failed, bulkScanRecord.Status)
 }
 
 func (suite *ScanServiceTestSuite) createMockJSONFile(content string) *os.File {
@@ -162,3 +479,47 @@ func (suite *ScanServiceTestSuite) createMockJSONFile(content string) *os.File {
 
 	return file
 }
+
+// expectCopyAllInTransaction runs the transaction function against copyAll and returns a pointer to the error it
+// returned, which the repository commits on nil and rolls back otherwise.
+func (suite *ScanServiceTestSuite) expectCopyAllInTransaction(copyAll func(scans []models.Scan) (int64, error)) *error {
+	var transactionErr error
+	suite.MockScanClient.EXPECT().CopyAllInTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ uint, fn func(copyAll func(scans []models.Scan) (int64, error)) error) error {
+			transactionErr = fn(copyAll)
+			return transactionErr
+		},
+	).Times(1)
+
+	return &transactionErr
+}
+
+func (suite *ScanServiceTestSuite) createScanService(batchSize int, validationPolicy models.ValidationPolicy) *ScanService {
+	locationGrammar, err := location.NewLocationGrammar(location.DefaultLocationPattern)
+	suite.Require().NoError(err)
+
+	return NewScanService(suite.MockBulkScanRecordClient, suite.MockScanClient, suite.MockFileStorageClient, locationGrammar, ScanServiceConfig{
+		BatchSize:               batchSize,
+		ValidationPolicy:        validationPolicy,
+		ValidationReportDirPath: "validation-reports",
+	})
+}
+
+func (suite *ScanServiceTestSuite) expectValidationReport() *os.File {
+	reportFile, err := os.CreateTemp("", "validation_report_*.json")
+	suite.Require().NoError(err)
+
+	suite.MockFileStorageClient.EXPECT().CreateFile("validation-reports", "validation_report_1.json").Return(reportFile, nil).Times(1)
+
+	return reportFile
+}
+
+func (suite *ScanServiceTestSuite) readValidationReport(filePath string) validationReport {
+	content, err := os.ReadFile(filePath)
+	suite.Require().NoError(err)
+
+	var report validationReport
+	suite.Require().NoError(json.Unmarshal(content, &report))
+
+	return report
+}
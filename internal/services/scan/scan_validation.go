@@ -0,0 +1,72 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/habbas99/dexory/internal/models"
+)
+
+// validationReport lists every invalid record of a bulk scan file. Under the quarantine policy the invalid records
+// are not ingested, the report is where they are kept.
+type validationReport struct {
+	BulkScanRecordID uint                    `json:"bulkScanRecordId"`
+	FileName         string                  `json:"fileName"`
+	ValidationPolicy models.ValidationPolicy `json:"validationPolicy"`
+	TotalRecords     int                     `json:"totalRecords"`
+	ValidRecords     int                     `json:"validRecords"`
+	InvalidRecords   int                     `json:"invalidRecords"`
+	Violations       []recordViolation       `json:"violations"`
+}
+
+type recordViolation struct {
+	RecordNumber int             `json:"recordNumber"`
+	Record       json.RawMessage `json:"record"`
+	Violations   []string        `json:"violations"`
+}
+
+func newValidationReport(bulkScanRecord *models.BulkScanRecord) *validationReport {
+	return &validationReport{
+		BulkScanRecordID: bulkScanRecord.ID,
+		FileName:         bulkScanRecord.FileName,
+		ValidationPolicy: bulkScanRecord.ValidationPolicy,
+		Violations:       []recordViolation{},
+	}
+}
+
+func (r *validationReport) addValidRecord() {
+	r.TotalRecords++
+	r.ValidRecords++
+}
+
+func (r *validationReport) addInvalidRecord(recordNumber int, record json.RawMessage, violations []string) {
+	r.TotalRecords++
+	r.InvalidRecords++
+	r.Violations = append(r.Violations, recordViolation{
+		RecordNumber: recordNumber,
+		Record:       record,
+		Violations:   violations,
+	})
+}
+
+// decodeFileScanData decodes a single record of the bulk scan file and returns every rule the record breaks.
+func decodeFileScanData(record json.RawMessage) (fileScanData, []string) {
+	var data fileScanData
+	if err := json.Unmarshal(record, &data); err != nil {
+		return data, []string{fmt.Sprintf("record could not be decoded: %v", err)}
+	}
+
+	violations := []string{}
+	if strings.TrimSpace(data.Name) == "" {
+		violations = append(violations, "name must not be empty")
+	}
+	if !data.Occupied && len(data.Barcodes) > 0 {
+		violations = append(violations, "detected_barcodes must be empty when occupied is false")
+	}
+	if !data.Scanned && data.Occupied {
+		violations = append(violations, "occupied must be false when scanned is false")
+	}
+
+	return data, violations
+}